@@ -0,0 +1,173 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/utils"
+	"github.com/wieku/rplpa"
+	"log"
+	"os"
+)
+
+type analysisOutput struct {
+	Beatmap  string
+	MD5      string
+	Expected expectedScore
+	*dance.SimulationResult
+}
+
+type expectedScore struct {
+	Score     int32
+	MaxCombo  uint16
+	Count300  uint16
+	Count100  uint16
+	Count50   uint16
+	CountMiss uint16
+}
+
+func analyzeReplay(beatMap *beatmap.BeatMap, replay *rplpa.Replay, format string) {
+	log.Println("Analyzing replay:", replay.Username, "on", beatMap.Artist, "-", beatMap.Name, "["+beatMap.Difficulty+"]")
+
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, false)
+
+	result := dance.SimulateReplay(beatMap, replay)
+
+	out := analysisOutput{
+		Beatmap: fmt.Sprintf("%s - %s [%s]", beatMap.Artist, beatMap.Name, beatMap.Difficulty),
+		MD5:     beatMap.MD5,
+		Expected: expectedScore{
+			Score:     replay.Score,
+			MaxCombo:  replay.MaxCombo,
+			Count300:  replay.Count300,
+			Count100:  replay.Count100,
+			Count50:   replay.Count50,
+			CountMiss: replay.CountMiss,
+		},
+		SimulationResult: result,
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+
+		if err := encoder.Encode(out); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	fmt.Println(out.Beatmap)
+	fmt.Println("Player:", result.Player, "Mods:", result.Mods)
+
+	summary := tablewriter.NewWriter(os.Stdout)
+	summary.SetHeader([]string{"", "Score", "Accuracy", "Grade", "300", "100", "50", "Miss", "SB", "Max Combo", "PP"})
+
+	summary.Append([]string{
+		"Simulated",
+		utils.Humanize(result.Score),
+		fmt.Sprintf("%.2f", result.Accuracy),
+		result.Grade,
+		utils.Humanize(result.Count300),
+		utils.Humanize(result.Count100),
+		utils.Humanize(result.Count50),
+		utils.Humanize(result.CountMiss),
+		utils.Humanize(result.CountSB),
+		utils.Humanize(result.MaxCombo),
+		fmt.Sprintf("%.2f", result.PP.Total),
+	})
+
+	summary.Append([]string{
+		"Replay",
+		utils.Humanize(replay.Score),
+		"",
+		"",
+		utils.Humanize(replay.Count300),
+		utils.Humanize(replay.Count100),
+		utils.Humanize(replay.Count50),
+		utils.Humanize(replay.CountMiss),
+		"",
+		utils.Humanize(replay.MaxCombo),
+		"",
+	})
+
+	summary.Render()
+
+	if result.Failed {
+		fmt.Println("Player failed at:", result.FailTime)
+	}
+
+	fmt.Println("Replay integrity:")
+
+	for _, line := range result.Integrity.Summary() {
+		fmt.Println("\t" + line)
+	}
+
+	printVerification(result.Verification)
+
+	timeline := tablewriter.NewWriter(os.Stdout)
+	timeline.SetHeader([]string{"Object", "Time", "Result", "Combo", "Score", "Position"})
+
+	for _, e := range result.Timeline {
+		timeline.Append([]string{
+			fmt.Sprintf("%d", e.Object),
+			fmt.Sprintf("%d", e.Time),
+			e.Result,
+			fmt.Sprintf("%d", e.Combo),
+			utils.Humanize(e.Score),
+			fmt.Sprintf("%.0fx%.0f", e.X, e.Y),
+		})
+	}
+
+	timeline.Render()
+}
+
+func printVerification(verification *dance.Verification) {
+	if verification.Matches {
+		fmt.Println("Verification: simulated score matches the replay")
+		return
+	}
+
+	fmt.Println("Verification: simulated score differs from the replay")
+
+	mismatches := tablewriter.NewWriter(os.Stdout)
+	mismatches.SetHeader([]string{"Value", "Replay", "Simulated"})
+
+	for _, m := range verification.Mismatches {
+		mismatches.Append([]string{m.Field, fmt.Sprintf("%d", m.Replay), fmt.Sprintf("%d", m.Simulated)})
+	}
+
+	mismatches.Render()
+
+	if len(verification.Suspects) == 0 {
+		return
+	}
+
+	fmt.Println("Judgements around the first one that was probably different:")
+
+	suspects := tablewriter.NewWriter(os.Stdout)
+	suspects.SetHeader([]string{"Object", "Time", "Type", "2B", "Result", "Combo", "Reason"})
+
+	for _, s := range verification.Suspects {
+		twoB := ""
+		if s.TwoB {
+			twoB = "Yes"
+		}
+
+		suspects.Append([]string{
+			fmt.Sprintf("%d", s.Object),
+			fmt.Sprintf("%d", s.Time),
+			s.Type,
+			twoB,
+			s.Result,
+			fmt.Sprintf("%d", s.Combo),
+			s.Reason,
+		})
+	}
+
+	suspects.Render()
+}
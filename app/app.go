@@ -2,6 +2,8 @@ package app
 
 import "C"
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -16,11 +18,15 @@ import (
 	"github.com/wieku/danser-go/app/beatmap"
 	difficulty2 "github.com/wieku/danser-go/app/beatmap/difficulty"
 	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/dance/movers"
 	"github.com/wieku/danser-go/app/database"
 	"github.com/wieku/danser-go/app/discord"
+	"github.com/wieku/danser-go/app/events"
 	"github.com/wieku/danser-go/app/ffmpeg"
 	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
 	"github.com/wieku/danser-go/app/settings"
+	skin2 "github.com/wieku/danser-go/app/skin"
 	"github.com/wieku/danser-go/app/states"
 	"github.com/wieku/danser-go/app/utils"
 	"github.com/wieku/danser-go/build"
@@ -48,6 +54,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -84,15 +91,82 @@ var preciseProgress bool
 
 var monitorHz int
 
+var logFile *os.File
+
+// stringList is a flag value which can be given multiple times
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ", ")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// mainThreadPanic is a panic recovered by callMain, stackTrace comes from the main thread
+type mainThreadPanic struct {
+	err        any
+	stackTrace []string
+}
+
+// callMain runs f on the main thread like mainthread.Call, but a panic in f is raised again in the calling goroutine
+func callMain(f func()) {
+	var mPanic *mainThreadPanic
+
+	mainthread.Call(func() {
+		defer func() {
+			if err := recover(); err != nil {
+				mPanic = &mainThreadPanic{err: err, stackTrace: goroutines.GetStackTrace(4)}
+			}
+		}()
+
+		f()
+	})
+
+	if mPanic != nil {
+		panic(mPanic)
+	}
+}
+
+// unwrapPanic returns the recovered value with its stack trace, it has to be called directly by the deferred function
+func unwrapPanic(err any) (any, []string) {
+	if mPanic, ok := err.(*mainThreadPanic); ok {
+		return mPanic.err, mPanic.stackTrace
+	}
+
+	return err, goroutines.GetStackTrace(6)
+}
+
 func run() {
 	defer func() {
 		if err := recover(); err != nil {
-			stackTrace := goroutines.GetStackTrace(4)
-			closeHandler(err, stackTrace)
+			closeHandler(unwrapPanic(err))
 		}
 	}()
 
-	mainthread.Call(func() {
+	launch(os.Args[1:], nil)
+}
+
+// launch runs danser with given command line arguments. Batch jobs pass beatmaps already loaded from database,
+// window, OpenGL and BASS are set up by the first job and reused by the next ones.
+func launch(args []string, beatmaps []*beatmap.BeatMap) {
+	batchJob := beatmaps != nil
+
+	player = nil
+	output = ""
+
+	var startBatch func() // Jobs need the main thread for rendering, so they are started after leaving it
+
+	callMain(func() {
+		errorHandling := flag.ExitOnError
+		if batchJob {
+			errorHandling = flag.ContinueOnError
+		}
+
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], errorHandling)
+
 		id := flag.Int64("id", -1, "Specify the beatmap id. Overrides other beatmap search flags")
 
 		md5 := flag.String("md5", "", "Specify the beatmap md5 hash. Overrides other beatmap search flags")
@@ -139,7 +213,11 @@ func run() {
 		replay := flag.String("replay", "", replayDesc)
 		flag.StringVar(replay, "r", "", replayDesc+shorthand)
 
-		skin := flag.String("skin", "", "Replace Skin.CurrentSkin setting temporarily")
+		skin := flag.String("skin", "", "Replace Skin.CurrentSkin setting temporarily. Path to an .osk file can be given as well, skin is imported to the skins directory before being used")
+
+		skinCheck := flag.String("skin-check", "", "Print which textures, animations, fonts and samples of the given skin fall back to Skin.FallbackSkin or danser's default skin, and which skin.ini keys are not used, without opening a window")
+
+		mover := flag.String("mover", "", fmt.Sprintf("Replace movers in CursorDance.Movers setting temporarily. Available movers: %s", strings.Join(movers.GetNames(), ", ")))
 
 		noDbCheck := flag.Bool("nodbcheck", false, "Don't validate the database and only import new beatmap sets if there are any. Useful for slow drives.")
 		noUpdCheck := flag.Bool("noupdatecheck", strings.HasPrefix(env.LibDir(), "/usr/lib/"), "Don't check for updates. Speeds up startup if older version of danser is needed for various reasons. Has no effect if danser is running as a linux package")
@@ -153,7 +231,50 @@ func run() {
 
 		flag.BoolVar(&preciseProgress, "preciseprogress", false, "Show rendering progress in 1% increments")
 
-		flag.Parse()
+		analyze := flag.Bool("analyze", false, "Simulate the replay given by -replay without opening a window and print the final score, replay integrity report (frame times, timewarp, key presses, cursor snaps, unstable rate, score mismatches) and per-object hit timeline")
+		format := flag.String("format", "text", "Output format of -analyze, -calc and -skin-check modes: text or json")
+
+		query := flag.String("query", "", "Search beatmaps by a query like \"stars>6 ar>=9.3 bpm<200 creator=xyz length<3m\". Available keys: stars, ar, od, cs, hp, bpm, minbpm, maxbpm, length, circles, sliders, spinners, objects, id, setid, mode, playcount, artist, title, difficulty, creator, source, tags, md5. Terms without a key are searched in beatmap's metadata. Fails if more than one beatmap matches")
+		list := flag.Bool("list", false, "List all beatmaps matching -query or other beatmap search flags instead of running the first one")
+
+		export := flag.String("export", "", "Save the beatmap as .osu file to the given path without opening a window. Mods (-mods), -speed and -ar/-od/-cs/-hp are baked into the exported map. Audio file is not changed")
+
+		dbExport := flag.String("db-export", "", "Export beatmap database (star ratings, local offsets, play stats and other cached values) to the given .json or .csv file")
+		dbImport := flag.String("db-import", "", "Import beatmap database exported with -db-export. Restores local offsets, play stats and star ratings, and adds beatmaps found in Songs directory without parsing them again")
+
+		dbDryRun := flag.Bool("db-migrate-dry-run", false, "Print pending database migrations without applying them and exit")
+
+		saveReplay := flag.Bool("savereplay", false, "Save cursor dance or -play session as an .osr file in \"replays/{a}\" where {a} is an md5 hash of .osu file, so it can be loaded with -replay or used in knockout. Cursor dance is saved only with -tag=1")
+
+		ppVersion := flag.String("ppversion", "", fmt.Sprintf("Replace Gameplay.PPVersion setting temporarily, used for star ratings and pp. Available versions: %s, %s", performance.Latest, strings.Join(performance.GetVersions(), ", ")))
+
+		var calcPaths stringList
+		flag.Var(&calcPaths, "calc", "Calculate star rating and pp of .osu files matching the glob pattern or found in the directory without opening a window, can be used multiple times. Mods are given by -mods as comma separated combinations like NM,HD,HDDT")
+		calcAcc := flag.String("acc", "100", "Comma separated accuracies used by -calc, e.g. 95,98,100")
+		calcMisses := flag.Int("misses", 0, "Number of misses used by -calc")
+		calcCombo := flag.Int("combo", -1, "Combo used by -calc, max combo if negative")
+
+		eventsTarget := flag.String("events", "", "Write a JSON-lines stream of events (stage changes, progress, output files, warnings and errors) to stdout, stderr, fd:N or a path to a file or named pipe")
+
+		batchPath := flag.String("batch", "", "Record jobs from the given JSON file one after another. Each job specifies Replay or MD5/ID/Query, Settings, Set, Mods, Start, End, Skin, Out, additional Args and Retries. Failed jobs are retried and a summary is printed at the end")
+		serverAddress := flag.String("server", "", "Start a local render server on the given address, e.g. :8080 (localhost is used if host is omitted). Replays are uploaded by POST /api/replays, jobs are submitted by POST /api/jobs, polled by GET /api/jobs/{id}, cancelled by DELETE /api/jobs/{id} and downloaded from GET /api/jobs/{id}/video")
+		queueSize := flag.Int("queue", 16, "Maximum number of queued jobs in -server mode")
+		noFFmpegCheck := flag.Bool("noffmpegcheck", false, "Don't check whether encoders set in Recording settings exist before recording. Used by -batch")
+
+		var overrides stringList
+		flag.Var(&overrides, "set", "Override a setting temporarily, e.g. -set Cursor.CursorSize=12 or -set CursorDance.Movers[0].Mover=flower. Paths are the same as JSON paths in settings files, can be used multiple times")
+
+		if err := flag.CommandLine.Parse(args); err != nil {
+			panic(fmt.Sprintf("Failed to parse arguments: %s", err))
+		}
+
+		if *eventsTarget != "" {
+			if err := events.Open(*eventsTarget); err != nil {
+				panic(fmt.Sprintf("flag -events: %s", err))
+			}
+
+			events.EmitStage(events.StageLoading)
+		}
 
 		var knockoutReplays []string
 
@@ -176,6 +297,84 @@ func run() {
 			}
 		}
 
+		if *analyze {
+			if *replay == "" {
+				panic("flag -analyze requires -replay to be specified")
+			}
+
+			if *format != "text" && *format != "json" {
+				panic(fmt.Sprintf("flag -format: unknown format \"%s\"", *format))
+			}
+
+			// Keep stdout clean for analysis output
+			log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+		}
+
+		calcMode := len(calcPaths) > 0
+
+		if calcMode {
+			if *format != "text" && *format != "json" {
+				panic(fmt.Sprintf("flag -format: unknown format \"%s\"", *format))
+			}
+
+			// Keep stdout clean for calculation output
+			log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+		}
+
+		skinCheckMode := *skinCheck != ""
+
+		if skinCheckMode {
+			if *format != "text" && *format != "json" {
+				panic(fmt.Sprintf("flag -format: unknown format \"%s\"", *format))
+			}
+
+			// Keep stdout clean for the report
+			log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+		}
+
+		var batchJobs *batchFile
+
+		if *batchPath != "" {
+			b, err := loadBatchFile(*batchPath)
+			if err != nil {
+				panic(fmt.Sprintf("flag -batch: %s", err))
+			}
+
+			batchJobs = b
+		}
+
+		serverMode := *serverAddress != ""
+
+		if serverMode && *queueSize < 1 {
+			panic("flag -queue: queue size has to be at least 1")
+		}
+
+		if batchJob && (batchJobs != nil || serverMode) {
+			panic("Incompatible flags selected: batch job, -batch/-server")
+		}
+
+		ffmpeg.SkipEncoderCheck = *noFFmpegCheck
+
+		if *list {
+			// Keep stdout clean for the beatmap table
+			log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+		}
+
+		var beatmapQuery *beatmap.Query
+
+		if *query != "" {
+			q, err := beatmap.ParseQuery(*query)
+			if err != nil {
+				panic(fmt.Sprintf("flag -query: %s", err))
+			}
+
+			beatmapQuery = q
+		}
+
+		if *mover != "" && !movers.IsRegistered(*mover) {
+			panic(fmt.Sprintf("flag -mover: unknown mover \"%s\"", *mover))
+		}
+
 		recordMode = *record
 		screenshotMode = !math.IsNaN(*ss)
 		screenshotTime = *ss
@@ -192,9 +391,32 @@ func run() {
 			panic("Incompatible flags selected: -ss, -play")
 		} else if screenshotMode && recordMode {
 			panic("Incompatible flags selected: -ss, -record")
+		} else if *analyze && (recordMode || screenshotMode) {
+			panic("Incompatible flags selected: -analyze, -record/-ss")
+		} else if *list && (*analyze || *replay != "") {
+			panic("Incompatible flags selected: -list, -analyze/-replay")
+		} else if calcMode && (*list || *analyze || *replay != "" || *play || *knockout || recordMode || screenshotMode) {
+			panic("Incompatible flags selected: -calc, -list/-analyze/-replay/-play/-knockout/-record/-ss")
+		} else if batchJobs != nil && (calcMode || *list || *analyze || *replay != "" || *play || *knockout || recordMode || screenshotMode || *saveReplay) {
+			panic("Incompatible flags selected: -batch, -calc/-list/-analyze/-replay/-play/-knockout/-record/-out/-ss/-savereplay")
+		} else if serverMode && (batchJobs != nil || calcMode || *list || *analyze || *replay != "" || *play || *knockout || recordMode || screenshotMode || *saveReplay) {
+			panic("Incompatible flags selected: -server, -batch/-calc/-list/-analyze/-replay/-play/-knockout/-record/-out/-ss/-savereplay")
+		} else if *export != "" && (calcMode || batchJobs != nil || serverMode || *list || *analyze || *replay != "" || *play || *knockout || recordMode || screenshotMode || *saveReplay) {
+			panic("Incompatible flags selected: -export, -calc/-batch/-server/-list/-analyze/-replay/-play/-knockout/-record/-out/-ss/-savereplay")
+		} else if skinCheckMode && (calcMode || batchJobs != nil || serverMode || *list || *analyze || *replay != "" || *play || *knockout || recordMode || screenshotMode || *export != "") {
+			panic("Incompatible flags selected: -skin-check, -calc/-batch/-server/-list/-analyze/-replay/-play/-knockout/-record/-out/-ss/-export")
+		} else if *saveReplay && (*knockout || *replay != "") {
+			panic("Incompatible flags selected: -savereplay, -knockout/-replay")
+		} else if *saveReplay && !*play && *tag > 1 {
+			panic("Incompatible flags selected: -savereplay, -tag")
 		}
 
-		modsParsed := difficulty2.ParseMods(*mods)
+		var modsParsed difficulty2.Modifier
+		if !calcMode { // -calc accepts a list of mod combinations
+			modsParsed = difficulty2.ParseMods(*mods)
+		}
+
+		var replayD *rplpa.Replay
 
 		if *replay != "" {
 			bytes, err := ioutil.ReadFile(*replay)
@@ -207,8 +429,10 @@ func run() {
 				panic(err)
 			}
 
-			if rp.PlayMode != 0 {
-				panic("Modes other than osu!standard are not supported")
+			replayD = rp
+
+			if rp.PlayMode != rplpa.OSU && rp.PlayMode != rplpa.MANIA {
+				panic("Modes other than osu!standard and osu!mania are not supported")
 			}
 
 			if rp.ReplayData == nil || len(rp.ReplayData) < 2 {
@@ -219,16 +443,32 @@ func run() {
 			*id = -1
 			modsParsed = difficulty2.Modifier(rp.Mods)
 			*knockout = true
-			settings.REPLAY = *replay
 		}
 
 		if !modsParsed.Compatible() {
 			panic("Incompatible mods selected!")
 		}
 
+		if *saveReplay && modsParsed.Active(difficulty2.Autoplay) {
+			panic("Incompatible flags selected: -savereplay, AT mod")
+		}
+
 		closeAfterSettingsLoad := false
 
-		if (*md5+*artist+*title+*difficulty+*creator) == "" && *id < 0 {
+		searchSpecified := (*md5+*artist+*title+*difficulty+*creator+*query) != "" || *id > -1
+		dbOnly := !searchSpecified && !*list && (*dbExport != "" || *dbImport != "" || *dbDryRun)
+
+		if batchJobs != nil && searchSpecified {
+			panic("Incompatible flags selected: -batch, beatmap search flags")
+		} else if serverMode && searchSpecified {
+			panic("Incompatible flags selected: -server, beatmap search flags")
+		}
+
+		if skinCheckMode && searchSpecified {
+			panic("Incompatible flags selected: -skin-check, beatmap search flags")
+		}
+
+		if !searchSpecified && !*list && !dbOnly && !calcMode && batchJobs == nil && !serverMode && !skinCheckMode {
 			log.Println("No beatmap specified, closing...")
 			closeAfterSettingsLoad = true
 		}
@@ -246,6 +486,8 @@ func run() {
 		settings.END = *end
 		settings.RECORD = recordMode || screenshotMode
 		settings.LOCALOFFSET = *offset
+		settings.SAVEREPLAY = *saveReplay
+		settings.REPLAY = *replay
 
 		if *settingsVersion == "credentials" || *settingsVersion == "launcher" {
 			panic(fmt.Sprintf("flag -settings: name \"%s\" is forbidden", *settingsVersion))
@@ -253,22 +495,113 @@ func run() {
 
 		newSettings := settings.LoadSettings(*settingsVersion)
 
-		if !newSettings && len(os.Args) == 1 {
+		if err := settings.SetOverrides(overrides); err != nil {
+			panic(fmt.Sprintf("flag -set: %s", err))
+		}
+
+		if *ppVersion != "" {
+			calculator := performance.GetCalculator(*ppVersion)
+			if calculator == nil {
+				panic(fmt.Sprintf("flag -ppversion: unknown version \"%s\"", *ppVersion))
+			}
+
+			version := performance.Latest
+			if !strings.EqualFold(*ppVersion, performance.Latest) {
+				version = strconv.Itoa(calculator.Version())
+			}
+
+			if err := settings.SetOverrides([]string{"Gameplay.PPVersion=" + version}); err != nil {
+				panic(fmt.Sprintf("flag -ppversion: %s", err))
+			}
+		}
+
+		if !newSettings && len(args) == 0 {
 			platform.OpenURL("https://youtu.be/dQw4w9WgXcQ")
 			closeAfterSettingsLoad = true
 		}
 
-		player = nil
+		if calcMode {
+			calculateBeatmaps(calcPaths, *mods, *calcAcc, *calcMisses, *calcCombo, *format)
+			return
+		}
+
+		if skinCheckMode {
+			checkSkin(*skinCheck, *format)
+			return
+		}
+
+		if batchJobs != nil {
+			startBatch = func() {
+				runBatch(batchJobs, *noDbCheck, *settingsVersion, overrides)
+			}
+
+			return
+		}
+
+		if serverMode {
+			runServer(*serverAddress, *queueSize, *noDbCheck, *settingsVersion, overrides)
+			return
+		}
+
+		if *dbDryRun {
+			if err := database.DryRunMigrations(); err != nil {
+				log.Println("Failed to check database migrations:", err)
+			}
+
+			return
+		}
+
 		var beatMap *beatmap.BeatMap = nil
 
 		if !closeAfterSettingsLoad {
-			err := database.Init()
-			if err != nil {
-				log.Println("Failed to initialize database:", err)
-			} else {
-				beatmaps := database.LoadBeatmaps(*noDbCheck, nil)
+			if !batchJob { // Database is kept open by the batch
+				if err := database.Init(); err != nil {
+					log.Println("Failed to initialize database:", err)
+				} else {
+					if *dbImport != "" {
+						if err = database.ImportBeatmaps(*dbImport); err != nil {
+							log.Println("Failed to import database:", err)
+						}
+					}
+
+					beatmaps = database.LoadBeatmaps(*noDbCheck, nil)
+
+					if *dbExport != "" {
+						if err = database.ExportBeatmaps(*dbExport); err != nil {
+							log.Println("Failed to export database:", err)
+						}
+					}
+				}
+			}
+
+			if beatmaps != nil {
+				partialMatch := func(b *beatmap.BeatMap) bool {
+					return (*artist == "" || strings.Contains(strings.ToLower(b.Artist), strings.ToLower(*artist))) &&
+						(*title == "" || strings.Contains(strings.ToLower(b.Name), strings.ToLower(*title))) &&
+						(*difficulty == "" || strings.Contains(strings.ToLower(b.Difficulty), strings.ToLower(*difficulty))) &&
+						(*creator == "" || strings.Contains(strings.ToLower(b.Creator), strings.ToLower(*creator)))
+				}
+
+				if beatmapQuery != nil || *list {
+					var matches []*beatmap.BeatMap
 
-				if *id > -1 {
+					for _, b := range beatmaps {
+						if (beatmapQuery == nil || beatmapQuery.Matches(b)) &&
+							(*id < 0 || b.ID == *id) &&
+							(*md5 == "" || strings.EqualFold(b.MD5, *md5)) &&
+							partialMatch(b) {
+							matches = append(matches, b)
+						}
+					}
+
+					if *list {
+						listBeatmaps(matches)
+					} else if len(matches) == 1 {
+						beatMap = matches[0]
+					} else if len(matches) > 1 {
+						log.Println(fmt.Sprintf("Query matched %d beatmaps, refine it or use -list to show them", len(matches)))
+					}
+				} else if *id > -1 {
 					for _, b := range beatmaps {
 						if b.ID == *id {
 							beatMap = b
@@ -284,7 +617,7 @@ func run() {
 							break
 						}
 					}
-				} else {
+				} else if !dbOnly {
 					for _, b := range beatmaps {
 						if (*artist == "" || strings.EqualFold(*artist, b.Artist)) &&
 							(*title == "" || strings.EqualFold(*title, b.Name)) &&
@@ -299,10 +632,7 @@ func run() {
 					if beatMap == nil {
 						log.Println("Beatmap with exact parameters not found, searching partially...")
 						for _, b := range beatmaps {
-							if (*artist == "" || strings.Contains(strings.ToLower(b.Artist), strings.ToLower(*artist))) &&
-								(*title == "" || strings.Contains(strings.ToLower(b.Name), strings.ToLower(*title))) &&
-								(*difficulty == "" || strings.Contains(strings.ToLower(b.Difficulty), strings.ToLower(*difficulty))) &&
-								(*creator == "" || strings.Contains(strings.ToLower(b.Creator), strings.ToLower(*creator))) {
+							if partialMatch(b) {
 								beatMap = b
 
 								break
@@ -312,18 +642,54 @@ func run() {
 				}
 			}
 
-			if beatMap == nil {
+			if *list || dbOnly {
+				closeAfterSettingsLoad = true
+			} else if beatMap == nil {
 				log.Println("Beatmap not found, closing...")
 				closeAfterSettingsLoad = true
 			} else {
+				if replayD != nil && (replayD.PlayMode == rplpa.MANIA) != (beatMap.Mode == beatmap.ModeMania) {
+					panic("Replays on converted beatmaps are not supported")
+				}
+
+				if batchJob { // Beatmaps are shared by all jobs
+					beatMap = beatMap.Copy()
+				}
+
 				beatMap.UpdatePlayStats()
 				database.UpdatePlayStats(beatMap)
 			}
 
-			database.Close()
+			if !batchJob {
+				database.Close()
+			}
 		}
 
-		assets.Init(build.Stream == "Dev")
+		if *list || dbOnly {
+			return
+		}
+
+		if *analyze {
+			if !closeAfterSettingsLoad {
+				analyzeReplay(beatMap, replayD, *format)
+			}
+
+			return
+		}
+
+		if *export != "" {
+			if !closeAfterSettingsLoad {
+				exportBeatmap(beatMap, *export, modsParsed, *speed, *ar, *od, *cs, *hp)
+			}
+
+			return
+		}
+
+		initialized := win != nil // Set up by a previous batch job
+
+		if !initialized {
+			assets.Init(build.Stream == "Dev")
+		}
 
 		if !closeAfterSettingsLoad {
 			log.Println("Initializing GLFW...")
@@ -351,6 +717,10 @@ func run() {
 		}
 
 		if closeAfterSettingsLoad {
+			if batchJob {
+				panic("Beatmap not found")
+			}
+
 			os.Exit(0)
 		}
 
@@ -364,12 +734,50 @@ func run() {
 			allowDA = true
 		}
 
+		// osu!mania maps can only be watched, without replays danser's autoplay is shown
+		if beatMap.Mode == beatmap.ModeMania {
+			if settings.PLAY {
+				panic("flag -play: osu!mania maps can't be played")
+			}
+
+			if !settings.KNOCKOUT {
+				log.Println("osu!mania map selected, switching to autoplay")
+
+				settings.KNOCKOUT = true
+				settings.Knockout.MaxPlayers = 0
+			}
+		}
+
 		lastSamples = int(settings.Graphics.MSAA)
 
+		skin2.Unload() // Skin may be loaded by a previous batch job
+		skin2.UnpackSkins()
+
 		if strings.TrimSpace(*skin) != "" {
+			if skin2.IsArchive(*skin) {
+				name, err := skin2.ImportSkin(*skin)
+				if err != nil {
+					panic(fmt.Sprintf("flag -skin: failed to import skin: %s", err))
+				}
+
+				*skin = name
+			}
+
 			settings.Skin.CurrentSkin = *skin
 		}
 
+		if *mover != "" {
+			for _, m := range settings.CursorDance.Movers {
+				m.Mover = strings.ToLower(*mover)
+			}
+		}
+
+		if !settings.PLAY && !settings.KNOCKOUT {
+			if err := movers.CheckScripts(); err != nil {
+				panic(err)
+			}
+		}
+
 		if *quickstart {
 			settings.SKIP = true
 			settings.Playfield.LeadInTime = 0
@@ -393,60 +801,66 @@ func run() {
 			settings.SKIP = false
 		}
 
-		if settings.Graphics.Fullscreen {
-			glfw.WindowHint(glfw.RedBits, monitor.GetVideoMode().RedBits)
-			glfw.WindowHint(glfw.GreenBits, monitor.GetVideoMode().GreenBits)
-			glfw.WindowHint(glfw.BlueBits, monitor.GetVideoMode().BlueBits)
-			glfw.WindowHint(glfw.RefreshRate, monitor.GetVideoMode().RefreshRate)
-			//glfw.WindowHint(glfw.Decorated, glfw.False)
-			win, err = glfw.CreateWindow(int(settings.Graphics.Width), int(settings.Graphics.Height), "danser", monitor, nil)
+		if initialized { // Batch jobs are recorded, so only the size has to be updated
+			win.SetSize(int(settings.Graphics.WindowWidth), int(settings.Graphics.WindowHeight))
 		} else {
-			win, err = glfw.CreateWindow(int(settings.Graphics.WindowWidth), int(settings.Graphics.WindowHeight), "danser", nil, nil)
-		}
+			if settings.Graphics.Fullscreen {
+				glfw.WindowHint(glfw.RedBits, monitor.GetVideoMode().RedBits)
+				glfw.WindowHint(glfw.GreenBits, monitor.GetVideoMode().GreenBits)
+				glfw.WindowHint(glfw.BlueBits, monitor.GetVideoMode().BlueBits)
+				glfw.WindowHint(glfw.RefreshRate, monitor.GetVideoMode().RefreshRate)
+				//glfw.WindowHint(glfw.Decorated, glfw.False)
+				win, err = glfw.CreateWindow(int(settings.Graphics.Width), int(settings.Graphics.Height), "danser", monitor, nil)
+			} else {
+				win, err = glfw.CreateWindow(int(settings.Graphics.WindowWidth), int(settings.Graphics.WindowHeight), "danser", nil, nil)
+			}
 
-		if err != nil {
-			panic(err)
-		}
+			if err != nil {
+				panic(err)
+			}
 
-		if !*record {
-			win.SetFocusCallback(func(w *glfw.Window, focused bool) {
-				log.Println("Focus changed: ", focused)
-				input.Focused = focused
-			})
-		}
+			if !*record {
+				win.SetFocusCallback(func(w *glfw.Window, focused bool) {
+					log.Println("Focus changed: ", focused)
+					input.Focused = focused
+				})
+			}
 
-		win.SetTitle("danser " + build.VERSION + " - " + beatMap.Artist + " - " + beatMap.Name + " [" + beatMap.Difficulty + "]")
-		input.Win = win
+			input.Win = win
 
-		if cTime := time.Now(); cTime.Month() == 12 && cTime.Day() >= 6 {
-			platform.LoadIcons(win, "dansercoin", "-s")
-		} else {
-			platform.LoadIcons(win, "dansercoin", "")
-		}
+			if cTime := time.Now(); cTime.Month() == 12 && cTime.Day() >= 6 {
+				platform.LoadIcons(win, "dansercoin", "-s")
+			} else {
+				platform.LoadIcons(win, "dansercoin", "")
+			}
 
-		win.MakeContextCurrent()
+			win.MakeContextCurrent()
 
-		log.Println("GLFW initialized!")
+			log.Println("GLFW initialized!")
 
-		err = platform.GLInit(*gldebug)
-		if err != nil {
-			panic("Failed to initialize OpenGL: " + err.Error())
-		}
+			err = platform.GLInit(*gldebug)
+			if err != nil {
+				panic("Failed to initialize OpenGL: " + err.Error())
+			}
+
+			if !settings.RECORD {
+				discord.Connect()
+				win.Show()
+			}
+
+			file, _ := assets.Open("assets/fonts/Quicksand-Bold.ttf")
+			font.LoadFont(file)
+			file.Close()
 
-		if !settings.RECORD {
-			discord.Connect()
-			win.Show()
+			batch = batch2.NewQuadBatch()
 		}
 
+		win.SetTitle("danser " + build.VERSION + " - " + beatMap.Artist + " - " + beatMap.Name + " [" + beatMap.Difficulty + "]")
+
 		gl.Enable(gl.BLEND)
 		gl.ClearColor(0, 0, 0, 1)
 		gl.Clear(gl.COLOR_BUFFER_BIT)
 
-		file, _ := assets.Open("assets/fonts/Quicksand-Bold.ttf")
-		font.LoadFont(file)
-		file.Close()
-
-		batch = batch2.NewQuadBatch()
 		batch.Begin()
 		batch.SetColor(1, 1, 1, 1)
 		camera := camera2.NewCamera()
@@ -463,7 +877,10 @@ func run() {
 		glfw.SwapInterval(1)
 		lastVSync = true
 
-		bass.Init(settings.RECORD)
+		if !initialized {
+			bass.Init(settings.RECORD)
+		}
+
 		audio.LoadSamples()
 
 		speedBefore := settings.SPEED
@@ -509,6 +926,15 @@ func run() {
 		limiter = frame.NewLimiter(int(settings.Graphics.FPSCap))
 	})
 
+	if startBatch != nil {
+		startBatch()
+		return
+	}
+
+	if player == nil { // headless modes don't create a player
+		return
+	}
+
 	if recordMode {
 		mainLoopRecord()
 	} else if screenshotMode {
@@ -518,6 +944,50 @@ func run() {
 	}
 }
 
+// getRecordingKey hashes everything that affects the recorded video, so only a recording with the same parameters is resumed
+func getRecordingKey(beatMap *beatmap.BeatMap) string {
+	hash := sha1.New()
+
+	hash.Write([]byte(build.VERSION))
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "events", "nodbcheck", "noupdatecheck", "noffmpegcheck", "preciseprogress":
+			return // Don't affect the output
+		}
+
+		hash.Write([]byte(fmt.Sprintf("-%s=%s\n", f.Name, f.Value.String())))
+	})
+
+	if data, err := json.Marshal(settings.GetFormat()); err == nil {
+		hash.Write(data)
+	}
+
+	// Files can be changed while flags pointing to them stay the same
+	hashFile(hash, beatMap.GetPath())
+
+	if settings.REPLAY != "" {
+		hashFile(hash, settings.REPLAY)
+	}
+
+	for _, path := range settings.KNOCKOUTREPLAYS {
+		hashFile(hash, path)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func hashFile(w io.Writer, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	_, _ = io.Copy(w, file)
+}
+
 func mainLoopRecord() {
 	count := int64(0)
 
@@ -532,11 +1002,13 @@ func mainLoopRecord() {
 
 	var fbo *buffer.Framebuffer
 
-	mainthread.Call(func() {
+	callMain(func() {
 		fbo = buffer.NewFrameMultisampleScreen(w, h, false, 0)
 	})
 
-	ffmpeg.StartFFmpeg(int(fps), w, h, audioFPS, output)
+	p, _ := player.(*states.Player)
+
+	ffmpeg.StartFFmpeg(int(fps), w, h, audioFPS, output, getRecordingKey(p.GetBeatMap()))
 
 	updateFPS := math.Max(fps, 1000)
 	updateDelta := 1000 / updateFPS
@@ -546,8 +1018,6 @@ func mainLoopRecord() {
 	deltaSumF := fpsDelta
 	deltaSumA := 0.0
 
-	p, _ := player.(*states.Player)
-
 	lastCount := int64(0)
 	lastRealTime := qpc.GetMilliTimeF()
 
@@ -567,18 +1037,20 @@ func mainLoopRecord() {
 
 		deltaSumF += updateDelta
 		if deltaSumF >= fpsDelta {
-			mainthread.Call(func() {
-				fbo.Bind()
+			callMain(func() {
+				if !ffmpeg.SkipFrame() { // Frames encoded in finished segments are only updated
+					fbo.Bind()
 
-				ffmpeg.PreFrame()
+					ffmpeg.PreFrame()
 
-				viewport.Push(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
-				pushFrame()
-				viewport.Pop()
+					viewport.Push(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
+					pushFrame()
+					viewport.Pop()
 
-				ffmpeg.MakeFrame()
+					ffmpeg.MakeFrame()
 
-				fbo.Unbind()
+					fbo.Unbind()
+				}
 
 				count++
 
@@ -598,6 +1070,8 @@ func mainLoopRecord() {
 
 					log.Println(fmt.Sprintf("Progress: %d%%, Speed: %.2fx, ETA: %s", progress, speed, etaText))
 
+					events.EmitProgress(float64(progress), speed, speed*fps, float64(eta))
+
 					lastProgress = progress
 
 					lastCount = count
@@ -609,8 +1083,9 @@ func mainLoopRecord() {
 		}
 	}
 
-	mainthread.Call(func() {
+	callMain(func() {
 		ffmpeg.StopFFmpeg()
+		fbo.Dispose()
 	})
 }
 
@@ -646,6 +1121,8 @@ func mainLoopSS() {
 }
 
 func mainLoopNormal() {
+	events.EmitStage(events.StagePlaying)
+
 	mainthread.Call(func() {
 		win.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
 			if action == glfw.Press {
@@ -659,6 +1136,7 @@ func mainLoopNormal() {
 				case glfw.KeyO:
 					if mods == glfw.ModControl {
 						log.Println("Launcher: Open settings")
+						events.EmitCommand(events.CommandOpenSettings)
 					}
 				default:
 					if kName, ok := platform.GetKeyName(key, scancode); ok && kName == settings.Input.ScreenshotKey {
@@ -740,7 +1218,12 @@ func pushFrame() {
 		screenFBO.Bind()
 	}
 
-	gl.ClearColor(0, 0, 0, 1)
+	if settings.RECORD && settings.Recording.HasAlpha() {
+		gl.ClearColor(0, 0, 0, 0)
+	} else {
+		gl.ClearColor(0, 0, 0, 1)
+	}
+
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 
 	if player != nil {
@@ -802,6 +1285,19 @@ func printPlatformInfo() {
 	log.Println("-------------------------------------------------------------------")
 }
 
+// hasEventsFlag checks whether -events flag is given, it's used before flags are parsed
+func hasEventsFlag(args []string) bool {
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		if strings.HasPrefix(arg, "-") && name == "events" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func Run() {
 	defer func() {
 		var err any
@@ -818,16 +1314,25 @@ func Run() {
 
 	log.Println("danser-go version:", build.VERSION)
 
-	file, err := os.Create(filepath.Join(env.DataDir(), "danser.log"))
+	var err error
+
+	// Appending keeps the log readable when danser processes started by -server write to it as well.
+	// Those processes get -events, so they don't clear the log of the server.
+	logFlags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if !hasEventsFlag(os.Args[1:]) {
+		logFlags |= os.O_TRUNC
+	}
+
+	logFile, err = os.OpenFile(filepath.Join(env.DataDir(), "danser.log"), logFlags, 0666)
 	if err != nil {
 		panic(err)
 	}
 
-	log.SetOutput(file)
+	log.SetOutput(logFile)
 
 	printPlatformInfo()
 
-	log.SetOutput(io.MultiWriter(os.Stdout, file))
+	log.SetOutput(io.MultiWriter(os.Stdout, logFile))
 
 	platform.DisableQuickEdit()
 
@@ -848,8 +1353,14 @@ func closeHandler(err any, stackTrace []string) {
 			log.Println(s)
 		}
 
+		events.EmitError(fmt.Sprint(err), stackTrace)
+		events.Close()
+
 		os.Exit(1)
 	}
 
 	log.Println("Exiting normally.")
+
+	events.EmitStage(events.StageFinished)
+	events.Close()
 }
@@ -0,0 +1,396 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/dance/movers"
+	"github.com/wieku/danser-go/app/database"
+	"github.com/wieku/danser-go/app/events"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/util"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	jobDone   = "done"
+	jobFailed = "failed"
+)
+
+// batchFile describes a batch of recordings given by -batch
+type batchFile struct {
+	Retries int // How many times a failed job is retried, can be overridden by a job
+	Jobs    []*batchJob
+}
+
+type batchJob struct {
+	Name string
+
+	Replay string
+	MD5    string
+	ID     *int64
+	Query  string
+
+	Settings string   // Settings profile, -settings flag is used if empty
+	Set      []string // Setting overrides, appended to -set flags
+	Mods     string
+	Start    *float64
+	End      *float64
+	Skin     string
+	Out      string
+	Args     []string // Additional flags passed to danser
+
+	Retries *int
+
+	status   string
+	attempts int
+	duration time.Duration
+	output   string
+	err      error
+}
+
+func loadBatchFile(path string) (*batchFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := new(batchFile)
+
+	if err = json.Unmarshal(data, batch); err != nil {
+		return nil, err
+	}
+
+	if len(batch.Jobs) == 0 {
+		return nil, errors.New("no jobs specified")
+	}
+
+	if batch.Retries < 0 {
+		return nil, errors.New("Retries can't be negative")
+	}
+
+	for i, job := range batch.Jobs {
+		if job == nil {
+			return nil, fmt.Errorf("job %d is empty", i+1)
+		}
+
+		if job.Name == "" {
+			job.Name = fmt.Sprintf("Job %d", i+1)
+		}
+
+		if job.Replay == "" && job.MD5 == "" && job.ID == nil && job.Query == "" {
+			return nil, fmt.Errorf("%s: Replay, MD5, ID or Query has to be specified", job.Name)
+		}
+
+		if job.Replay != "" && (job.MD5 != "" || job.ID != nil || job.Query != "") {
+			return nil, fmt.Errorf("%s: Replay can't be used with MD5, ID or Query", job.Name)
+		}
+
+		if job.Retries != nil && *job.Retries < 0 {
+			return nil, fmt.Errorf("%s: Retries can't be negative", job.Name)
+		}
+
+		if job.Settings == "credentials" || job.Settings == "launcher" {
+			return nil, fmt.Errorf("%s: settings name \"%s\" is forbidden", job.Name, job.Settings)
+		}
+	}
+
+	return batch, nil
+}
+
+// runBatch records jobs one after another in this process.
+// Database is loaded and ffmpeg's encoders are checked only once, before first job.
+func runBatch(batch *batchFile, noDbCheck bool, settingsVersion string, overrides []string) {
+	log.Println(fmt.Sprintf("Starting batch of %d jobs", len(batch.Jobs)))
+
+	settings.CloseWatcher() // Jobs load their own settings
+
+	if err := database.Init(); err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %s", err))
+	}
+
+	defer database.Close()
+
+	beatmaps := database.LoadBeatmaps(noDbCheck, nil)
+
+	ffmpeg.PreCheck()
+
+	batchStart := time.Now()
+
+	for i, job := range batch.Jobs {
+		retries := batch.Retries
+		if job.Retries != nil {
+			retries = *job.Retries
+		}
+
+		args := job.getArguments(settingsVersion, overrides)
+
+		for job.attempts <= retries {
+			job.attempts++
+
+			log.Println(fmt.Sprintf("Batch job %d/%d \"%s\", attempt %d/%d", i+1, len(batch.Jobs), job.Name, job.attempts, retries+1))
+
+			start := time.Now()
+
+			job.output, job.err = runBatchJob(args, beatmaps)
+
+			job.duration += time.Since(start)
+
+			if job.err == nil {
+				job.status = jobDone
+
+				log.Println(fmt.Sprintf("Batch job \"%s\" finished: %s", job.Name, job.output))
+
+				break
+			}
+
+			job.status = jobFailed
+
+			log.Println(fmt.Sprintf("Batch job \"%s\" failed: %s", job.Name, job.err))
+			events.EmitWarning(fmt.Sprintf("Batch job \"%s\" failed: %s", job.Name, job.err))
+		}
+	}
+
+	failed := printBatchSummary(batch, time.Since(batchStart))
+
+	if failed > 0 {
+		panic(fmt.Sprintf("%d of %d batch jobs failed", failed, len(batch.Jobs)))
+	}
+}
+
+// runBatchJob runs danser with given arguments and returns the path of recorded video
+func runBatchJob(args []string, beatmaps []*beatmap.BeatMap) (resultFile string, err error) {
+	events.SetListener(func(event events.Event) {
+		if event.Type == events.TypeOutput && (event.Kind == events.OutputVideo || event.Kind == events.OutputSequence) {
+			resultFile = event.Path
+		}
+	})
+
+	defer func() {
+		events.SetListener(nil)
+
+		if r := recover(); r != nil {
+			r, stackTrace := unwrapPanic(r)
+
+			log.Println("panic:", r)
+
+			for _, s := range stackTrace {
+				log.Println(s)
+			}
+
+			resultFile, err = "", fmt.Errorf("%v", r)
+		} else if resultFile == "" {
+			err = errors.New("no video was recorded, check the log for details")
+		}
+	}()
+
+	launch(args, beatmaps)
+
+	return
+}
+
+func (job *batchJob) getArguments(settingsVersion string, overrides []string) []string {
+	args := []string{"-record", "-nodbcheck", "-noupdatecheck"}
+
+	if job.Settings == "" || job.Settings == settingsVersion {
+		job.Settings = settingsVersion
+
+		// Encoders may be changed only by a different profile or overrides
+		if len(job.Set) == 0 {
+			args = append(args, "-noffmpegcheck")
+		}
+	}
+
+	if job.Settings != "" {
+		args = append(args, "-settings", job.Settings)
+	}
+
+	for _, o := range overrides {
+		args = append(args, "-set", o)
+	}
+
+	for _, o := range job.Set {
+		args = append(args, "-set", o)
+	}
+
+	if job.Replay != "" {
+		args = append(args, "-replay", job.Replay)
+	}
+
+	if job.MD5 != "" {
+		args = append(args, "-md5", job.MD5)
+	}
+
+	if job.ID != nil {
+		args = append(args, "-id", strconv.FormatInt(*job.ID, 10))
+	}
+
+	if job.Query != "" {
+		args = append(args, "-query", job.Query)
+	}
+
+	if job.Mods != "" {
+		args = append(args, "-mods", job.Mods)
+	}
+
+	if job.Start != nil {
+		args = append(args, "-start", strconv.FormatFloat(*job.Start, 'f', -1, 64))
+	}
+
+	if job.End != nil {
+		args = append(args, "-end", strconv.FormatFloat(*job.End, 'f', -1, 64))
+	}
+
+	if job.Skin != "" {
+		args = append(args, "-skin", job.Skin)
+	}
+
+	if job.Out != "" {
+		args = append(args, "-out", job.Out)
+	}
+
+	return append(args, job.Args...)
+}
+
+// checkScripts compiles mover scripts of job's settings, so a broken script fails the job without starting danser.
+// It has to be called after getArguments which resolves job's settings name.
+func (job *batchJob) checkScripts(overrides []string) error {
+	config, err := settings.ReadSettings(job.Settings, append(append([]string{}, overrides...), job.Set...))
+	if err != nil {
+		return err
+	}
+
+	return movers.CheckConfigScripts(config)
+}
+
+// runDanserProcess runs danser with given arguments and returns the path of recorded video.
+// Events emitted by the process are passed to the listener, process is killed if ctx is cancelled.
+func runDanserProcess(ctx context.Context, dExec string, args []string, listener func(event events.Event)) (string, error) {
+	cmd := exec.CommandContext(ctx, dExec, append([]string{"-events", "stderr"}, args...)...)
+	cmd.Stdout = os.Stdout
+
+	// Processes started by danser (like ffmpeg) may inherit the pipe and keep it open after danser is killed,
+	// so it's closed on our side when ctx is cancelled instead of waiting for EOF
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+
+	defer stderr.Close()
+
+	cmd.Stderr = stderrW
+
+	err = cmd.Start()
+
+	stderrW.Close()
+
+	if err != nil {
+		return "", err
+	}
+
+	streamDone := make(chan struct{})
+	defer close(streamDone)
+
+	goroutines.Run(func() {
+		select {
+		case <-ctx.Done():
+			stderr.Close()
+		case <-streamDone:
+		}
+	})
+
+	panicMessage := ""
+	resultFile := ""
+
+	streamWait := &sync.WaitGroup{}
+	streamWait.Add(1)
+
+	goroutines.Run(func() {
+		defer streamWait.Done()
+
+		sc := bufio.NewScanner(stderr)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for sc.Scan() {
+			event, ok := events.Parse(sc.Text())
+			if !ok { // Not an event, probably go runtime's output
+				_, _ = fmt.Fprintln(os.Stderr, sc.Text())
+				continue
+			}
+
+			switch event.Type {
+			case events.TypeError:
+				panicMessage = event.Message
+			case events.TypeOutput:
+				if event.Kind == events.OutputVideo || event.Kind == events.OutputSequence {
+					resultFile = event.Path
+				}
+			}
+
+			if listener != nil {
+				listener(event)
+			}
+		}
+	})
+
+	streamWait.Wait()
+
+	err = cmd.Wait()
+
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	if panicMessage != "" {
+		return "", errors.New(panicMessage)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if resultFile == "" {
+		return "", errors.New("no video was recorded, check the log for details")
+	}
+
+	return resultFile, nil
+}
+
+func printBatchSummary(batch *batchFile, duration time.Duration) (failed int) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Job", "Status", "Attempts", "Time", "Result"})
+
+	for i, job := range batch.Jobs {
+		result := job.output
+		if job.status == jobFailed {
+			result = job.err.Error()
+			failed++
+		}
+
+		table.Append([]string{
+			strconv.Itoa(i + 1),
+			job.Name,
+			job.status,
+			strconv.Itoa(job.attempts),
+			util.FormatSeconds(int(job.duration.Seconds())),
+			result,
+		})
+	}
+
+	table.Render()
+
+	fmt.Printf("Batch finished in %s: %d done, %d failed\n", util.FormatSeconds(int(duration.Seconds())), len(batch.Jobs)-failed, failed)
+
+	return
+}
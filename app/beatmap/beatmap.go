@@ -4,13 +4,25 @@ import (
 	"github.com/wieku/danser-go/app/audio"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/mutils"
 	"golang.org/x/exp/slices"
 	"math"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Game modes stored in Mode
+const (
+	ModeStandard = int64(iota)
+	ModeTaiko
+	ModeCatch
+	ModeMania
+)
+
 type BeatMap struct {
 	Artist        string
 	ArtistUnicode string
@@ -57,6 +69,13 @@ type BeatMap struct {
 	processed  []objects.IHitObject
 	Version    int
 
+	ComboColors []color.Color // Combo colors from [Colours] section, sorted by their number
+
+	extraEvents []string // Video and storyboard lines of [Events] section, without breaks and background
+	extraColors []string // Lines of [Colours] section other than combo colors, like SliderBorder
+
+	Warnings []*ParseError // Malformed lines skipped or only partially parsed by the parser
+
 	ARSpecified bool
 
 	LocalOffset int
@@ -75,6 +94,20 @@ func NewBeatMap() *BeatMap {
 	return beatMap
 }
 
+// GetPath returns the path of .osu file. Dir is relative to Songs directory unless it's absolute, like for beatmaps loaded by -calc
+func (beatMap *BeatMap) GetPath() string {
+	if filepath.IsAbs(beatMap.Dir) {
+		return filepath.Join(beatMap.Dir, beatMap.File)
+	}
+
+	return filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.File)
+}
+
+// GetManiaKeys returns the number of columns in osu!mania map, it's stored as CS
+func (beatMap *BeatMap) GetManiaKeys() int {
+	return mutils.Clamp(int(math.Round(beatMap.Diff.GetBaseCS())), 1, 18)
+}
+
 func (beatMap *BeatMap) Reset() {
 	beatMap.Queue = beatMap.GetObjectsCopy()
 	beatMap.processed = make([]objects.IHitObject, 0)
@@ -90,6 +123,24 @@ func (beatMap *BeatMap) Clear() {
 	beatMap.Timings = objects.NewTimings()
 }
 
+// Copy returns a beatmap with the same metadata and difficulty but without timing points and objects,
+// so a beatmap loaded from database can be parsed and played more than once
+func (beatMap *BeatMap) Copy() *BeatMap {
+	bCopy := *beatMap
+
+	diff := *beatMap.Diff
+	bCopy.Diff = &diff
+
+	bCopy.Clear()
+
+	bCopy.Pauses = nil
+	bCopy.Warnings = nil
+	bCopy.Queue = nil
+	bCopy.processed = nil
+
+	return &bCopy
+}
+
 func (beatMap *BeatMap) Update(time float64) {
 	beatMap.Timings.Update(time)
 
@@ -52,6 +52,10 @@ func parseExtras(data []string, extraIndex int) (info audio.HitSoundInfo) {
 			volume, _ := strconv.Atoi(extras[3])
 			info.CustomVolume = float64(volume) / 100.0
 		}
+
+		if len(extras) > 4 {
+			info.Filename = extras[4]
+		}
 	}
 
 	return
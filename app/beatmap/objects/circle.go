@@ -119,10 +119,24 @@ func (circle *Circle) PlaySound() {
 		return
 	}
 
+	sampleSet, additionSet, hitsound, index := circle.GetHitSound(0)
+
+	audio.PlaySample(sampleSet, additionSet, hitsound, index, circle.Timings.GetPointAt(circle.StartTime).SampleVolume, circle.HitObjectID, circle.GetStackedStartPosition().X64())
+}
+
+func (circle *Circle) GetEdgeCount() int {
+	return 1
+}
+
+func (circle *Circle) GetEdgeTime(_ int) float64 {
+	return circle.StartTime
+}
+
+func (circle *Circle) GetHitSound(_ int) (sampleSet, additionSet, hitsound, index int) {
 	point := circle.Timings.GetPointAt(circle.StartTime)
 
-	index := circle.BasicHitSound.CustomIndex
-	sampleSet := circle.BasicHitSound.SampleSet
+	index = circle.BasicHitSound.CustomIndex
+	sampleSet = circle.BasicHitSound.SampleSet
 
 	if index == 0 {
 		index = point.SampleIndex
@@ -132,7 +146,12 @@ func (circle *Circle) PlaySound() {
 		sampleSet = point.SampleSet
 	}
 
-	audio.PlaySample(sampleSet, circle.BasicHitSound.AdditionSet, circle.sample, index, point.SampleVolume, circle.HitObjectID, circle.GetStackedStartPosition().X64())
+	additionSet = circle.BasicHitSound.AdditionSet
+	if additionSet == 0 {
+		additionSet = sampleSet
+	}
+
+	return sampleSet, additionSet, circle.sample, index
 }
 
 func (circle *Circle) SetTiming(timings *Timings, _ int, _ bool) {
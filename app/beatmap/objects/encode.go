@@ -0,0 +1,167 @@
+package objects
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/framework/math/curves"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Transform describes changes applied to hit objects when they are encoded
+type Transform struct {
+	FlipY bool    // Flip objects vertically like HardRock does
+	Speed float64 // Playback speed baked into times, 0 is treated as 1
+}
+
+// Time returns time after applying the speed change
+func (t Transform) Time(time float64) float64 {
+	if t.Speed == 0 {
+		return time
+	}
+
+	return time / t.Speed
+}
+
+// Position returns position after flipping
+func (t Transform) Position(pos vector.Vector2f) vector.Vector2f {
+	if t.FlipY {
+		pos.Y = 384 - pos.Y
+	}
+
+	return pos
+}
+
+// EncodeObject returns hit object as a line of [HitObjects] section
+func EncodeObject(obj IHitObject, transform Transform) (string, error) {
+	switch o := obj.(type) {
+	case *Circle:
+		return o.encode(transform), nil
+	case *Slider:
+		return o.encode(transform), nil
+	case *Spinner:
+		return o.encode(transform), nil
+	case *ManiaNote:
+		return o.encode(transform), nil
+	}
+
+	return "", fmt.Errorf("unsupported hit object type %T", obj)
+}
+
+func (hitObject *HitObject) encodeCommon(transform Transform, objType Type, sample int) []string {
+	if hitObject.NewCombo {
+		objType |= NEWCOMBO
+	}
+
+	objType |= Type(hitObject.ColorOffset << 4)
+
+	pos := transform.Position(hitObject.StartPosRaw)
+
+	return []string{
+		formatFloat32(pos.X),
+		formatFloat32(pos.Y),
+		formatTime(transform.Time(hitObject.StartTime)),
+		strconv.Itoa(int(objType)),
+		strconv.Itoa(sample),
+	}
+}
+
+func (circle *Circle) encode(transform Transform) string {
+	values := circle.encodeCommon(transform, CIRCLE, circle.sample)
+
+	return strings.Join(append(values, encodeHitSample(circle.BasicHitSound)), ",")
+}
+
+func (spinner *Spinner) encode(transform Transform) string {
+	values := spinner.encodeCommon(transform, SPINNER, spinner.sample)
+
+	return strings.Join(append(values, formatTime(transform.Time(spinner.EndTime)), encodeHitSample(spinner.BasicHitSound)), ",")
+}
+
+// encode keeps note's original X, HardRock doesn't flip osu!mania notes
+func (note *ManiaNote) encode(transform Transform) string {
+	values := note.encodeCommon(Transform{Speed: transform.Speed}, note.GetType(), note.sample)
+	values[0] = formatFloat32(note.rawX)
+	values[1] = "192"
+
+	if note.hold {
+		return strings.Join(append(values, formatTime(transform.Time(note.EndTime))+":"+encodeHitSample(note.BasicHitSound)), ",")
+	}
+
+	return strings.Join(append(values, encodeHitSample(note.BasicHitSound)), ",")
+}
+
+func (slider *Slider) encode(transform Transform) string {
+	values := slider.encodeCommon(transform, SLIDER, slider.baseSample)
+
+	edgeSounds := make([]string, len(slider.samples))
+	edgeSets := make([]string, len(slider.samples))
+
+	for i := range slider.samples {
+		edgeSounds[i] = strconv.Itoa(slider.samples[i])
+		edgeSets[i] = fmt.Sprintf("%d:%d", slider.sampleSets[i], slider.additionSets[i])
+	}
+
+	values = append(values,
+		encodeCurve(slider.curveDefs, transform),
+		strconv.Itoa(slider.RepeatCount),
+		strconv.FormatFloat(slider.pixelLength, 'f', -1, 64),
+		strings.Join(edgeSounds, "|"),
+		strings.Join(edgeSets, "|"),
+		encodeHitSample(slider.BasicHitSound),
+	)
+
+	return strings.Join(values, ",")
+}
+
+// encodeCurve writes curve definitions in lazer's format. Type of the next definition precedes the point shared with the previous one.
+func encodeCurve(defs []curves.CurveDef, transform Transform) string {
+	var parts []string
+
+	for i, def := range defs {
+		if i == 0 {
+			parts = append(parts, getCurveLetter(def.CurveType))
+		}
+
+		// First point is either slider's head or the last point of the previous definition
+		for j := 1; j < len(def.Points); j++ {
+			if j == len(def.Points)-1 && i < len(defs)-1 {
+				parts = append(parts, getCurveLetter(defs[i+1].CurveType))
+			}
+
+			pos := transform.Position(def.Points[j])
+
+			parts = append(parts, formatFloat32(pos.X)+":"+formatFloat32(pos.Y))
+		}
+	}
+
+	return strings.Join(parts, "|")
+}
+
+func getCurveLetter(cType curves.CType) string {
+	switch cType {
+	case curves.CCirArc:
+		return "P"
+	case curves.CLine:
+		return "L"
+	case curves.CBezier:
+		return "B"
+	default:
+		return "C"
+	}
+}
+
+// encodeHitSample writes sample info including custom sample file name
+func encodeHitSample(info audio.HitSoundInfo) string {
+	return fmt.Sprintf("%d:%d:%d:%d:%s", info.SampleSet, info.AdditionSet, info.CustomIndex, int(math.Round(info.CustomVolume*100)), info.Filename)
+}
+
+func formatTime(time float64) string {
+	return strconv.FormatInt(int64(math.Round(time)), 10)
+}
+
+func formatFloat32(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
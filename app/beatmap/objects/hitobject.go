@@ -65,6 +65,15 @@ type ILongObject interface {
 	GetPartLen() float32
 }
 
+// IHasHitSound is implemented by objects that play hitsounds when hit, sliders play them at each edge
+type IHasHitSound interface {
+	GetEdgeCount() int
+	GetEdgeTime(edge int) float64
+
+	// GetHitSound returns resolved sample and addition sets, additions bitmask and sample index of the edge
+	GetHitSound(edge int) (sampleSet, additionSet, hitsound, index int)
+}
+
 type HitObject struct {
 	StartPosRaw vector.Vector2f
 	EndPosRaw   vector.Vector2f
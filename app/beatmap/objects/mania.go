@@ -0,0 +1,189 @@
+package objects
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Layout of osu!mania stage in osu!pixels. Notes are placed on the judgement line of their column,
+// so hit results and sample panning follow the column.
+const (
+	ManiaHitPosition = 384.0
+
+	maniaStageWidth     = 320.0
+	maniaMaxColumnWidth = 40.0
+)
+
+// GetManiaColumnWidth returns width of a single column in osu!pixels
+func GetManiaColumnWidth(keys int) float64 {
+	return math.Min(maniaMaxColumnWidth, maniaStageWidth/float64(keys))
+}
+
+// GetManiaColumnX returns X of column's centre, stage is centred on the playfield
+func GetManiaColumnX(column, keys int) float64 {
+	width := GetManiaColumnWidth(keys)
+
+	return 256 - width*float64(keys)/2 + width*(float64(column)+0.5)
+}
+
+// ManiaNoteState is used by the renderer to show how the note was judged
+type ManiaNoteState struct {
+	Hit        bool    // Note or hold's head was hit
+	Missed     bool    // Note, head or tail was missed
+	Holding    bool    // Hold is being held
+	Finished   bool    // Note was hit or hold's tail was judged as a hit
+	FinishTime float64 // Time when note was finished
+}
+
+type ManiaNote struct {
+	*HitObject
+
+	Column int
+	Keys   int
+
+	Timings *Timings
+
+	rawX     float32
+	sample   int
+	hold     bool
+	lastTime float64
+
+	state ManiaNoteState
+}
+
+func NewManiaNote(data []string, keys int) *ManiaNote {
+	objType, _ := strconv.Atoi(data[3])
+
+	note := &ManiaNote{
+		Keys: keys,
+		hold: (Type(objType) & LONGNOTE) > 0,
+	}
+
+	var endTime string
+
+	if note.hold && len(data) > 5 {
+		// Hold's end time precedes the hit sample: endTime:sampleSet:additionSet:index:volume:filename
+		parts := strings.SplitN(data[5], ":", 2)
+
+		endTime = parts[0]
+
+		data = append(data[:5:5], "")
+		if len(parts) > 1 {
+			data[5] = parts[1]
+		}
+	}
+
+	note.HitObject = commonParse(data, 5)
+
+	if note.hold {
+		note.EndTime, _ = strconv.ParseFloat(endTime, 64)
+		note.EndTime = math.Max(note.EndTime, note.StartTime)
+	}
+
+	note.rawX = note.StartPosRaw.X
+	note.Column = mutils.Clamp(int(math.Floor(float64(note.rawX)*float64(keys)/512)), 0, keys-1)
+
+	note.StartPosRaw = vector.NewVec2f(float32(GetManiaColumnX(note.Column, keys)), ManiaHitPosition)
+	note.EndPosRaw = note.StartPosRaw
+
+	sample, _ := strconv.Atoi(data[4])
+	note.sample = sample
+
+	return note
+}
+
+func (note *ManiaNote) Update(time float64) bool {
+	if ((!settings.PLAY && !settings.KNOCKOUT) || settings.PLAYERS > 1) && note.lastTime < note.StartTime && time >= note.StartTime {
+		note.Arm(true, note.StartTime)
+		note.PlaySound()
+	}
+
+	if note.hold && ((!settings.PLAY && !settings.KNOCKOUT) || settings.PLAYERS > 1) && note.lastTime < note.EndTime && time >= note.EndTime {
+		note.ArmEnd(true, note.EndTime)
+	}
+
+	note.lastTime = time
+
+	return true
+}
+
+func (note *ManiaNote) PlaySound() {
+	if note.audioSubmissionDisabled {
+		return
+	}
+
+	point := note.Timings.GetPointAt(note.StartTime)
+
+	index := note.BasicHitSound.CustomIndex
+	sampleSet := note.BasicHitSound.SampleSet
+
+	if index == 0 {
+		index = point.SampleIndex
+	}
+
+	if sampleSet == 0 {
+		sampleSet = point.SampleSet
+	}
+
+	audio.PlaySample(sampleSet, note.BasicHitSound.AdditionSet, note.sample, index, point.SampleVolume, note.HitObjectID, note.StartPosRaw.X64())
+}
+
+func (note *ManiaNote) SetTiming(timings *Timings, _ int, _ bool) {
+	note.Timings = timings
+}
+
+// Arm marks note or hold's head as hit or missed
+func (note *ManiaNote) Arm(hit bool, time float64) {
+	if !hit {
+		note.state.Missed = true
+		note.state.Holding = false
+
+		return
+	}
+
+	note.state.Hit = true
+	note.state.Holding = note.hold
+
+	if !note.hold {
+		note.state.Finished = true
+		note.state.FinishTime = time
+	}
+}
+
+// ArmEnd marks hold's tail as hit or missed
+func (note *ManiaNote) ArmEnd(hit bool, time float64) {
+	note.state.Holding = false
+
+	if hit {
+		note.state.Finished = true
+		note.state.FinishTime = time
+	} else {
+		note.state.Missed = true
+	}
+}
+
+// SetHolding is used when hold with missed head is pressed again
+func (note *ManiaNote) SetHolding(holding bool) {
+	note.state.Holding = holding
+}
+
+func (note *ManiaNote) GetState() ManiaNoteState {
+	return note.state
+}
+
+func (note *ManiaNote) IsHold() bool {
+	return note.hold
+}
+
+func (note *ManiaNote) GetType() Type {
+	if note.hold {
+		return LONGNOTE
+	}
+
+	return CIRCLE
+}
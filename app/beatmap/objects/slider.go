@@ -52,6 +52,7 @@ type Slider struct {
 	*HitObject
 
 	multiCurve  *curves.MultiCurve
+	curveDefs   []curves.CurveDef
 	scorePath   []PathLine
 	Timings     *Timings
 	TPoint      TimingPoint
@@ -245,6 +246,8 @@ func (slider *Slider) parseCurve(curveData string) *curves.MultiCurve {
 		}
 	}
 
+	slider.curveDefs = defs
+
 	return curves.NewMultiCurveT(defs, slider.pixelLength)
 }
 
@@ -972,12 +975,39 @@ func (slider *Slider) PlayEdgeSample(index int) {
 		return
 	}
 
-	sampleSet := slider.sampleSets[index]
-	if sampleSet == 0 && index == 0 {
+	sampleSet, additionSet, hitsound, sampleIndex := slider.GetHitSound(index)
+
+	edgeTime := slider.GetEdgeTime(index)
+
+	audio.PlaySample(sampleSet, additionSet, hitsound, sampleIndex, slider.Timings.GetPointAt(edgeTime+5).SampleVolume, slider.HitObjectID, slider.GetStackedPositionAt(edgeTime).X64())
+}
+
+func (slider *Slider) GetEdgeCount() int {
+	return len(slider.samples)
+}
+
+func (slider *Slider) GetEdgeTime(edge int) float64 {
+	return slider.StartTime + math.Floor(float64(edge)*slider.partLen)
+}
+
+func (slider *Slider) GetHitSound(edge int) (sampleSet, additionSet, hitsound, index int) {
+	point := slider.Timings.GetPointAt(slider.GetEdgeTime(edge) + 5)
+
+	sampleSet = slider.sampleSets[edge]
+	if sampleSet == 0 && edge == 0 {
 		sampleSet = slider.BasicHitSound.SampleSet
 	}
 
-	slider.playSampleT(sampleSet, slider.additionSets[index], slider.samples[index], slider.Timings.GetPointAt(slider.StartTime+math.Floor(float64(index)*slider.partLen)+5), slider.GetStackedPositionAt(slider.StartTime+math.Floor(float64(index)*slider.partLen)))
+	if sampleSet == 0 {
+		sampleSet = point.SampleSet
+	}
+
+	additionSet = slider.additionSets[edge]
+	if additionSet == 0 {
+		additionSet = sampleSet
+	}
+
+	return sampleSet, additionSet, slider.samples[edge], point.SampleIndex
 }
 
 func (slider *Slider) HitEdge(index int, time float64, isHit bool) {
@@ -1001,18 +1031,6 @@ func (slider *Slider) PlayTick() {
 	audio.PlaySliderTick(slider.Timings.Current.SampleSet, slider.Timings.Current.SampleIndex, slider.Timings.Current.SampleVolume, slider.HitObjectID, slider.Pos.X64())
 }
 
-func (slider *Slider) playSampleT(sampleSet, additionSet, sample int, point TimingPoint, pos vector.Vector2f) {
-	if sampleSet == 0 {
-		sampleSet = point.SampleSet
-	}
-
-	if additionSet == 0 {
-		additionSet = sampleSet
-	}
-
-	audio.PlaySample(sampleSet, additionSet, sample, point.SampleIndex, point.SampleVolume, slider.HitObjectID, pos.X64())
-}
-
 func (slider *Slider) GetPosition() vector.Vector2f {
 	return slider.Pos
 }
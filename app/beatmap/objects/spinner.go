@@ -137,6 +137,7 @@ func (spinner *Spinner) SetDifficulty(diff *difficulty.Difficulty) {
 		spinner.middle.ResetValuesToTransforms()
 	} else {
 		spinner.background = sprite.NewSpriteSingle(skin.GetTexture("spinner-background"), 0.0, vector.NewVec2d(spinner.ScaledWidth/2, 46.5+350.4), vector.Centre)
+		spinner.background.SetColor(skin.GetInfo().SpinnerBackground)
 
 		sMetre := skin.GetTexture("spinner-metre")
 
@@ -315,19 +316,38 @@ func (spinner *Spinner) Hit(_ float64, isHit bool) {
 		return
 	}
 
+	sampleSet, additionSet, hitsound, index := spinner.GetHitSound(0)
+
+	audio.PlaySample(sampleSet, additionSet, hitsound, index, spinner.Timings.GetPointAt(spinner.EndTime).SampleVolume, spinner.HitObjectID, spinner.StartPosRaw.X64())
+}
+
+func (spinner *Spinner) GetEdgeCount() int {
+	return 1
+}
+
+func (spinner *Spinner) GetEdgeTime(_ int) float64 {
+	return spinner.EndTime
+}
+
+func (spinner *Spinner) GetHitSound(_ int) (sampleSet, additionSet, hitsound, index int) {
 	point := spinner.Timings.GetPointAt(spinner.EndTime)
 
-	index := spinner.BasicHitSound.CustomIndex
+	index = spinner.BasicHitSound.CustomIndex
 	if index == 0 {
 		index = point.SampleIndex
 	}
 
-	sampleSet := spinner.BasicHitSound.SampleSet
+	sampleSet = spinner.BasicHitSound.SampleSet
 	if sampleSet == 0 {
 		sampleSet = point.SampleSet
 	}
 
-	audio.PlaySample(sampleSet, spinner.BasicHitSound.AdditionSet, spinner.sample, index, point.SampleVolume, spinner.HitObjectID, spinner.StartPosRaw.X64())
+	additionSet = spinner.BasicHitSound.AdditionSet
+	if additionSet == 0 {
+		additionSet = sampleSet
+	}
+
+	return sampleSet, additionSet, spinner.sample, index
 }
 
 func (spinner *Spinner) SetRotation(f float64) {
@@ -1,7 +1,6 @@
 package objects
 
 import (
-	"github.com/wieku/danser-go/app/settings"
 	"strconv"
 )
 
@@ -12,9 +11,7 @@ func CreateObject(data []string) IHitObject {
 	if (objType & CIRCLE) > 0 {
 		return NewCircle(data)
 	} else if (objType & SPINNER) > 0 {
-		if settings.Objects.LoadSpinners || settings.KNOCKOUT || settings.PLAY {
-			return NewSpinner(data)
-		}
+		return NewSpinner(data)
 	} else if (objType & SLIDER) > 0 {
 		if sl := NewSlider(data); sl != nil {
 			return sl
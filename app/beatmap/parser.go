@@ -1,14 +1,22 @@
 package beatmap
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/wieku/danser-go/app/beatmap/objects"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/skin"
 	"github.com/wieku/danser-go/framework/files"
+	"github.com/wieku/danser-go/framework/math/color"
 	"github.com/wieku/danser-go/framework/math/mutils"
+	"io"
+	"io/fs"
+	"log"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -81,7 +89,13 @@ func parseDifficulty(line []string, beatMap *BeatMap) {
 		beatMap.ARSpecified = true
 	case "CircleSize":
 		parsed, _ := strconv.ParseFloat(line[1], 64)
-		beatMap.Diff.SetCS(mutils.ClampF(parsed, 0, 10))
+
+		// It's the key count in osu!mania
+		if beatMap.Mode == ModeMania {
+			beatMap.Diff.SetCS(mutils.ClampF(parsed, 1, 18))
+		} else {
+			beatMap.Diff.SetCS(mutils.ClampF(parsed, 0, 10))
+		}
 	case "SliderTickRate":
 		beatMap.Timings.TickRate, _ = strconv.ParseFloat(line[1], 64)
 	case "HPDrainRate":
@@ -97,18 +111,139 @@ func parseDifficulty(line []string, beatMap *BeatMap) {
 	}
 }
 
-func parseEvents(line []string, beatMap *BeatMap) {
+func parseEvents(raw string, line []string, beatMap *BeatMap, options ParseOptions) error {
 	switch line[0] {
 	case "Background", "0":
-		beatMap.Bg = strings.Replace(line[2], "\"", "", -1)
+		if len(line) < 3 {
+			return fmt.Errorf("expected at least 3 values, got %d", len(line))
+		}
+
+		if !options.SkipMetadata {
+			beatMap.Bg = strings.Replace(line[2], "\"", "", -1)
+		}
 	case "Break", "2":
-		beatMap.Pauses = append(beatMap.Pauses, NewPause(line))
+		if len(line) < 3 {
+			return fmt.Errorf("expected at least 3 values, got %d", len(line))
+		}
+
+		if !options.SkipTimings {
+			beatMap.Pauses = append(beatMap.Pauses, NewPause(line))
+		}
+	default:
+		// Video and storyboard are not used by danser, they are kept only for Encode
+		if !options.SkipMetadata && !options.SkipObjects {
+			beatMap.extraEvents = append(beatMap.extraEvents, raw)
+		}
 	}
+
+	return nil
 }
 
-func parseHitObjects(line []string, beatMap *BeatMap) {
+func validatePoint(line []string) error {
+	if _, err := strconv.ParseFloat(line[0], 64); err != nil {
+		return fmt.Errorf("invalid time %q", line[0])
+	}
+
+	if _, err := strconv.ParseFloat(line[1], 64); err != nil {
+		return fmt.Errorf("invalid beat length %q", line[1])
+	}
+
+	return nil
+}
+
+func parseComboColor(line []string) (comboColor, error) {
+	index, err := strconv.Atoi(strings.TrimPrefix(line[0], "Combo"))
+	if err != nil {
+		return comboColor{}, fmt.Errorf("invalid combo color name %q", line[0])
+	}
+
+	// Missing components are left at full intensity, like in skin.ini
+	divided := strings.Split(line[1], ",")
+
+	clr := color.NewL(1)
+	components := []*float32{&clr.R, &clr.G, &clr.B, &clr.A}
+
+	for i := 0; i < len(divided) && i < len(components); i++ {
+		value, err := strconv.ParseFloat(strings.TrimSpace(divided[i]), 64)
+		if err != nil {
+			return comboColor{}, fmt.Errorf("invalid color %q", line[1])
+		}
+
+		*components[i] = float32(value) / 255
+	}
+
+	return comboColor{index: index, color: clr}, nil
+}
+
+// validateObject checks whether hit object has all values needed by its type
+func validateObject(line []string) error {
+	if len(line) < 5 {
+		return fmt.Errorf("expected at least 5 values, got %d", len(line))
+	}
+
+	if _, err := strconv.ParseFloat(line[2], 64); err != nil {
+		return fmt.Errorf("invalid time %q", line[2])
+	}
+
+	objTypeI, err := strconv.Atoi(line[3])
+	if err != nil {
+		return fmt.Errorf("invalid type %q", line[3])
+	}
+
+	required := 5
+
+	objType := objects.Type(objTypeI)
+	if (objType & objects.CIRCLE) > 0 {
+		required = 5
+	} else if (objType&objects.SPINNER) > 0 || (objType&objects.LONGNOTE) > 0 {
+		required = 6
+	} else if (objType & objects.SLIDER) > 0 {
+		required = 8
+	}
+
+	if len(line) < required {
+		return fmt.Errorf("expected at least %d values, got %d", required, len(line))
+	}
+
+	return nil
+}
+
+// countObject counts objects by their type and updates beatmap's length
+func countObject(line []string, beatMap *BeatMap) {
+	var time string
+
+	objTypeI, _ := strconv.Atoi(line[3])
+	objType := objects.Type(objTypeI)
+	if (objType & objects.CIRCLE) > 0 {
+		beatMap.Circles++
+		time = line[2]
+	} else if (objType & objects.SPINNER) > 0 {
+		beatMap.Spinners++
+		time = line[5]
+	} else if (objType & objects.SLIDER) > 0 {
+		beatMap.Sliders++
+		time = line[2]
+	} else if (objType & objects.LONGNOTE) > 0 {
+		beatMap.Sliders++
+		time = strings.Split(line[5], ":")[0]
+	}
+	timeI, _ := strconv.Atoi(time)
+
+	beatMap.Length = mutils.Max(beatMap.Length, timeI)
+}
+
+func parseHitObjects(line []string, beatMap *BeatMap, options ParseOptions) {
+	if beatMap.Mode == ModeMania {
+		beatMap.HitObjects = append(beatMap.HitObjects, objects.NewManiaNote(line, beatMap.GetManiaKeys()))
+		return
+	}
+
 	obj := objects.CreateObject(line)
 
+	if _, ok := obj.(*objects.Spinner); ok && options.SkipSpinners {
+		return
+	}
+
 	if obj != nil {
 		beatMap.HitObjects = append(beatMap.HitObjects, obj)
 	}
@@ -148,27 +283,80 @@ var bufferPool = &sync.Pool{
 	},
 }
 
-func ParseBeatMap(beatMap *BeatMap) error {
-	file, err := os.Open(filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.File))
-	if err != nil {
-		return err
+// ParseError describes a line of .osu file that couldn't be parsed
+type ParseError struct {
+	Line    int
+	Section string
+	Err     error
+}
+
+func (err *ParseError) Error() string {
+	if err.Section == "" {
+		return fmt.Sprintf("line %d: %s", err.Line, err.Err)
 	}
 
-	defer file.Close()
+	return fmt.Sprintf("line %d [%s]: %s", err.Line, err.Section, err.Err)
+}
 
-	scanner := files.NewScanner(file)
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// ParseOptions select parts of .osu file that are parsed, zero value parses everything
+type ParseOptions struct {
+	Strict       bool // Malformed lines fail parsing instead of being skipped and stored in Warnings
+	SkipMetadata bool // [General], [Metadata] and [Difficulty] sections, background, object counts and MD5
+	SkipTimings  bool // Timing points and breaks
+	SkipObjects  bool // Hit objects and [Colours] section
+	DiffCalcOnly bool // Hit objects are prepared only for difficulty calculation
+	SkipSpinners bool // Spinners are not created
+}
+
+type comboColor struct {
+	index int
+	color color.Color
+}
+
+// ParseFromReader parses all sections of .osu file in a single pass. Hit objects are appended to the existing ones.
+// Malformed lines are skipped (timing points with junk values are still kept) and added to beatmap's Warnings, unless options are Strict.
+// Dir and File are not touched, they have to be set by the caller if beatmap's files (audio, background, storyboard) are going to be loaded.
+func ParseFromReader(r io.Reader, beatMap *BeatMap, options ParseOptions) error {
+	hash := md5.New()
+
+	if !options.SkipMetadata {
+		r = io.TeeReader(r, hash)
+	}
+
+	scanner := files.NewScanner(r)
 
 	buf := bufferPool.Get().(*[]byte)
 	scanner.Buffer(*buf, cap(*buf))
 
 	defer bufferPool.Put(buf)
 
+	// Lines kept for Encode are collected again, so they are not duplicated when beatmap is parsed more than once
+	if !options.SkipObjects {
+		beatMap.extraColors = nil
+
+		if !options.SkipMetadata {
+			beatMap.extraEvents = nil
+		}
+	}
+
 	var currentSection string
+	var colors []comboColor
 
-	counter := 0
+	lineNumber := 0
+	timingPoints := 0
 
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNumber++
+
+		if strings.HasPrefix(line, "osu file format v") {
+			trim := strings.TrimPrefix(line, "osu file format v")
+			beatMap.Version, _ = strconv.Atoi(trim)
+		}
 
 		section := getSection(line)
 		if section != "" {
@@ -176,180 +364,222 @@ func ParseBeatMap(beatMap *BeatMap) error {
 			continue
 		}
 
+		var err, fatal error
+
 		switch currentSection {
 		case "General":
-			if arr := tokenizeN(line, ":", 2); len(arr) > 1 {
-				if err := parseGeneral(arr, beatMap); err {
-					return errors.New("wrong mode")
+			if arr := tokenizeN(line, ":", 2); len(arr) > 1 && !options.SkipMetadata {
+				if parseGeneral(arr, beatMap) {
+					fatal = errors.New("wrong mode")
 				}
 			}
 		case "Metadata":
-			if arr := tokenizeN(line, ":", 2); len(arr) > 1 {
+			if arr := tokenizeN(line, ":", 2); len(arr) > 1 && !options.SkipMetadata {
 				parseMetadata(arr, beatMap)
 			}
 		case "Difficulty":
-			if arr := tokenizeN(line, ":", 2); len(arr) > 1 {
+			if arr := tokenizeN(line, ":", 2); len(arr) > 1 && !options.SkipMetadata {
 				parseDifficulty(arr, beatMap)
 			}
 		case "Events":
 			if arr := tokenize(line, ","); len(arr) > 1 {
-				parseEvents(arr, beatMap)
+				err = parseEvents(line, arr, beatMap, options)
 			}
 		case "TimingPoints":
-			if arr := tokenize(line, ","); len(arr) > 1 {
-				beatMap.ParsePoint(line)
-				counter++
+			if arr := tokenize(line, ","); len(arr) > 1 && !options.SkipTimings {
+				// Junk in optional fields is tolerated by ParsePoint, so the point is kept unless parsing is strict
+				if err = validatePoint(arr); err == nil || !options.Strict {
+					beatMap.ParsePoint(line)
+					timingPoints++
+				}
+			}
+		case "Colours": //nolint:misspell
+			if arr := tokenizeN(line, ":", 2); len(arr) > 1 && !options.SkipObjects {
+				if !strings.HasPrefix(arr[0], "Combo") {
+					// SliderBorder and SliderTrackOverride are not used by danser, they are kept only for Encode
+					beatMap.extraColors = append(beatMap.extraColors, arr[0]+" : "+arr[1])
+					break
+				}
+
+				var c comboColor
+
+				if c, err = parseComboColor(arr); err == nil {
+					colors = append(colors, c)
+				}
 			}
 		case "HitObjects":
 			if arr := tokenize(line, ","); arr != nil {
-				var time string
-
-				objTypeI, _ := strconv.Atoi(arr[3])
-				objType := objects.Type(objTypeI)
-				if (objType & objects.CIRCLE) > 0 {
-					beatMap.Circles++
-					time = arr[2]
-				} else if (objType & objects.SPINNER) > 0 {
-					beatMap.Spinners++
-					time = arr[5]
-				} else if (objType & objects.SLIDER) > 0 {
-					beatMap.Sliders++
-					time = arr[2]
-				} else if (objType & objects.LONGNOTE) > 0 {
-					beatMap.Sliders++
-					time = strings.Split(arr[5], ":")[0]
+				if err = validateObject(arr); err != nil {
+					break
+				}
+
+				if !options.SkipMetadata {
+					countObject(arr, beatMap)
 				}
-				timeI, _ := strconv.Atoi(time)
 
-				beatMap.Length = mutils.Max(beatMap.Length, timeI)
+				if !options.SkipObjects {
+					parseHitObjects(arr, beatMap, options)
+				}
 			}
 		}
-	}
 
-	beatMap.FinalizePoints()
+		if err != nil && options.Strict {
+			fatal = err
+		}
 
-	file.Seek(0, 0)
+		if fatal != nil {
+			return &ParseError{
+				Line:    lineNumber,
+				Section: currentSection,
+				Err:     fatal,
+			}
+		}
 
-	if beatMap.Name+beatMap.Artist+beatMap.Creator == "" || counter == 0 {
-		return errors.New("corrupted file")
-	}
+		if err != nil {
+			warning := &ParseError{
+				Line:    lineNumber,
+				Section: currentSection,
+				Err:     err,
+			}
 
-	return nil
-}
+			log.Println(fmt.Sprintf("BeatMap: Malformed line in \"%s\": %s", beatMap.File, warning))
 
-func ParseBeatMapFile(file *os.File) *BeatMap {
-	beatMap := NewBeatMap()
-	beatMap.Dir = filepath.Base(filepath.Dir(file.Name()))
-	f, _ := file.Stat()
-	beatMap.File = f.Name()
+			beatMap.Warnings = append(beatMap.Warnings, warning)
+		}
+	}
 
-	err := ParseBeatMap(beatMap)
+	if err := scanner.Err(); err != nil {
+		return &ParseError{
+			Line:    lineNumber + 1,
+			Section: currentSection,
+			Err:     err,
+		}
+	}
 
-	if err != nil {
-		return nil
+	if !options.SkipTimings {
+		beatMap.FinalizePoints()
 	}
 
-	return beatMap
-}
+	if !options.SkipMetadata {
+		beatMap.MD5 = hex.EncodeToString(hash.Sum(nil))
 
-func ParseTimingPointsAndPauses(beatMap *BeatMap) {
-	if beatMap.Timings.HasPoints() {
-		return
+		if !options.SkipTimings && (beatMap.Name+beatMap.Artist+beatMap.Creator == "" || timingPoints == 0) {
+			return errors.New("corrupted file")
+		}
 	}
 
-	file, err := os.Open(filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.File))
-	if err != nil {
-		panic(err)
-	}
+	if !options.SkipObjects {
+		sort.SliceStable(colors, func(i, j int) bool {
+			return colors[i].index < colors[j].index
+		})
 
-	defer file.Close()
+		beatMap.ComboColors = make([]color.Color, 0, len(colors))
 
-	scanner := files.NewScanner(file)
+		for _, c := range colors {
+			beatMap.ComboColors = append(beatMap.ComboColors, c.color)
+		}
 
-	buf := bufferPool.Get().(*[]byte)
-	scanner.Buffer(*buf, cap(*buf))
+		finalizeObjects(beatMap, options.DiffCalcOnly)
+	}
 
-	defer bufferPool.Put(buf)
+	return nil
+}
 
-	var currentSection string
+// ParseFromFS parses .osu file with given name from fsys, which can be for example an .osz archive opened with zip.NewReader.
+// Dir and File are set to the directory and base of the name.
+func ParseFromFS(fsys fs.FS, name string, options ParseOptions) (*BeatMap, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	defer file.Close()
 
-		section := getSection(line)
-		if section != "" {
-			currentSection = section
-			continue
-		}
+	beatMap := NewBeatMap()
+	beatMap.Dir = path.Dir(name)
+	beatMap.File = path.Base(name)
 
-		switch currentSection {
-		case "Events":
-			if arr := tokenize(line, ","); len(arr) > 1 && (arr[0] == "2" || arr[0] == "Break") {
-				beatMap.Pauses = append(beatMap.Pauses, NewPause(arr))
-			}
-		case "TimingPoints":
-			if arr := tokenize(line, ","); arr != nil {
-				beatMap.ParsePoint(line)
-			}
-		}
+	if err = ParseFromReader(file, beatMap, options); err != nil {
+		return nil, err
 	}
 
-	beatMap.FinalizePoints()
+	return beatMap, nil
 }
 
-func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
-	file, err := os.Open(filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.File))
+// parseFromPath parses .osu file located by beatmap's GetPath
+func parseFromPath(beatMap *BeatMap, options ParseOptions) error {
+	file, err := os.Open(beatMap.GetPath())
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	defer file.Close()
 
-	scanner := files.NewScanner(file)
+	return ParseFromReader(file, beatMap, options)
+}
 
-	buf := bufferPool.Get().(*[]byte)
-	scanner.Buffer(*buf, cap(*buf))
+// ParseBeatMap parses everything except hit objects from beatmap's file in Songs directory
+func ParseBeatMap(beatMap *BeatMap) error {
+	return parseFromPath(beatMap, ParseOptions{SkipObjects: true})
+}
 
-	defer bufferPool.Put(buf)
+// ParseBeatMapFile parses everything except hit objects from an .osu file located in Songs directory
+func ParseBeatMapFile(file *os.File) (*BeatMap, error) {
+	beatMap := NewBeatMap()
+	beatMap.Dir = filepath.Base(filepath.Dir(file.Name()))
+	beatMap.File = filepath.Base(file.Name())
 
-	var currentSection string
+	if err := ParseFromReader(file, beatMap, ParseOptions{SkipObjects: true}); err != nil {
+		return nil, err
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	return beatMap, nil
+}
 
-		if strings.HasPrefix(line, "osu file format v") {
-			trim := strings.TrimPrefix(line, "osu file format v")
-			beatMap.Version, _ = strconv.Atoi(trim)
-		}
+// ParseTimingPointsAndPauses parses timing points and breaks of a beatmap loaded from database
+func ParseTimingPointsAndPauses(beatMap *BeatMap) {
+	if beatMap.Timings.HasPoints() {
+		return
+	}
 
-		section := getSection(line)
-		if section != "" {
-			currentSection = section
-			continue
-		}
+	if err := parseFromPath(beatMap, ParseOptions{SkipMetadata: true, SkipObjects: true}); err != nil {
+		handleLoadError(beatMap, err)
+	}
+}
 
-		switch currentSection {
-		case "Colours": //nolint:misspell
-			if parseColors {
-				if arr := tokenize(line, ":"); arr != nil {
-					skin.AddBeatmapColor(arr)
-				}
-			}
-		case "HitObjects":
-			if arr := tokenize(line, ","); arr != nil {
-				parseHitObjects(arr, beatMap)
-			}
-		}
+// ParseObjects parses hit objects of a beatmap that already has timing points. If parseColors is true, beatmap's combo colors are passed to the skin.
+func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
+	options := ParseOptions{
+		SkipMetadata: true,
+		SkipTimings:  true,
+		DiffCalcOnly: diffCalcOnly,
+		SkipSpinners: !settings.Objects.LoadSpinners && !settings.KNOCKOUT && !settings.PLAY,
 	}
 
-	sort.SliceStable(beatMap.HitObjects, func(i, j int) bool {
-		return beatMap.HitObjects[i].GetStartTime() < beatMap.HitObjects[j].GetStartTime()
-	})
+	if err := parseFromPath(beatMap, options); err != nil {
+		handleLoadError(beatMap, err)
+	}
 
 	if parseColors {
-		skin.FinishBeatmapColors()
+		skin.SetBeatmapColors(beatMap.ComboColors)
+	}
+}
+
+// handleLoadError logs errors of a file that was read only partially, beatmap's file that can't be opened is still fatal
+func handleLoadError(beatMap *BeatMap, err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		panic(err)
 	}
 
+	log.Println(fmt.Sprintf("BeatMap: Failed to read \"%s\": %s", beatMap.File, err))
+}
+
+func finalizeObjects(beatMap *BeatMap, diffCalcOnly bool) {
+	sort.SliceStable(beatMap.HitObjects, func(i, j int) bool {
+		return beatMap.HitObjects[i].GetStartTime() < beatMap.HitObjects[j].GetStartTime()
+	})
+
 	num := 0
 	comboNumber := 1
 	comboSet := 0
@@ -0,0 +1,320 @@
+package beatmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type queryOperator int
+
+const (
+	opContains  = queryOperator(iota) // = or :, equality for numbers
+	opEquals                          // ==
+	opNotEquals                       // !=
+	opLess
+	opLessEqual
+	opGreater
+	opGreaterEqual
+)
+
+// Ordered so that longer operators are matched first
+var queryOperators = []struct {
+	text string
+	op   queryOperator
+}{
+	{"==", opEquals},
+	{"!=", opNotEquals},
+	{">=", opGreaterEqual},
+	{"<=", opLessEqual},
+	{">", opGreater},
+	{"<", opLess},
+	{"=", opContains},
+	{":", opContains},
+}
+
+var numericKeys = map[string]func(b *BeatMap) float64{
+	"stars":     func(b *BeatMap) float64 { return b.Stars },
+	"ar":        func(b *BeatMap) float64 { return b.Diff.GetAR() },
+	"od":        func(b *BeatMap) float64 { return b.Diff.GetOD() },
+	"cs":        func(b *BeatMap) float64 { return b.Diff.GetCS() },
+	"hp":        func(b *BeatMap) float64 { return b.Diff.GetHP() },
+	"minbpm":    func(b *BeatMap) float64 { return b.MinBPM },
+	"maxbpm":    func(b *BeatMap) float64 { return b.MaxBPM },
+	"length":    func(b *BeatMap) float64 { return float64(b.Length) },
+	"circles":   func(b *BeatMap) float64 { return float64(b.Circles) },
+	"sliders":   func(b *BeatMap) float64 { return float64(b.Sliders) },
+	"spinners":  func(b *BeatMap) float64 { return float64(b.Spinners) },
+	"objects":   func(b *BeatMap) float64 { return float64(b.Circles + b.Sliders + b.Spinners) },
+	"id":        func(b *BeatMap) float64 { return float64(b.ID) },
+	"setid":     func(b *BeatMap) float64 { return float64(b.SetID) },
+	"mode":      func(b *BeatMap) float64 { return float64(b.Mode) },
+	"playcount": func(b *BeatMap) float64 { return float64(b.PlayCount) },
+}
+
+// Keys with a range of values, condition matches if any value in the range meets it
+var rangeKeys = map[string]func(b *BeatMap) (float64, float64){
+	"bpm": func(b *BeatMap) (float64, float64) { return b.MinBPM, b.MaxBPM },
+}
+
+var textKeys = map[string]func(b *BeatMap) string{
+	"artist":     func(b *BeatMap) string { return b.Artist + "\n" + b.ArtistUnicode },
+	"title":      func(b *BeatMap) string { return b.Name + "\n" + b.NameUnicode },
+	"difficulty": func(b *BeatMap) string { return b.Difficulty },
+	"creator":    func(b *BeatMap) string { return b.Creator },
+	"source":     func(b *BeatMap) string { return b.Source },
+	"tags":       func(b *BeatMap) string { return b.Tags },
+	"md5":        func(b *BeatMap) string { return b.MD5 },
+}
+
+var keyAliases = map[string]string{
+	"star":    "stars",
+	"sr":      "stars",
+	"diff":    "difficulty",
+	"version": "difficulty",
+	"mapper":  "creator",
+	"name":    "title",
+	"drain":   "hp",
+}
+
+type queryCondition struct {
+	key string
+	op  queryOperator
+
+	number  float64
+	text    string
+	numeric bool
+}
+
+// Query filters beatmaps by a list of conditions in form of "key<operator>value", all of them have to match.
+// Terms without an operator are searched in artist, title, difficulty, creator, source and tags.
+type Query struct {
+	conditions []queryCondition
+	words      []string
+}
+
+// ParseQuery parses queries like `stars>6 ar>=9.3 bpm<200 creator=xyz length<3m "some words"`
+func ParseQuery(query string) (*Query, error) {
+	terms, err := splitQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(Query)
+
+	for _, term := range terms {
+		if !term.literal {
+			condition, ok, err := parseCondition(term.text)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				result.conditions = append(result.conditions, condition)
+				continue
+			}
+		}
+
+		result.words = append(result.words, strings.ToLower(term.text))
+	}
+
+	return result, nil
+}
+
+type queryTerm struct {
+	text string
+
+	// Terms starting with a quote are always searched as text
+	literal bool
+}
+
+// splitQuery splits query by whitespace, keeping quoted parts together and removing the quotes
+func splitQuery(query string) (terms []queryTerm, err error) {
+	var current strings.Builder
+
+	quoted := false
+	hasTerm := false
+	literal := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			if !hasTerm {
+				literal = true
+			}
+
+			quoted = !quoted
+			hasTerm = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if hasTerm {
+				terms = append(terms, queryTerm{current.String(), literal})
+				current.Reset()
+				hasTerm = false
+				literal = false
+			}
+		default:
+			current.WriteRune(r)
+			hasTerm = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+
+	if hasTerm {
+		terms = append(terms, queryTerm{current.String(), literal})
+	}
+
+	return
+}
+
+func parseCondition(term string) (condition queryCondition, ok bool, err error) {
+	index := -1
+	var operator string
+
+	for _, o := range queryOperators {
+		if i := strings.Index(term, o.text); i > 0 && (index == -1 || i < index) {
+			index = i
+			operator = o.text
+			condition.op = o.op
+		}
+	}
+
+	if index == -1 {
+		return
+	}
+
+	key := strings.ToLower(term[:index])
+	value := term[index+len(operator):]
+
+	if alias, ok1 := keyAliases[key]; ok1 {
+		key = alias
+	}
+
+	condition.key = key
+
+	_, isRange := rangeKeys[key]
+
+	if _, ok1 := numericKeys[key]; ok1 || isRange {
+		condition.numeric = true
+
+		if key == "length" {
+			condition.number, err = parseLength(value)
+		} else {
+			condition.number, err = strconv.ParseFloat(value, 64)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("invalid value of \"%s\": \"%s\"", key, value)
+		}
+
+		return condition, err == nil, err
+	}
+
+	if _, ok1 := textKeys[key]; ok1 {
+		if condition.op != opContains && condition.op != opEquals && condition.op != opNotEquals {
+			return condition, false, fmt.Errorf("operator \"%s\" can't be used with \"%s\"", operator, key)
+		}
+
+		condition.text = strings.ToLower(value)
+
+		return condition, true, nil
+	}
+
+	return condition, false, fmt.Errorf("unknown key \"%s\", put the term in quotes to search it as text", key)
+}
+
+// parseLength parses length in milliseconds. Plain numbers are seconds, otherwise Go's duration format is used (3m, 1m30s)
+func parseLength(value string) (float64, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return seconds * 1000, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(duration.Milliseconds()), nil
+}
+
+// Matches checks whether all conditions are met by the beatmap
+func (query *Query) Matches(beatMap *BeatMap) bool {
+	for _, c := range query.conditions {
+		if !c.matches(beatMap) {
+			return false
+		}
+	}
+
+	if len(query.words) > 0 {
+		searchable := strings.ToLower(strings.Join([]string{
+			beatMap.Artist,
+			beatMap.ArtistUnicode,
+			beatMap.Name,
+			beatMap.NameUnicode,
+			beatMap.Difficulty,
+			beatMap.Creator,
+			beatMap.Source,
+			beatMap.Tags,
+		}, "\n"))
+
+		for _, word := range query.words {
+			if !strings.Contains(searchable, word) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (c queryCondition) matches(beatMap *BeatMap) bool {
+	if c.numeric {
+		var minValue, maxValue float64
+
+		if getRange, ok := rangeKeys[c.key]; ok {
+			minValue, maxValue = getRange(beatMap)
+		} else {
+			minValue = numericKeys[c.key](beatMap)
+			maxValue = minValue
+		}
+
+		// Values are shown with 2 decimal places so exact comparison would be confusing
+		inRange := c.number > minValue-0.005 && c.number < maxValue+0.005
+
+		switch c.op {
+		case opContains, opEquals:
+			return inRange
+		case opNotEquals:
+			return !inRange
+		case opLess:
+			return minValue < c.number
+		case opLessEqual:
+			return minValue <= c.number
+		case opGreater:
+			return maxValue > c.number
+		default:
+			return maxValue >= c.number
+		}
+	}
+
+	value := strings.ToLower(textKeys[c.key](beatMap))
+
+	if c.op == opContains {
+		return strings.Contains(value, c.text)
+	}
+
+	// != is the negation of ==, so it matches if neither romanized nor unicode value is exactly the same
+	equal := false
+
+	for _, v := range strings.Split(value, "\n") {
+		if v == c.text {
+			equal = true
+			break
+		}
+	}
+
+	return equal == (c.op == opEquals)
+}
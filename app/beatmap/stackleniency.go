@@ -26,6 +26,11 @@ func calculateStackLeniency(b *BeatMap, diffCalcOnly bool) {
 		return
 	}
 
+	// osu!mania notes in the same column share the position
+	if b.Mode == ModeMania {
+		return
+	}
+
 	diffNM := difficulty.NewDifficulty(b.Diff.GetHP(), b.Diff.GetCS(), b.Diff.GetOD(), b.Diff.GetAR())
 	diffEZ := difficulty.NewDifficulty(b.Diff.GetHP(), b.Diff.GetCS(), b.Diff.GetOD(), b.Diff.GetAR())
 	diffHR := difficulty.NewDifficulty(b.Diff.GetHP(), b.Diff.GetCS(), b.Diff.GetOD(), b.Diff.GetAR())
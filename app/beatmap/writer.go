@@ -0,0 +1,190 @@
+package beatmap
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Encode writes the beatmap as .osu file in format v14.
+// If applyMods is true, mods, custom speed and custom AR/OD/CS/HP from beatMap.Diff are baked into the map:
+// times are scaled by the speed (audio file is not changed), HardRock flips objects vertically
+// and difficulty values are the ones seen by the player (AR/OD above 10 are clamped by osu!stable and danser's parser).
+// Difficulty name gets mods appended and BeatmapID is cleared.
+// Video and storyboard events are written unchanged, so they are not synchronized with baked-in speed or HardRock.
+func Encode(w io.Writer, beatMap *BeatMap, applyMods bool) error {
+	var transform objects.Transform
+
+	if applyMods {
+		transform = objects.Transform{
+			FlipY: beatMap.Diff.CheckModActive(difficulty.HardRock),
+			Speed: beatMap.Diff.Speed,
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	writeLine := func(format string, a ...any) {
+		_, _ = fmt.Fprintf(bw, format+"\r\n", a...)
+	}
+
+	writeLine("osu file format v14")
+	writeLine("")
+
+	writeLine("[General]")
+	writeLine("AudioFilename: %s", beatMap.Audio)
+	writeLine("PreviewTime: %d", int64(math.Round(transform.Time(float64(beatMap.PreviewTime)))))
+	writeLine("SampleSet: %s", getSampleSetName(beatMap.Timings.BaseSet))
+	writeLine("StackLeniency: %s", formatFloat(beatMap.StackLeniency))
+	writeLine("Mode: %d", beatMap.Mode)
+	writeLine("")
+
+	version, id := beatMap.Difficulty, beatMap.ID
+
+	if modString := beatMap.Diff.GetModString(); applyMods && modString != "" {
+		version = fmt.Sprintf("%s (%s)", version, modString)
+		id = 0
+	}
+
+	writeLine("[Metadata]")
+	writeLine("Title:%s", beatMap.Name)
+	writeLine("TitleUnicode:%s", beatMap.NameUnicode)
+	writeLine("Artist:%s", beatMap.Artist)
+	writeLine("ArtistUnicode:%s", beatMap.ArtistUnicode)
+	writeLine("Creator:%s", beatMap.Creator)
+	writeLine("Version:%s", version)
+	writeLine("Source:%s", beatMap.Source)
+	writeLine("Tags:%s", beatMap.Tags)
+	writeLine("BeatmapID:%d", id)
+	writeLine("BeatmapSetID:%d", beatMap.SetID)
+	writeLine("")
+
+	hp, cs, od, ar := beatMap.Diff.GetBaseHP(), beatMap.Diff.GetBaseCS(), beatMap.Diff.GetBaseOD(), beatMap.Diff.GetBaseAR()
+
+	if applyMods {
+		hp = beatMap.Diff.HPMod
+		od = beatMap.Diff.ODReal
+		ar = beatMap.Diff.ARReal
+
+		// CS is the key count in osu!mania, mods don't change it
+		if beatMap.Mode != ModeMania {
+			cs = difficulty.DiffFromRate(beatMap.Diff.CircleRadiusU, 54.4, 32, 9.6)
+		}
+	}
+
+	writeLine("[Difficulty]")
+	writeLine("HPDrainRate:%s", formatFloat(hp))
+	writeLine("CircleSize:%s", formatFloat(cs))
+	writeLine("OverallDifficulty:%s", formatFloat(od))
+	writeLine("ApproachRate:%s", formatFloat(ar))
+	writeLine("SliderMultiplier:%s", formatFloat(beatMap.SliderMultiplier))
+	writeLine("SliderTickRate:%s", formatFloat(beatMap.Timings.TickRate))
+	writeLine("")
+
+	writeLine("[Events]")
+	writeLine("//Background and Video events")
+
+	if beatMap.Bg != "" {
+		writeLine("0,0,\"%s\",0,0", beatMap.Bg)
+	}
+
+	if len(beatMap.extraEvents) > 0 && (transform.FlipY || (transform.Speed != 0 && transform.Speed != 1)) {
+		log.Println("BeatMap: Video and storyboard events are exported without speed and HardRock changes")
+	}
+
+	for _, event := range beatMap.extraEvents {
+		writeLine("%s", event)
+	}
+
+	writeLine("//Break Periods")
+
+	for _, pause := range beatMap.Pauses {
+		writeLine("2,%d,%d", int64(math.Round(transform.Time(pause.StartTime))), int64(math.Round(transform.Time(pause.EndTime))))
+	}
+
+	writeLine("")
+
+	writeLine("[TimingPoints]")
+
+	for _, point := range beatMap.Timings.GetPoints() {
+		beatLength := point.GetRawBeatLength()
+
+		uninherited := 1
+		if point.Inherited {
+			uninherited = 0
+		} else {
+			beatLength = transform.Time(beatLength)
+		}
+
+		effects := 0
+
+		if point.Kiai {
+			effects |= 1
+		}
+
+		if point.OmitFirstBarLine {
+			effects |= 8
+		}
+
+		writeLine("%s,%s,%d,%d,%d,%d,%d,%d",
+			strconv.FormatFloat(transform.Time(point.Time), 'f', -1, 64),
+			strconv.FormatFloat(beatLength, 'f', -1, 64),
+			point.Signature,
+			point.SampleSet,
+			point.SampleIndex,
+			int(math.Round(point.SampleVolume*100)),
+			uninherited,
+			effects,
+		)
+	}
+
+	writeLine("")
+
+	if len(beatMap.ComboColors)+len(beatMap.extraColors) > 0 {
+		writeLine("[Colours]") //nolint:misspell
+
+		for i, c := range beatMap.ComboColors {
+			writeLine("Combo%d : %d,%d,%d", i+1, int(math.Round(float64(c.R*255))), int(math.Round(float64(c.G*255))), int(math.Round(float64(c.B*255))))
+		}
+
+		for _, c := range beatMap.extraColors {
+			writeLine("%s", c)
+		}
+
+		writeLine("")
+	}
+
+	writeLine("[HitObjects]")
+
+	for _, obj := range beatMap.HitObjects {
+		line, err := objects.EncodeObject(obj, transform)
+		if err != nil {
+			return err
+		}
+
+		writeLine("%s", line)
+	}
+
+	return bw.Flush()
+}
+
+func getSampleSetName(set int) string {
+	switch set {
+	case 2:
+		return "Soft"
+	case 3:
+		return "Drum"
+	default:
+		return "Normal"
+	}
+}
+
+func formatFloat(value float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(float64(float32(value)), 'f', -1, 32), ".0")
+}
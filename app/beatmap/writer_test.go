@@ -0,0 +1,122 @@
+package beatmap
+
+import (
+	"bytes"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func parseFixture(t *testing.T, data []byte) *BeatMap {
+	t.Helper()
+
+	beatMap := NewBeatMap()
+
+	if err := ParseFromReader(bytes.NewReader(data), beatMap, ParseOptions{Strict: true, DiffCalcOnly: true}); err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+
+	return beatMap
+}
+
+func encode(t *testing.T, beatMap *BeatMap) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	if err := Encode(&buf, beatMap, false); err != nil {
+		t.Fatalf("failed to encode: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/roundtrip.osu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := parseFixture(t, data)
+	encoded := encode(t, original)
+	parsed := parseFixture(t, encoded)
+
+	originalPoints, parsedPoints := original.Timings.GetPoints(), parsed.Timings.GetPoints()
+
+	if !reflect.DeepEqual(originalPoints, parsedPoints) {
+		t.Errorf("timing points differ:\n%+v\n%+v", originalPoints, parsedPoints)
+	}
+
+	if len(original.HitObjects) != len(parsed.HitObjects) {
+		t.Fatalf("expected %d hit objects, got %d", len(original.HitObjects), len(parsed.HitObjects))
+	}
+
+	for i, obj := range original.HitObjects {
+		other := parsed.HitObjects[i]
+
+		if obj.GetType() != other.GetType() || obj.GetStartTime() != other.GetStartTime() || obj.GetEndTime() != other.GetEndTime() ||
+			obj.GetStartPosition() != other.GetStartPosition() || obj.GetEndPosition() != other.GetEndPosition() ||
+			obj.IsNewCombo() != other.IsNewCombo() || obj.GetColorOffset() != other.GetColorOffset() {
+			t.Errorf("hit object %d differs", i)
+		}
+
+		line, _ := objects.EncodeObject(obj, objects.Transform{})
+		otherLine, _ := objects.EncodeObject(other, objects.Transform{})
+
+		if line != otherLine {
+			t.Errorf("hit object %d differs:\n%s\n%s", i, line, otherLine)
+		}
+	}
+
+	if !reflect.DeepEqual(original.ComboColors, parsed.ComboColors) {
+		t.Errorf("combo colors differ: %v, %v", original.ComboColors, parsed.ComboColors)
+	}
+
+	if !reflect.DeepEqual(original.extraEvents, parsed.extraEvents) {
+		t.Errorf("events differ:\n%q\n%q", original.extraEvents, parsed.extraEvents)
+	}
+
+	if !reflect.DeepEqual(original.extraColors, parsed.extraColors) {
+		t.Errorf("colors differ: %q, %q", original.extraColors, parsed.extraColors)
+	}
+
+	if !bytes.Equal(encoded, encode(t, parsed)) {
+		t.Error("encoding is not stable")
+	}
+}
+
+func TestEncodeKeepsRawValues(t *testing.T) {
+	data, err := os.ReadFile("testdata/roundtrip.osu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := encode(t, parseFixture(t, data))
+
+	for _, expected := range []string{"123.456,333.333333333333,", "1:0:3:50:custom.wav", "2:1:0:0:hit.wav", " _M,0,1000,2000,320,240,100,100", "SliderTrackOverride : 0,0,0"} {
+		if !bytes.Contains(encoded, []byte(expected)) {
+			t.Errorf("encoded beatmap doesn't contain %q", expected)
+		}
+	}
+}
+
+func TestEncodeAfterReparse(t *testing.T) {
+	data, err := os.ReadFile("testdata/roundtrip.osu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := encode(t, parseFixture(t, data))
+
+	beatMap := parseFixture(t, data)
+	beatMap.HitObjects = nil
+
+	if err = ParseFromReader(bytes.NewReader(data), beatMap, ParseOptions{SkipMetadata: true, SkipTimings: true, DiffCalcOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if encoded := encode(t, beatMap); !bytes.Equal(expected, encoded) {
+		t.Errorf("encoded beatmap changed after parsing objects again:\n%s", encoded)
+	}
+}
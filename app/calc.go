@@ -0,0 +1,331 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type calcOutput struct {
+	File       string
+	Beatmap    string
+	MD5        string
+	Mods       string
+	PPVersion  int
+	AR         float64
+	OD         float64
+	CS         float64
+	HP         float64
+	MaxCombo   int
+	Stars      float64
+	Aim        float64
+	Speed      float64
+	Flashlight float64
+	Scenarios  []calcScenario
+}
+
+type calcScenario struct {
+	Accuracy  float64
+	Combo     int
+	Count300  int
+	Count100  int
+	Count50   int
+	CountMiss int
+	PP        performance.PPv2Results
+}
+
+// parseModCombinations parses comma separated mod combinations like "NM,HD,HDDT"
+func parseModCombinations(value string) ([]difficulty.Modifier, error) {
+	if strings.TrimSpace(value) == "" {
+		return []difficulty.Modifier{difficulty.None}, nil
+	}
+
+	var combinations []difficulty.Modifier
+
+	for _, s := range strings.Split(value, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+
+		if s == "" || s == "NM" {
+			combinations = append(combinations, difficulty.None)
+			continue
+		}
+
+		mods := difficulty.ParseMods(s)
+
+		if mods.String() == "" || len(s)%2 != 0 {
+			return nil, fmt.Errorf("invalid mods \"%s\"", s)
+		}
+
+		if !mods.Compatible() {
+			return nil, fmt.Errorf("incompatible mods \"%s\"", s)
+		}
+
+		combinations = append(combinations, mods)
+	}
+
+	return combinations, nil
+}
+
+// parseAccuracies parses comma separated accuracies in percent
+func parseAccuracies(value string) ([]float64, error) {
+	var accuracies []float64
+
+	for _, s := range strings.Split(value, ",") {
+		acc, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+		if err != nil || acc < 0 || acc > 100 {
+			return nil, fmt.Errorf("invalid accuracy \"%s\", expected a number between 0 and 100", s)
+		}
+
+		accuracies = append(accuracies, acc)
+	}
+
+	return accuracies, nil
+}
+
+// collectBeatmapFiles expands globs and directories to a list of .osu files
+func collectBeatmapFiles(patterns []string) ([]string, error) {
+	var result []string
+
+	found := make(map[string]bool)
+
+	add := func(path string) {
+		abs, err := filepath.Abs(path)
+		if err != nil || found[abs] {
+			return
+		}
+
+		found[abs] = true
+		result = append(result, abs)
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern \"%s\": %s", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match \"%s\"", pattern)
+		}
+
+		for _, match := range matches {
+			stat, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+
+			if !stat.IsDir() {
+				add(match)
+				continue
+			}
+
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".osu") {
+					add(path)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// getHitCounts approximates hit counts for given accuracy, filling 100s first and 50s if 100s are not enough
+func getHitCounts(objects int, accuracy float64, misses int) (n300, n100, n50, nmiss int) {
+	nmiss = mutils.Clamp(misses, 0, objects)
+
+	remaining := objects - nmiss
+	lost := float64(objects)*(1-accuracy/100) - float64(nmiss)
+
+	n100 = mutils.Max(0, int(math.Round(1.5*lost)))
+
+	if n100 > remaining {
+		n100 = 0
+		n50 = mutils.Clamp(int(math.Round(1.2*lost)), 0, remaining)
+	}
+
+	n300 = remaining - n100 - n50
+
+	return
+}
+
+func calculateBeatmaps(patterns []string, modsValue, accValue string, misses, combo int, format string) {
+	combinations, err := parseModCombinations(modsValue)
+	if err != nil {
+		panic(fmt.Sprintf("flag -mods: %s", err))
+	}
+
+	accuracies, err := parseAccuracies(accValue)
+	if err != nil {
+		panic(fmt.Sprintf("flag -acc: %s", err))
+	}
+
+	paths, err := collectBeatmapFiles(patterns)
+	if err != nil {
+		panic(fmt.Sprintf("flag -calc: %s", err))
+	}
+
+	calculator := performance.GetCurrent()
+
+	log.Println(fmt.Sprintf("Calculating %d beatmaps using pp version %s", len(paths), calculator.Description()))
+
+	results := make([]calcOutput, 0, len(paths)*len(combinations))
+
+	for _, path := range paths {
+		out, err := calculateBeatmap(path, combinations, accuracies, misses, combo, calculator)
+		if err != nil {
+			log.Println(fmt.Sprintf("Failed to calculate \"%s\": %s", path, err))
+			continue
+		}
+
+		results = append(results, out...)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+
+		if err = encoder.Encode(results); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Beatmap", "Mods", "Stars", "Aim", "Speed", "FL", "Acc", "Combo", "300/100/50/X", "Aim PP", "Speed PP", "Acc PP", "FL PP", "PP"})
+
+	for _, r := range results {
+		for _, s := range r.Scenarios {
+			table.Append([]string{
+				r.Beatmap,
+				r.Mods,
+				fmt.Sprintf("%.2f", r.Stars),
+				fmt.Sprintf("%.2f", r.Aim),
+				fmt.Sprintf("%.2f", r.Speed),
+				fmt.Sprintf("%.2f", r.Flashlight),
+				fmt.Sprintf("%.2f%%", s.Accuracy),
+				fmt.Sprintf("%d/%d", s.Combo, r.MaxCombo),
+				fmt.Sprintf("%d/%d/%d/%d", s.Count300, s.Count100, s.Count50, s.CountMiss),
+				fmt.Sprintf("%.2f", s.PP.Aim),
+				fmt.Sprintf("%.2f", s.PP.Speed),
+				fmt.Sprintf("%.2f", s.PP.Acc),
+				fmt.Sprintf("%.2f", s.PP.Flashlight),
+				fmt.Sprintf("%.2f", s.PP.Total),
+			})
+		}
+	}
+
+	table.Render()
+
+	fmt.Printf("Calculated %d beatmaps\n", len(results)/len(combinations))
+}
+
+func calculateBeatmap(path string, combinations []difficulty.Modifier, accuracies []float64, misses, combo int, calculator performance.Calculator) ([]calcOutput, error) {
+	bMap := beatmap.NewBeatMap()
+	bMap.Dir = filepath.Dir(path)
+	bMap.File = filepath.Base(path)
+
+	if err := beatmap.ParseBeatMap(bMap); err != nil {
+		return nil, err
+	}
+
+	if bMap.Mode != 0 {
+		return nil, errors.New("modes other than osu!standard are not supported")
+	}
+
+	baseDiff := bMap.Diff
+
+	results := make([]calcOutput, 0, len(combinations))
+
+	for _, mods := range combinations {
+		// Stacking depends on mods, so objects have to be parsed again for each combination. Timing points are kept.
+		bMap.HitObjects = nil
+		bMap.Diff = difficulty.NewDifficulty(baseDiff.GetBaseHP(), baseDiff.GetBaseCS(), baseDiff.GetBaseOD(), baseDiff.GetBaseAR())
+		bMap.Diff.SetMods(mods)
+
+		beatmap.ParseObjects(bMap, true, false)
+
+		if len(bMap.HitObjects) == 0 {
+			return nil, errors.New("beatmap has no objects")
+		}
+
+		attribs := calculator.CalculateSingle(bMap.HitObjects, bMap.Diff)
+
+		modsString := mods.String()
+		if modsString == "" {
+			modsString = "NM"
+		}
+
+		out := calcOutput{
+			File:       path,
+			Beatmap:    fmt.Sprintf("%s - %s [%s]", bMap.Artist, bMap.Name, bMap.Difficulty),
+			MD5:        bMap.MD5,
+			Mods:       modsString,
+			PPVersion:  calculator.Version(),
+			AR:         bMap.Diff.ARReal,
+			OD:         bMap.Diff.ODReal,
+			CS:         difficulty.DiffFromRate(bMap.Diff.CircleRadiusU, 54.4, 32, 9.6),
+			HP:         bMap.Diff.HPMod,
+			MaxCombo:   attribs.MaxCombo,
+			Stars:      attribs.Total,
+			Aim:        attribs.Aim,
+			Speed:      attribs.Speed,
+			Flashlight: attribs.Flashlight,
+		}
+
+		for _, acc := range accuracies {
+			n300, n100, n50, nmiss := getHitCounts(attribs.ObjectCount, acc, misses)
+
+			scenarioCombo := attribs.MaxCombo
+			if combo >= 0 {
+				scenarioCombo = mutils.Min(combo, attribs.MaxCombo)
+			}
+
+			out.Scenarios = append(out.Scenarios, calcScenario{
+				Accuracy:  getAccuracy(n300, n100, n50, nmiss),
+				Combo:     scenarioCombo,
+				Count300:  n300,
+				Count100:  n100,
+				Count50:   n50,
+				CountMiss: nmiss,
+				PP:        calculator.CalculatePP(attribs, scenarioCombo, n300, n100, n50, nmiss, bMap.Diff),
+			})
+		}
+
+		results = append(results, out)
+	}
+
+	return results, nil
+}
+
+func getAccuracy(n300, n100, n50, nmiss int) float64 {
+	total := n300 + n100 + n50 + nmiss
+	if total == 0 {
+		return 100
+	}
+
+	return float64(n300*300+n100*100+n50*50) / float64(total*300) * 100
+}
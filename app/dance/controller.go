@@ -8,6 +8,9 @@ import (
 	"github.com/wieku/danser-go/app/dance/spinners"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/rplpa"
+	"log"
 	"sort"
 	"strings"
 )
@@ -19,10 +22,18 @@ type Controller interface {
 	GetCursors() []*graphics.Cursor
 }
 
+// ReplayExporter is implemented by controllers that can save their session as an osu! replay
+type ReplayExporter interface {
+	// ExportReplay passes the replay to done, or nil if session wasn't recorded. done may be called from another goroutine.
+	ExportReplay(failed bool, done func(replay *rplpa.Replay))
+}
+
 type GenericController struct {
 	bMap       *beatmap.BeatMap
 	cursors    []*graphics.Cursor
 	schedulers []schedulers.Scheduler
+
+	recorder *ReplayRecorder
 }
 
 func NewGenericController() Controller {
@@ -164,6 +175,11 @@ func (controller *GenericController) InitCursors() {
 
 		controller.schedulers[i].Init(queues[i].hitObjects, controller.bMap.Diff, controller.cursors[i], spinners.GetMoverCtorByName(spinMover), true)
 	}
+
+	// Only a single cursor plays the whole map
+	if settings.SAVEREPLAY && settings.TAG == 1 {
+		controller.recorder = NewReplayRecorder(controller.cursors[0])
+	}
 }
 
 func (controller *GenericController) Update(time float64, delta float64) {
@@ -174,6 +190,34 @@ func (controller *GenericController) Update(time float64, delta float64) {
 		controller.cursors[i].LeftButton = controller.cursors[i].LeftKey || controller.cursors[i].LeftMouse
 		controller.cursors[i].RightButton = controller.cursors[i].RightKey || controller.cursors[i].RightMouse
 	}
+
+	if controller.recorder != nil {
+		controller.recorder.Update(time)
+	}
+}
+
+// ExportReplay creates a replay of the first cursor, score and life bar are obtained by simulating it in the background
+func (controller *GenericController) ExportReplay(_ bool, done func(replay *rplpa.Replay)) {
+	if controller.recorder == nil {
+		done(nil)
+		return
+	}
+
+	frames := append([]*rplpa.ReplayData{}, controller.recorder.GetFrames()...)
+
+	replay := newExportReplay(controller.bMap, "danser", controller.bMap.Diff.Mods, frames)
+
+	goroutines.Run(func() {
+		result := SimulateReplay(controller.bMap, replay)
+
+		if len(result.LifeBar) == 0 {
+			log.Println("GenericController: Simulation didn't produce life bar graph, exported replay won't have it")
+		}
+
+		setReplayScore(replay, result.score, result.Failed, result.LifeBar)
+
+		done(replay)
+	})
 }
 
 func (controller *GenericController) GetCursors() []*graphics.Cursor {
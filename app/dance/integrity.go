@@ -0,0 +1,504 @@
+package dance
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/rplpa"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+const (
+	// Median frame time (after applying speed mods) at or below which replay is considered timewarped
+	timewarpFrameTime = 13.0
+
+	// Number of frames checked together when looking for timewarped segments
+	timewarpWindow = 60
+
+	frameHistogramStep = 2.0
+	frameHistogramMax  = 40.0
+
+	keyHistogramStep = 10.0
+	keyHistogramMax  = 200.0
+
+	// Cursor jump has to be at least that long (in osu!pixels) and snapRatio times longer than surrounding movement to count as a snap
+	snapMinDistance = 50.0
+	snapRatio       = 6.0
+)
+
+type HistogramBin struct {
+	From  float64
+	To    float64
+	Count int
+}
+
+type FrameTimeStats struct {
+	Frames    int
+	Median    float64
+	Mean      float64
+	StdDev    float64
+	Min       float64
+	Max       float64
+	Histogram []HistogramBin
+}
+
+type TimewarpSegment struct {
+	Start           int64
+	End             int64
+	Frames          int
+	MedianFrameTime float64
+}
+
+type KeyStats struct {
+	Key            string
+	Presses        int
+	MeanDuration   float64
+	MedianDuration float64
+	StdDev         float64
+	MinDuration    float64
+	MaxDuration    float64
+	Histogram      []HistogramBin
+}
+
+type CursorStats struct {
+	Distance     float64
+	MeanVelocity float64
+	MaxVelocity  float64
+	MeanJerk     float64
+	MaxJerk      float64
+	Snaps        int
+	SnapTimes    []int64
+}
+
+type HitErrorStats struct {
+	Hits         int
+	UnstableRate float64
+	Mean         float64
+	EarlyMean    float64
+	LateMean     float64
+}
+
+// IntegrityReport describes replay input and how well danser's simulation matches the replay header.
+// Times and durations are in real time, so they are not affected by speed changing mods.
+type IntegrityReport struct {
+	Player   string
+	Mods     string
+	Duration int64
+
+	FrameTimes       FrameTimeStats
+	Timewarped       bool
+	TimewarpSegments []TimewarpSegment
+
+	Keys   []KeyStats
+	Cursor CursorStats
+
+	HitErrors HitErrorStats
+
+	ScoreChecked    bool
+	ScoreMismatches []ScoreMismatch
+
+	replay *rplpa.Replay
+	diff   *difficulty.Difficulty
+
+	// Running mean and sum of squared differences (Welford's method), and sums of early and late hit errors
+	errorMean, errorM2  float64
+	earlySum, lateSum   float64
+	earlyHits, lateHits int
+}
+
+// NewIntegrityReport analyzes replay frames, frames have to be cleaned up by loadFrames first.
+// Hit errors and score are added later by whatever runs the replay.
+func NewIntegrityReport(replay *rplpa.Replay, frames []*rplpa.ReplayData) *IntegrityReport {
+	diff := difficulty.NewDifficulty(5, 5, 5, 5)
+	diff.SetMods(difficulty.Modifier(replay.Mods))
+
+	report := &IntegrityReport{
+		Player: replay.Username,
+		Mods:   diff.Mods.String(),
+		replay: replay,
+		diff:   diff,
+	}
+
+	times := make([]int64, len(frames))
+
+	currentTime := int64(0)
+
+	for i, frame := range frames {
+		currentTime += frame.Time
+		times[i] = currentTime
+
+		report.Duration += frame.Time
+	}
+
+	report.Duration = int64(diff.GetModifiedTime(float64(report.Duration)))
+
+	report.analyzeFrameTimes(frames, times)
+	report.analyzeKeys(frames, times)
+
+	// osu!mania replays store pressed columns instead of the cursor position
+	if replay.PlayMode != rplpa.MANIA {
+		report.analyzeCursor(frames, times)
+	}
+
+	return report
+}
+
+func (report *IntegrityReport) analyzeFrameTimes(frames []*rplpa.ReplayData, times []int64) {
+	frameTimes := make([]float64, 0, len(frames))
+
+	for _, frame := range frames {
+		if frame.Time >= 0 {
+			frameTimes = append(frameTimes, report.diff.GetModifiedTime(float64(frame.Time)))
+		}
+	}
+
+	report.FrameTimes.Frames = len(frameTimes)
+	report.FrameTimes.Median, report.FrameTimes.Mean, report.FrameTimes.StdDev, report.FrameTimes.Min, report.FrameTimes.Max = getStats(frameTimes)
+	report.FrameTimes.Histogram = getHistogram(frameTimes, frameHistogramStep, frameHistogramMax)
+
+	// osu!mania replays have frames only when keys change, so frame times say nothing about the game clock
+	if report.diff.CheckModActive(difficulty.Autoplay|difficulty.Relax|difficulty.Relax2) || report.replay.PlayMode == rplpa.MANIA {
+		return
+	}
+
+	report.Timewarped = len(frameTimes) > 0 && report.FrameTimes.Median <= timewarpFrameTime
+
+	if len(frames) < timewarpWindow {
+		return
+	}
+
+	var current *TimewarpSegment
+
+	firstFrame := 0
+
+	window := make([]float64, 0, timewarpWindow)
+
+	for i := 0; i+timewarpWindow <= len(frames); i += timewarpWindow / 2 {
+		window = window[:0]
+
+		for _, frame := range frames[i : i+timewarpWindow] {
+			if frame.Time >= 0 {
+				window = append(window, report.diff.GetModifiedTime(float64(frame.Time)))
+			}
+		}
+
+		median, _, _, _, _ := getStats(window)
+
+		if len(window) == 0 || median > timewarpFrameTime {
+			current = nil
+			continue
+		}
+
+		end := int64(report.diff.GetModifiedTime(float64(times[i+timewarpWindow-1])))
+
+		if current != nil { // Windows overlap, so the segment is extended
+			current.End = end
+			current.Frames = i + timewarpWindow - firstFrame
+			current.MedianFrameTime = math.Min(current.MedianFrameTime, median)
+
+			continue
+		}
+
+		firstFrame = i
+
+		report.TimewarpSegments = append(report.TimewarpSegments, TimewarpSegment{
+			Start:           int64(report.diff.GetModifiedTime(float64(times[i]))),
+			End:             end,
+			Frames:          timewarpWindow,
+			MedianFrameTime: median,
+		})
+
+		current = &report.TimewarpSegments[len(report.TimewarpSegments)-1]
+	}
+}
+
+func (report *IntegrityReport) analyzeKeys(frames []*rplpa.ReplayData, times []int64) {
+	names := []string{"K1", "K2", "M1", "M2"}
+
+	mania := report.replay.PlayMode == rplpa.MANIA
+
+	if mania {
+		columns := uint32(0)
+		for _, frame := range frames {
+			columns |= uint32(frame.MouseX)
+		}
+
+		names = make([]string, bits.Len32(columns))
+		for k := range names {
+			names[k] = fmt.Sprintf("Column %d", k+1)
+		}
+	}
+
+	durations := make([][]float64, len(names))
+	pressStart := make([]int64, len(names))
+	pressed := make([]bool, len(names))
+
+	for i, frame := range frames {
+		var state uint32
+
+		if mania {
+			state = uint32(frame.MouseX)
+		} else if frame.KeyPressed != nil {
+			// K1 and K2 are always sent together with M1 and M2
+			if frame.KeyPressed.Key1 {
+				state |= 1
+			}
+
+			if frame.KeyPressed.Key2 {
+				state |= 2
+			}
+
+			if frame.KeyPressed.LeftClick && !frame.KeyPressed.Key1 {
+				state |= 4
+			}
+
+			if frame.KeyPressed.RightClick && !frame.KeyPressed.Key2 {
+				state |= 8
+			}
+		}
+
+		for k := range names {
+			down := state&(1<<k) > 0
+
+			if down && !pressed[k] {
+				pressStart[k] = times[i]
+			} else if !down && pressed[k] {
+				durations[k] = append(durations[k], report.diff.GetModifiedTime(float64(times[i]-pressStart[k])))
+			}
+
+			pressed[k] = down
+		}
+	}
+
+	for k, name := range names {
+		if len(durations[k]) == 0 {
+			continue
+		}
+
+		stats := KeyStats{
+			Key:       name,
+			Presses:   len(durations[k]),
+			Histogram: getHistogram(durations[k], keyHistogramStep, keyHistogramMax),
+		}
+
+		stats.MedianDuration, stats.MeanDuration, stats.StdDev, stats.MinDuration, stats.MaxDuration = getStats(durations[k])
+
+		report.Keys = append(report.Keys, stats)
+	}
+}
+
+func (report *IntegrityReport) analyzeCursor(frames []*rplpa.ReplayData, times []int64) {
+	type sample struct {
+		time     float64
+		distance float64
+		velocity float64
+	}
+
+	samples := make([]sample, 0, len(frames))
+
+	for i := 1; i < len(frames); i++ {
+		dt := report.diff.GetModifiedTime(float64(times[i] - times[i-1]))
+		if dt <= 0 {
+			continue
+		}
+
+		distance := math.Hypot(float64(frames[i].MouseX-frames[i-1].MouseX), float64(frames[i].MouseY-frames[i-1].MouseY))
+
+		samples = append(samples, sample{
+			time:     report.diff.GetModifiedTime(float64(times[i])),
+			distance: distance,
+			velocity: distance / dt,
+		})
+
+		report.Cursor.Distance += distance
+	}
+
+	if len(samples) == 0 {
+		return
+	}
+
+	velocitySum := 0.0
+
+	for _, s := range samples {
+		velocitySum += s.velocity
+		report.Cursor.MaxVelocity = math.Max(report.Cursor.MaxVelocity, s.velocity)
+	}
+
+	report.Cursor.MeanVelocity = velocitySum / float64(len(samples))
+
+	jerkSum := 0.0
+	jerkCount := 0
+
+	for i := 2; i < len(samples); i++ {
+		dt1 := samples[i].time - samples[i-1].time
+		dt2 := samples[i-1].time - samples[i-2].time
+
+		if dt1 <= 0 || dt2 <= 0 {
+			continue
+		}
+
+		acc1 := (samples[i].velocity - samples[i-1].velocity) / dt1
+		acc2 := (samples[i-1].velocity - samples[i-2].velocity) / dt2
+
+		jerk := math.Abs(acc1-acc2) / dt1
+
+		jerkSum += jerk
+		jerkCount++
+
+		report.Cursor.MaxJerk = math.Max(report.Cursor.MaxJerk, jerk)
+	}
+
+	if jerkCount > 0 {
+		report.Cursor.MeanJerk = jerkSum / float64(jerkCount)
+	}
+
+	for i := 1; i < len(samples)-1; i++ {
+		surrounding := math.Max(samples[i-1].distance, samples[i+1].distance)
+
+		if samples[i].distance >= snapMinDistance && samples[i].distance >= surrounding*snapRatio {
+			report.Cursor.Snaps++
+			report.Cursor.SnapTimes = append(report.Cursor.SnapTimes, int64(samples[i].time))
+		}
+	}
+}
+
+// AddHitError adds the difference between hit time and object's start time, in beatmap time
+func (report *IntegrityReport) AddHitError(hitError float64) {
+	stats := HitErrorStats{Hits: report.HitErrors.Hits + 1}
+
+	delta := hitError - report.errorMean
+	report.errorMean += delta / float64(stats.Hits)
+	report.errorM2 += delta * (hitError - report.errorMean)
+
+	if hitError < 0 {
+		report.earlySum += hitError
+		report.earlyHits++
+	} else {
+		report.lateSum += hitError
+		report.lateHits++
+	}
+
+	stats.Mean = report.errorMean
+	stats.EarlyMean = report.earlySum / math.Max(float64(report.earlyHits), 1)
+	stats.LateMean = report.lateSum / math.Max(float64(report.lateHits), 1)
+
+	variance := report.errorM2 / float64(stats.Hits)
+
+	// Values are converted to real time, the same way osu! does it
+	stats.UnstableRate = math.Sqrt(variance) * 10 / report.diff.Speed
+	stats.Mean /= report.diff.Speed
+	stats.EarlyMean /= report.diff.Speed
+	stats.LateMean /= report.diff.Speed
+
+	report.HitErrors = stats
+}
+
+// SetScore compares the final score with the one stored in replay's header
+func (report *IntegrityReport) SetScore(score osu.Score) {
+	report.ScoreChecked = true
+	report.ScoreMismatches = compareScore(report.replay, score)
+}
+
+// IsSuspicious checks whether timewarp was detected or the score doesn't match the replay header
+func (report *IntegrityReport) IsSuspicious() bool {
+	return report.Timewarped || len(report.TimewarpSegments) > 0 || len(report.ScoreMismatches) > 0
+}
+
+// Summary returns short human-readable lines describing the report
+func (report *IntegrityReport) Summary() (lines []string) {
+	lines = append(lines, fmt.Sprintf("Frame time: median %.2fms, mean %.2fms, stddev %.2fms", report.FrameTimes.Median, report.FrameTimes.Mean, report.FrameTimes.StdDev))
+
+	if report.Timewarped || len(report.TimewarpSegments) > 0 {
+		warped := int64(0)
+		for _, s := range report.TimewarpSegments {
+			warped += s.End - s.Start
+		}
+
+		lines = append(lines, fmt.Sprintf("Timewarp: SUSPECTED in %d segments (%.1fs)", len(report.TimewarpSegments), float64(warped)/1000))
+	} else {
+		lines = append(lines, "Timewarp: not detected")
+	}
+
+	for _, k := range report.Keys {
+		lines = append(lines, fmt.Sprintf("%s: %d presses, %.0fms avg, %.0fms stddev", k.Key, k.Presses, k.MeanDuration, k.StdDev))
+	}
+
+	if report.replay.PlayMode != rplpa.MANIA {
+		lines = append(lines, fmt.Sprintf("Cursor: %d snaps, %.2f px/ms max velocity, %.4f px/ms^3 mean jerk", report.Cursor.Snaps, report.Cursor.MaxVelocity, report.Cursor.MeanJerk))
+	}
+
+	if report.HitErrors.Hits > 0 {
+		lines = append(lines, fmt.Sprintf("UR: %.2f (%.2fms early, +%.2fms late)", report.HitErrors.UnstableRate, report.HitErrors.EarlyMean, report.HitErrors.LateMean))
+	}
+
+	switch {
+	case !report.ScoreChecked:
+		lines = append(lines, "Score: not checked")
+	case len(report.ScoreMismatches) == 0:
+		lines = append(lines, "Score: matches replay")
+	default:
+		mismatches := make([]string, 0, len(report.ScoreMismatches))
+		for _, m := range report.ScoreMismatches {
+			mismatches = append(mismatches, fmt.Sprintf("%s %d/%d", m.Field, m.Replay, m.Simulated))
+		}
+
+		lines = append(lines, "Score MISMATCH (replay/danser): "+strings.Join(mismatches, ", "))
+	}
+
+	return
+}
+
+// getStats returns median, mean, standard deviation, min and max of values
+func getStats(values []float64) (median, mean, stdDev, min, max float64) {
+	if len(values) == 0 {
+		return
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+
+	sort.Float64s(sorted)
+
+	l := len(sorted)
+
+	median = sorted[l/2]
+	if l%2 == 0 {
+		median = (sorted[l/2] + sorted[l/2-1]) / 2
+	}
+
+	for _, v := range sorted {
+		mean += v
+	}
+
+	mean /= float64(l)
+
+	for _, v := range sorted {
+		stdDev += (v - mean) * (v - mean)
+	}
+
+	stdDev = math.Sqrt(stdDev / float64(l))
+
+	return median, mean, stdDev, sorted[0], sorted[l-1]
+}
+
+// getHistogram puts values into bins of size step, the last bin has To set to 0 and holds all values above max
+func getHistogram(values []float64, step, max float64) []HistogramBin {
+	bins := make([]HistogramBin, int(max/step)+1)
+
+	for i := range bins {
+		bins[i].From = float64(i) * step
+		bins[i].To = float64(i+1) * step
+	}
+
+	bins[len(bins)-1].To = 0
+
+	for _, v := range values {
+		bins[mutils.Clamp(int(v/step), 0, len(bins)-1)].Count++
+	}
+
+	return bins
+}
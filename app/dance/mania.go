@@ -0,0 +1,95 @@
+package dance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/rplpa"
+	"math"
+	"sort"
+)
+
+// How long autoplay holds the column for a single note
+const maniaAutoplayRelease = 40
+
+type maniaKeyEvent struct {
+	time   int64
+	column int
+	press  bool
+}
+
+// newManiaAutoplayFrames generates osu!mania replay frames hitting every note perfectly. First frame only sets the start time.
+func newManiaAutoplayFrames(beatMap *beatmap.BeatMap) []*rplpa.ReplayData {
+	columns := make(map[int][]*objects.ManiaNote)
+
+	for _, o := range beatMap.HitObjects {
+		if note, ok := o.(*objects.ManiaNote); ok {
+			columns[note.Column] = append(columns[note.Column], note)
+		}
+	}
+
+	events := make([]maniaKeyEvent, 0, len(beatMap.HitObjects)*2)
+
+	for column, notes := range columns {
+		for i, note := range notes {
+			start := int64(math.Round(note.GetStartTime()))
+
+			release := start + maniaAutoplayRelease
+			if note.IsHold() {
+				release = int64(math.Round(note.GetEndTime()))
+			}
+
+			// Column has to be released before the next note
+			if i+1 < len(notes) {
+				release = mutils.Min(release, int64(math.Round(notes[i+1].GetStartTime()))-1)
+			}
+
+			events = append(events,
+				maniaKeyEvent{time: start, column: column, press: true},
+				maniaKeyEvent{time: mutils.Max(release, start), column: column, press: false},
+			)
+		}
+	}
+
+	// Releases go first, so the column can be pressed again at the same time
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].time != events[j].time {
+			return events[i].time < events[j].time
+		}
+
+		return !events[i].press && events[j].press
+	})
+
+	startTime := int64(0)
+	if len(events) > 0 {
+		startTime = mutils.Min(startTime, events[0].time)
+	}
+
+	frames := []*rplpa.ReplayData{{Time: startTime, KeyPressed: &rplpa.KeyPressed{}}}
+
+	lastTime := startTime
+	keys := uint32(0)
+
+	for i, event := range events {
+		if event.press {
+			keys |= 1 << event.column
+		} else {
+			keys &^= 1 << event.column
+		}
+
+		// Chords are pressed in a single frame
+		if i+1 < len(events) && events[i+1].time == event.time && events[i+1].press == event.press {
+			continue
+		}
+
+		frames = append(frames, &rplpa.ReplayData{
+			Time:       event.time - lastTime,
+			MouseX:     float32(keys),
+			KeyPressed: &rplpa.KeyPressed{},
+		})
+
+		lastTime = event.time
+	}
+
+	return frames
+}
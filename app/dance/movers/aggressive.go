@@ -17,6 +17,10 @@ type AggressiveMover struct {
 	lastAngle float32
 }
 
+func init() {
+	Register("aggressive", NewAggressiveMover)
+}
+
 func NewAggressiveMover() MultiPointMover {
 	return &AggressiveMover{basicMover: &basicMover{}}
 }
@@ -21,6 +21,8 @@ type AngleOffsetMover struct {
 	invert    float32
 }
 
+var flowerSettings = RegisterWithSettings("flower", NewAngleOffsetMover, "Flower", settings.DefaultsFactory.InitFlower)
+
 func NewAngleOffsetMover() MultiPointMover {
 	return &AngleOffsetMover{basicMover: &basicMover{}}
 }
@@ -34,7 +36,7 @@ func (mover *AngleOffsetMover) Reset(diff *difficulty.Difficulty, id int) {
 }
 
 func (mover *AngleOffsetMover) SetObjects(objs []objects.IHitObject) int {
-	config := settings.CursorDance.MoverSettings.Flower[mover.id%len(settings.CursorDance.MoverSettings.Flower)]
+	config := flowerSettings.Get(mover.id)
 
 	start, end := objs[0], objs[1]
 
@@ -15,6 +15,10 @@ type AxisMover struct {
 	curve *curves.MultiCurve
 }
 
+func init() {
+	Register("axis", NewAxisMover)
+}
+
 func NewAxisMover() MultiPointMover {
 	return &AxisMover{basicMover: &basicMover{}}
 }
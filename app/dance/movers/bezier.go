@@ -20,6 +20,8 @@ type BezierMover struct {
 	invert        float32
 }
 
+var bezierSettings = RegisterWithSettings("bezier", NewBezierMover, "Bezier", settings.DefaultsFactory.InitBezier)
+
 func NewBezierMover() MultiPointMover {
 	return &BezierMover{basicMover: &basicMover{}}
 }
@@ -33,7 +35,7 @@ func (mover *BezierMover) Reset(diff *difficulty.Difficulty, id int) {
 }
 
 func (mover *BezierMover) SetObjects(objs []objects.IHitObject) int {
-	config := settings.CursorDance.MoverSettings.Bezier[mover.id%len(settings.CursorDance.MoverSettings.Bezier)]
+	config := bezierSettings.Get(mover.id)
 
 	start, end := objs[0], objs[1]
 
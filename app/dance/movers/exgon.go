@@ -23,6 +23,8 @@ type ExGonMover struct {
 	delay   float64
 }
 
+var exGonSettings = RegisterWithSettings("exgon", NewExGonMover, "ExGon", settings.DefaultsFactory.InitExGon)
+
 func NewExGonMover() MultiPointMover {
 	return &ExGonMover{basicMover: &basicMover{}}
 }
@@ -33,7 +35,7 @@ func (mover *ExGonMover) Reset(diff *difficulty.Difficulty, id int) {
 }
 
 func (mover *ExGonMover) SetObjects(objs []objects.IHitObject) int {
-	config := settings.CursorDance.MoverSettings.ExGon[mover.id%len(settings.CursorDance.MoverSettings.ExGon)]
+	config := exGonSettings.Get(mover.id)
 	mover.delay = float64(config.Delay)
 
 	if !mover.wasFirst {
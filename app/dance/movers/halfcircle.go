@@ -18,6 +18,8 @@ type HalfCircleMover struct {
 	invert float32
 }
 
+var halfCircleSettings = RegisterWithSettings("circular", NewHalfCircleMover, "HalfCircle", settings.DefaultsFactory.InitCircular)
+
 func NewHalfCircleMover() MultiPointMover {
 	return &HalfCircleMover{basicMover: &basicMover{}}
 }
@@ -29,7 +31,7 @@ func (mover *HalfCircleMover) Reset(diff *difficulty.Difficulty, id int) {
 }
 
 func (mover *HalfCircleMover) SetObjects(objs []objects.IHitObject) int {
-	config := settings.CursorDance.MoverSettings.HalfCircle[mover.id%len(settings.CursorDance.MoverSettings.HalfCircle)]
+	config := halfCircleSettings.Get(mover.id)
 
 	start, end := objs[0], objs[1]
 
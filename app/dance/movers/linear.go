@@ -17,6 +17,8 @@ type LinearMover struct {
 	simple bool
 }
 
+var linearSettings = RegisterWithSettings("linear", NewLinearMover, "Linear", settings.DefaultsFactory.InitLinear)
+
 func NewLinearMover() MultiPointMover {
 	return &LinearMover{basicMover: &basicMover{}}
 }
@@ -42,7 +44,7 @@ func (mover *LinearMover) SetObjects(objs []objects.IHitObject) int {
 	if mover.simple {
 		mover.startTime = math.Max(mover.startTime, mover.endTime-(mover.diff.Preempt-100*mover.diff.Speed))
 	} else {
-		config := settings.CursorDance.MoverSettings.Linear[mover.id%len(settings.CursorDance.MoverSettings.Linear)]
+		config := linearSettings.Get(mover.id)
 
 		if config.WaitForPreempt {
 			mover.startTime = math.Max(mover.startTime, mover.endTime-(mover.diff.Preempt-config.ReactionTime*mover.diff.Speed))
@@ -58,7 +60,7 @@ func (mover *LinearMover) Update(time float64) vector.Vector2f {
 }
 
 func (mover *LinearMover) GetObjectsPosition(time float64, object objects.IHitObject) vector.Vector2f {
-	config := settings.CursorDance.MoverSettings.Linear[mover.id%len(settings.CursorDance.MoverSettings.Linear)]
+	config := linearSettings.Get(mover.id)
 
 	if !config.ChoppyLongObjects || mover.simple || object.GetType() == objects.CIRCLE {
 		return mover.basicMover.GetObjectsPosition(time, object)
@@ -23,6 +23,8 @@ type MomentumMover struct {
 	wasStream bool
 }
 
+var momentumSettings = RegisterWithSettings("momentum", NewMomentumMover, "Momentum", settings.DefaultsFactory.InitMomentum)
+
 func NewMomentumMover() MultiPointMover {
 	return &MomentumMover{basicMover: &basicMover{}}
 }
@@ -58,7 +60,7 @@ func anorm2(a float32) float32 {
 }
 
 func (mover *MomentumMover) SetObjects(objs []objects.IHitObject) int {
-	ms := settings.CursorDance.MoverSettings.Momentum[mover.id%len(settings.CursorDance.MoverSettings.Momentum)]
+	ms := momentumSettings.Get(mover.id)
 
 	i := 0
 
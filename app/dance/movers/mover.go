@@ -1,8 +1,10 @@
 package movers
 
 import (
+	"fmt"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/math/vector"
 	"strings"
 )
@@ -64,6 +66,42 @@ func (mover *basicMover) GetEndTime() float64 {
 	return mover.endTime
 }
 
+var registry = make(map[string]func() MultiPointMover)
+
+// Register adds a cursor dance mover without settings under the given name, making it available in settings and -mover flag
+func Register(name string, ctor func() MultiPointMover) {
+	settings.RegisterMover(addToRegistry(name, ctor))
+}
+
+// RegisterWithSettings adds a cursor dance mover like Register and creates its settings array under key in
+// CursorDance.MoverSettings, factory creates the default element. Mover reads its settings through returned handle.
+func RegisterWithSettings[T any](name string, ctor func() MultiPointMover, key string, factory func() T) *settings.MoverSettings[T] {
+	return settings.RegisterMoverWithSettings(addToRegistry(name, ctor), key, factory)
+}
+
+func addToRegistry(name string, ctor func() MultiPointMover) string {
+	name = strings.ToLower(name)
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("Mover \"%s\" is already registered", name))
+	}
+
+	registry[name] = ctor
+
+	return name
+}
+
+// IsRegistered checks if mover with the given name exists
+func IsRegistered(name string) bool {
+	_, ok := registry[strings.ToLower(name)]
+	return ok
+}
+
+// GetNames returns names of registered movers in registration order
+func GetNames() []string {
+	return settings.DefaultsFactory.MoverOptions()
+}
+
 func GetMoverByName(name string) MultiPointMover {
 	ctor, _ := GetMoverCtorByName(name)
 
@@ -73,26 +111,8 @@ func GetMoverByName(name string) MultiPointMover {
 func GetMoverCtorByName(name string) (moverCtor func() MultiPointMover, finalName string) {
 	finalName = strings.ToLower(name)
 
-	switch finalName {
-	case "spline":
-		moverCtor = NewSplineMover
-	case "bezier":
-		moverCtor = NewBezierMover
-	case "circular":
-		moverCtor = NewHalfCircleMover
-	case "linear":
-		moverCtor = NewLinearMover
-	case "axis":
-		moverCtor = NewAxisMover
-	case "exgon":
-		moverCtor = NewExGonMover
-	case "aggressive":
-		moverCtor = NewAggressiveMover
-	case "momentum":
-		moverCtor = NewMomentumMover
-	case "pippi":
-		moverCtor = NewPippiMover
-	default:
+	moverCtor, ok := registry[finalName]
+	if !ok {
 		moverCtor = NewAngleOffsetMover
 		finalName = "flower"
 	}
@@ -16,6 +16,8 @@ type PippiMover struct {
 	curve curves.Curve
 }
 
+var pippiSettings = RegisterWithSettings("pippi", NewPippiMover, "Pippi", settings.DefaultsFactory.InitPippi)
+
 func NewPippiMover() MultiPointMover {
 	return &PippiMover{basicMover: &basicMover{}}
 }
@@ -84,7 +86,7 @@ func (mover *PippiMover) GetObjectsPosition(time float64, object objects.IHitObj
 }
 
 func (mover *PippiMover) modifyPos(time float64, spinner bool, pos vector.Vector2f) vector.Vector2f {
-	config := settings.CursorDance.MoverSettings.Pippi[mover.id%len(settings.CursorDance.MoverSettings.Pippi)]
+	config := pippiSettings.Get(mover.id)
 
 	rad := math.Mod(time/1000*config.RotationSpeed, 1) * 2 * math.Pi
 
@@ -0,0 +1,188 @@
+package movers
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/danser-go/framework/math/expression"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Variables available to mover scripts
+var scriptInputs = []string{
+	"t",         // progress between objects, 0 to 1
+	"time",      // milliseconds since leaving the previous object
+	"duration",  // milliseconds between objects
+	"startX",    // end position of the previous object
+	"startY",    //
+	"endX",      // start position of the next object
+	"endY",      //
+	"startVelX", // velocity (osu!pixels per ms) at the end of the previous object, 0 for circles
+	"startVelY", //
+	"endVelX",   // velocity at the start of the next object, 0 for circles
+	"endVelY",   //
+	"id",        // mover id, to differentiate cursors using the same script
+	"index",     // number of movements done by this mover so far
+}
+
+type ScriptedMover struct {
+	*basicMover
+
+	program *expression.Program
+
+	inputs map[string]*float64
+
+	t, time *float64
+	x, y    *float64
+
+	index float64
+}
+
+func init() {
+	Register("scripted", NewScriptedMover)
+}
+
+func NewScriptedMover() MultiPointMover {
+	return &ScriptedMover{basicMover: &basicMover{}}
+}
+
+func (mover *ScriptedMover) Reset(diff *difficulty.Difficulty, id int) {
+	mover.basicMover.Reset(diff, id)
+
+	path := getScriptPath(id)
+
+	program, err := compileScript(path)
+	if err != nil {
+		panic(fmt.Sprintf("ScriptedMover: Failed to load \"%s\": %s", path, err))
+	}
+
+	mover.program = program
+
+	mover.inputs = make(map[string]*float64)
+	for _, name := range scriptInputs {
+		mover.inputs[name] = program.Variable(name)
+	}
+
+	mover.t = program.Variable("t")
+	mover.time = program.Variable("time")
+	mover.x = program.Variable("x")
+	mover.y = program.Variable("y")
+
+	*mover.inputs["id"] = float64(id)
+
+	mover.index = 0
+}
+
+func (mover *ScriptedMover) SetObjects(objs []objects.IHitObject) int {
+	start, end := objs[0], objs[1]
+
+	mover.startTime = start.GetEndTime()
+	mover.endTime = end.GetStartTime()
+
+	startPos := start.GetStackedEndPositionMod(mover.diff.Mods)
+	endPos := end.GetStackedStartPositionMod(mover.diff.Mods)
+
+	var startVel, endVel vector.Vector2f
+
+	if s, ok := start.(objects.ILongObject); ok {
+		startVel = startPos.Sub(s.GetStackedPositionAtMod(mover.startTime-10, mover.diff.Mods)).Scl(0.1)
+	}
+
+	if s, ok := end.(objects.ILongObject); ok {
+		endVel = s.GetStackedPositionAtMod(mover.endTime+10, mover.diff.Mods).Sub(endPos).Scl(0.1)
+	}
+
+	*mover.inputs["duration"] = mover.endTime - mover.startTime
+	*mover.inputs["startX"] = float64(startPos.X)
+	*mover.inputs["startY"] = float64(startPos.Y)
+	*mover.inputs["endX"] = float64(endPos.X)
+	*mover.inputs["endY"] = float64(endPos.Y)
+	*mover.inputs["startVelX"] = float64(startVel.X)
+	*mover.inputs["startVelY"] = float64(startVel.Y)
+	*mover.inputs["endVelX"] = float64(endVel.X)
+	*mover.inputs["endVelY"] = float64(endVel.Y)
+	*mover.inputs["index"] = mover.index
+
+	mover.index++
+
+	return 2
+}
+
+func (mover *ScriptedMover) Update(time float64) vector.Vector2f {
+	duration := mover.endTime - mover.startTime
+
+	t := 1.0
+	if duration > 0 {
+		t = mutils.ClampF((time-mover.startTime)/duration, 0, 1)
+	}
+
+	*mover.t = t
+	*mover.time = mutils.ClampF(time-mover.startTime, 0, duration)
+
+	mover.program.Run()
+
+	return vector.NewVec2d(*mover.x, *mover.y).Copy32()
+}
+
+// getScriptPath finds the script of id-th scripted entry in CursorDance.Movers
+func getScriptPath(id int) string {
+	paths := settings.CursorDance.GetScripts()
+
+	if len(paths) == 0 {
+		return ""
+	}
+
+	return paths[id%len(paths)]
+}
+
+func compileScript(path string) (*expression.Program, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("script file is not set")
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(env.DataDir(), path)
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := expression.Compile(string(source), scriptInputs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !program.Assigns("x") || !program.Assigns("y") {
+		return nil, fmt.Errorf("script has to assign both x and y")
+	}
+
+	return program, nil
+}
+
+// CheckScripts compiles scripts of all scripted entries in CursorDance.Movers, so errors surface before the map starts
+func CheckScripts() error {
+	return checkScripts(settings.CursorDance.GetScripts())
+}
+
+// CheckConfigScripts does the same as CheckScripts but for settings that are not current, like the ones of batch jobs
+func CheckConfigScripts(config *settings.Config) error {
+	return checkScripts(config.CursorDance.GetScripts())
+}
+
+func checkScripts(scripts []string) error {
+	for _, script := range scripts {
+		if _, err := compileScript(script); err != nil {
+			return fmt.Errorf("ScriptedMover: Failed to load \"%s\": %s", script, err)
+		}
+	}
+
+	return nil
+}
@@ -22,12 +22,14 @@ type SplineMover struct {
 	curve *curves.Spline
 }
 
+var splineSettings = RegisterWithSettings("spline", NewSplineMover, "Spline", settings.DefaultsFactory.InitSpline)
+
 func NewSplineMover() MultiPointMover {
 	return &SplineMover{basicMover: &basicMover{}}
 }
 
 func (mover *SplineMover) SetObjects(objs []objects.IHitObject) int {
-	config := settings.CursorDance.MoverSettings.Spline[mover.id%len(settings.CursorDance.MoverSettings.Spline)]
+	config := splineSettings.Get(mover.id)
 
 	points := make([]vector.Vector2f, 0)
 	timing := make([]float64, 0)
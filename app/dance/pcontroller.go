@@ -16,6 +16,7 @@ import (
 	"github.com/wieku/danser-go/app/utils"
 	"github.com/wieku/danser-go/framework/math/vector"
 	"github.com/wieku/danser-go/framework/platform"
+	"github.com/wieku/rplpa"
 	"log"
 	"strings"
 	"time"
@@ -40,6 +41,8 @@ type PlayerController struct {
 
 	quickRestart     bool
 	quickRestartTime float64
+
+	recorder *ReplayRecorder
 }
 
 func NewPlayerController() Controller {
@@ -77,6 +80,10 @@ func (controller *PlayerController) InitCursors() {
 			log.Println("InputManager: Raw input not supported!")
 		}
 	}
+
+	if settings.SAVEREPLAY {
+		controller.recorder = NewReplayRecorder(controller.cursors[0])
+	}
 }
 
 func (controller *PlayerController) KeyEvent(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, _ glfw.ModifierKey) {
@@ -170,6 +177,11 @@ func (controller *PlayerController) Update(time float64, delta float64) {
 	controller.ruleset.UpdatePostFor(controller.cursors[0], int64(time), false)
 	controller.ruleset.Update(int64(time))
 
+	if controller.recorder != nil {
+		controller.recorder.Update(time)
+		controller.recorder.UpdateLife(time, controller.ruleset.GetHP(controller.cursors[0]))
+	}
+
 	controller.lastTime = time
 
 	controller.cursors[0].Update(delta)
@@ -183,6 +195,19 @@ func (controller *PlayerController) GetCursors() []*graphics.Cursor {
 	return controller.cursors
 }
 
+func (controller *PlayerController) ExportReplay(failed bool, done func(replay *rplpa.Replay)) {
+	if controller.recorder == nil {
+		done(nil)
+		return
+	}
+
+	replay := newExportReplay(controller.bMap, controller.cursors[0].Name, controller.bMap.Diff.Mods, controller.recorder.GetFrames())
+
+	setReplayScore(replay, controller.ruleset.GetScore(controller.cursors[0]), failed, controller.recorder.GetLifeBar())
+
+	done(replay)
+}
+
 func (controller *PlayerController) updateRaw(mousePos vector.Vector2f) {
 	hovered := controller.window.GetAttrib(glfw.Hovered) == 1
 
@@ -16,6 +16,7 @@ import (
 	//"github.com/thehowl/go-osuapi"
 	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/events"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/rulesets/osu"
 	"github.com/wieku/danser-go/app/settings"
@@ -58,6 +59,8 @@ type subControl struct {
 	relaxController *input.RelaxInputProcessor
 	mouseController schedulers.Scheduler
 	mods            difficulty.Modifier
+	integrity       *IntegrityReport
+	mania           bool // Frames hold pressed osu!mania columns instead of cursor position
 }
 
 func NewSubControl() *subControl {
@@ -72,6 +75,7 @@ type ReplayController struct {
 	controllers []*subControl
 	ruleset     *osu.OsuRuleSet
 	lastTime    float64
+	verified    bool
 }
 
 func NewReplayController() Controller {
@@ -124,18 +128,10 @@ func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
 	for i, replay := range candidates {
 		log.Println(fmt.Sprintf("Loading replay for \"%s\":", replay.Username))
 
-		control := NewSubControl()
-		control.mods = difficulty.Modifier(replay.Mods)
-
-		log.Println("\tMods:", control.mods.String())
-
-		loadFrames(control, replay.ReplayData)
+		control := newReplaySubControl(replay)
 
 		mxCombo := replay.MaxCombo
 
-		control.newHandling = replay.OsuVersion >= 20190506 // This was when slider scoring was changed, so *I think* replay handling as well: https://osu.ppy.sh/home/changelog/cuttingedge/20190506
-		control.oldSpinners = replay.OsuVersion < 20190510  // This was when spinner scoring was changed: https://osu.ppy.sh/home/changelog/cuttingedge/20190510.2
-
 		controller.replays = append(controller.replays, RpData{replay.Username + string(rune(unicode.MaxRune-i)), (control.mods & displayedMods).String(), control.mods, 100, 0, int64(mxCombo), osu.NONE, replay.ScoreID, replay.Timestamp})
 		controller.controllers = append(controller.controllers, control)
 
@@ -147,8 +143,15 @@ func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
 		control := NewSubControl()
 		control.mods = difficulty.Autoplay | beatMap.Diff.Mods
 
-		control.danceController = NewGenericController()
-		control.danceController.SetBeatMap(beatMap)
+		if beatMap.Mode == beatmap.ModeMania {
+			// There's no cursor to dance with, so autoplay presses the columns like a replay
+			control.mania = true
+			control.newHandling = true
+			control.frames = newManiaAutoplayFrames(beatMap)
+		} else {
+			control.danceController = NewGenericController()
+			control.danceController.SetBeatMap(beatMap)
+		}
 
 		controller.replays = append([]RpData{{settings.Knockout.DanserName, control.mods.String(), control.mods, 100, 0, 0, osu.NONE, -1, time.Now()}}, controller.replays...)
 		controller.controllers = append([]*subControl{control}, controller.controllers...)
@@ -161,6 +164,35 @@ func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
 	settings.PLAYERS = len(controller.replays)
 }
 
+func newReplaySubControl(replay *rplpa.Replay) *subControl {
+	control := NewSubControl()
+	control.mods = difficulty.Modifier(replay.Mods)
+	control.mania = replay.PlayMode == rplpa.MANIA
+
+	log.Println("\tMods:", control.mods.String())
+
+	loadFrames(control, replay.ReplayData)
+
+	control.integrity = NewIntegrityReport(replay, control.frames)
+
+	log.Println(fmt.Sprintf("\tMean cv frametime: %.2fms", control.integrity.FrameTimes.Median))
+
+	if control.integrity.Timewarped {
+		log.Println("\tWARNING!!! THIS REPLAY WAS PROBABLY TIMEWARPED!!!")
+		events.EmitWarning(fmt.Sprintf("Replay of \"%s\" was probably timewarped", replay.Username))
+	} else if len(control.integrity.TimewarpSegments) > 0 {
+		log.Println(fmt.Sprintf("\tWARNING!!! %d PARTS OF THIS REPLAY WERE PROBABLY TIMEWARPED!!!", len(control.integrity.TimewarpSegments)))
+		events.EmitWarning(fmt.Sprintf("%d parts of \"%s\"'s replay were probably timewarped", len(control.integrity.TimewarpSegments), replay.Username))
+	}
+
+	log.Println(fmt.Sprintf("\tReplay duration: %dms", control.integrity.Duration))
+
+	control.newHandling = replay.OsuVersion >= 20190506 // This was when slider scoring was changed, so *I think* replay handling as well: https://osu.ppy.sh/home/changelog/cuttingedge/20190506
+	control.oldSpinners = replay.OsuVersion < 20190510  // This was when spinner scoring was changed: https://osu.ppy.sh/home/changelog/cuttingedge/20190510.2
+
+	return control
+}
+
 func organizeReplays() {
 	replayDir := filepath.Join(env.DataDir(), replaysMaster)
 
@@ -231,6 +263,11 @@ func (controller *ReplayController) getCandidates() (candidates []*rplpa.Replay)
 			return
 		}
 
+		if (replayD.PlayMode == rplpa.MANIA) != (controller.bMap.Mode == beatmap.ModeMania) || (replayD.PlayMode != rplpa.OSU && replayD.PlayMode != rplpa.MANIA) {
+			log.Println("Excluding for different game mode:", replayD.Username)
+			return
+		}
+
 		if !difficulty.Modifier(replayD.Mods).Compatible() || difficulty.Modifier(replayD.Mods).Active(difficulty.Target) {
 			log.Println("Excluding for incompatible mods:", replayD.Username)
 			return
@@ -290,41 +327,6 @@ func loadFrames(subController *subControl, frames []*rplpa.ReplayData) {
 		frames = frames[1:]
 	}
 
-	times := make([]float64, 0, len(frames))
-
-	duration := 0
-
-	for _, frame := range frames {
-		if frame.Time >= 0 {
-			times = append(times, float64(frame.Time))
-		}
-
-		duration += int(frame.Time)
-	}
-
-	sort.Float64s(times)
-
-	l := len(times)
-
-	meanFrameTime := times[l/2]
-
-	if l%2 == 0 {
-		meanFrameTime = (times[l/2] + times[l/2-1]) / 2
-	}
-
-	diff := difficulty.NewDifficulty(5, 5, 5, 5)
-	diff.SetMods(subController.mods)
-
-	meanFrameTime = diff.GetModifiedTime(meanFrameTime)
-
-	log.Println(fmt.Sprintf("\tMean cv frametime: %.2fms", meanFrameTime))
-
-	if meanFrameTime <= 13 && !diff.CheckModActive(difficulty.Autoplay|difficulty.Relax|difficulty.Relax2) {
-		log.Println("\tWARNING!!! THIS REPLAY WAS PROBABLY TIMEWARPED!!!")
-	}
-
-	log.Println(fmt.Sprintf("\tReplay duration: %dms", duration))
-
 	subController.frames = frames
 }
 
@@ -355,11 +357,14 @@ func (controller *ReplayController) InitCursors() {
 			cursor.OldSpinnerScoring = controller.controllers[i].oldSpinners
 			cursor.IsReplay = true
 
-			cursor.SetPos(vector.NewVec2f(c.frames[0].MouseX, c.frames[0].MouseY))
-			cursor.Update(0)
+			// osu!mania autoplay is the only replay without a file
+			if c.integrity == nil {
+				cursor.IsPlayer = true
+				cursor.IsAutoplay = true
+			}
 
-			c.replayTime += c.frames[0].Time
-			c.frames = c.frames[1:]
+			c.initReplayCursor(cursor)
+			cursor.Update(0)
 
 			controller.cursors = append(controller.cursors, cursor)
 		}
@@ -373,20 +378,40 @@ func (controller *ReplayController) InitCursors() {
 
 	controller.ruleset = osu.NewOsuRuleset(controller.bMap, controller.cursors, modifiers)
 
-	for i := range controller.controllers {
-		if controller.replays[i].ModsV.Active(difficulty.Relax) {
-			controller.controllers[i].relaxController = input.NewRelaxInputProcessor(controller.ruleset, controller.cursors[i])
+	for i, c := range controller.controllers {
+		if c.danceController == nil {
+			c.initInputProcessors(controller.ruleset, controller.cursors[i], controller.bMap)
 		}
+	}
+}
 
-		if controller.replays[i].ModsV.Active(difficulty.Relax2) {
-			controller.controllers[i].mouseController = schedulers.NewGenericScheduler(movers.NewLinearMoverSimple, 0, 0)
+// initReplayCursor moves the cursor to the first replay frame and consumes it
+func (c *subControl) initReplayCursor(cursor *graphics.Cursor) {
+	if c.mania {
+		cursor.SetPos(vector.NewVec2f(256, 192))
+		cursor.ManiaKeys = uint32(c.frames[0].MouseX)
+	} else {
+		cursor.SetPos(vector.NewVec2f(c.frames[0].MouseX, c.frames[0].MouseY))
+	}
 
-			diff := difficulty.NewDifficulty(controller.bMap.Diff.GetHP(), controller.bMap.Diff.GetCS(), controller.bMap.Diff.GetOD(), controller.bMap.Diff.GetAR())
-			diff.SetMods(controller.replays[i].ModsV)
-			diff.SetCustomSpeed(controller.bMap.Diff.CustomSpeed)
+	c.replayTime += c.frames[0].Time
+	c.frames = c.frames[1:]
+}
 
-			controller.controllers[i].mouseController.Init(controller.bMap.GetObjectsCopy(), diff, controller.cursors[i], spinners.GetMoverCtorByName("circle"), false)
-		}
+// initInputProcessors creates processors simulating input that is missing in relax/autopilot replays
+func (c *subControl) initInputProcessors(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor, bMap *beatmap.BeatMap) {
+	if c.mods.Active(difficulty.Relax) {
+		c.relaxController = input.NewRelaxInputProcessor(ruleset, cursor)
+	}
+
+	if c.mods.Active(difficulty.Relax2) {
+		c.mouseController = schedulers.NewGenericScheduler(movers.NewLinearMoverSimple, 0, 0)
+
+		diff := difficulty.NewDifficulty(bMap.Diff.GetHP(), bMap.Diff.GetCS(), bMap.Diff.GetOD(), bMap.Diff.GetAR())
+		diff.SetMods(c.mods)
+		diff.SetCustomSpeed(bMap.Diff.CustomSpeed)
+
+		c.mouseController.Init(bMap.GetObjectsCopy(), diff, cursor, spinners.GetMoverCtorByName("circle"), false)
 	}
 }
 
@@ -411,6 +436,35 @@ func (controller *ReplayController) Update(time float64, delta float64) {
 		controller.replays[i].Combo = int64(sc.Combo)
 		controller.replays[i].Grade = sc.Grade
 	}
+
+	if !controller.verified && controller.ruleset.IsEnded() {
+		controller.verified = true
+		controller.verifyScores()
+	}
+}
+
+// verifyScores compares final scores with replay headers. Per object verification needs a listener, so it's left to -analyze
+func (controller *ReplayController) verifyScores() {
+	for i, c := range controller.controllers {
+		if c.integrity == nil {
+			continue
+		}
+
+		mismatches := compareScore(c.integrity.replay, controller.ruleset.GetScore(controller.cursors[i]))
+
+		if len(mismatches) == 0 {
+			log.Println(fmt.Sprintf("Score of \"%s\" matches the replay", c.integrity.Player))
+			continue
+		}
+
+		values := make([]string, 0, len(mismatches))
+		for _, m := range mismatches {
+			values = append(values, fmt.Sprintf("%s: %d (danser: %d)", m.Field, m.Replay, m.Simulated))
+		}
+
+		log.Println(fmt.Sprintf("Score of \"%s\" differs from the replay! %s. Use -analyze to find objects that were judged differently", c.integrity.Player, strings.Join(values, ", ")))
+		events.EmitWarning(fmt.Sprintf("Score of \"%s\" differs from the replay: %s", c.integrity.Player, strings.Join(values, ", ")))
+	}
 }
 
 func (controller *ReplayController) updateMain(nTime float64) {
@@ -436,110 +490,118 @@ func (controller *ReplayController) updateMain(nTime float64) {
 
 			c.lastTime = int64(nTime)
 		} else {
-			wasUpdated := false
+			c.updateReplay(controller.ruleset, controller.cursors[i], nTime)
+		}
+	}
 
-			isRelax := (controller.replays[i].ModsV & difficulty.Relax) > 0
-			isAutopilot := (controller.replays[i].ModsV & difficulty.Relax2) > 0
+	if int64(nTime) != int64(controller.lastTime) {
+		controller.ruleset.Update(int64(nTime))
+	}
 
-			if isAutopilot {
-				c.mouseController.Update(nTime)
-			}
+	controller.lastTime = nTime
+}
 
-			if c.replayIndex < len(c.frames) {
-				for c.replayIndex < len(c.frames) && c.replayTime+c.frames[c.replayIndex].Time <= int64(nTime) {
-					frame := c.frames[c.replayIndex]
-					c.replayTime += frame.Time
+// updateReplay feeds replay frames up to nTime to the ruleset
+func (c *subControl) updateReplay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor, nTime float64) {
+	wasUpdated := false
 
-					// If next frame is not in the next millisecond, assume it's -36ms slider end
-					processAhead := true
-					if c.replayIndex+1 < len(c.frames) && c.frames[c.replayIndex+1].Time == 1 {
-						processAhead = false
-					}
+	isRelax := (c.mods & difficulty.Relax) > 0
+	isAutopilot := (c.mods & difficulty.Relax2) > 0
 
-					if !isAutopilot {
-						controller.cursors[i].SetPos(vector.NewVec2f(frame.MouseX, frame.MouseY))
-					}
+	if isAutopilot {
+		c.mouseController.Update(nTime)
+	}
 
-					controller.cursors[i].LastFrameTime = controller.cursors[i].CurrentFrameTime
-					controller.cursors[i].CurrentFrameTime = c.replayTime
-					controller.cursors[i].IsReplayFrame = true
+	if c.replayIndex < len(c.frames) {
+		for c.replayIndex < len(c.frames) && c.replayTime+c.frames[c.replayIndex].Time <= int64(nTime) {
+			frame := c.frames[c.replayIndex]
+			c.replayTime += frame.Time
 
-					if !isRelax {
-						controller.cursors[i].LeftKey = frame.KeyPressed.LeftClick && frame.KeyPressed.Key1
-						controller.cursors[i].RightKey = frame.KeyPressed.RightClick && frame.KeyPressed.Key2
+			// If next frame is not in the next millisecond, assume it's -36ms slider end
+			processAhead := true
+			if c.replayIndex+1 < len(c.frames) && c.frames[c.replayIndex+1].Time == 1 {
+				processAhead = false
+			}
 
-						controller.cursors[i].LeftMouse = frame.KeyPressed.LeftClick && !frame.KeyPressed.Key1
-						controller.cursors[i].RightMouse = frame.KeyPressed.RightClick && !frame.KeyPressed.Key2
+			if c.mania {
+				cursor.ManiaKeys = uint32(frame.MouseX)
+			} else if !isAutopilot {
+				cursor.SetPos(vector.NewVec2f(frame.MouseX, frame.MouseY))
+			}
 
-						controller.cursors[i].LeftButton = frame.KeyPressed.LeftClick
-						controller.cursors[i].RightButton = frame.KeyPressed.RightClick
-					} else {
-						c.relaxController.Update(float64(c.replayTime))
-					}
+			cursor.LastFrameTime = cursor.CurrentFrameTime
+			cursor.CurrentFrameTime = c.replayTime
+			cursor.IsReplayFrame = true
 
-					controller.cursors[i].SmokeKey = frame.KeyPressed.Smoke
+			if isRelax {
+				c.relaxController.Update(float64(c.replayTime))
+			} else if !c.mania {
+				cursor.LeftKey = frame.KeyPressed.LeftClick && frame.KeyPressed.Key1
+				cursor.RightKey = frame.KeyPressed.RightClick && frame.KeyPressed.Key2
 
-					controller.ruleset.UpdateClickFor(controller.cursors[i], c.replayTime)
-					controller.ruleset.UpdateNormalFor(controller.cursors[i], c.replayTime, processAhead)
+				cursor.LeftMouse = frame.KeyPressed.LeftClick && !frame.KeyPressed.Key1
+				cursor.RightMouse = frame.KeyPressed.RightClick && !frame.KeyPressed.Key2
 
-					// New replays (after 20190506) scores object ends only on replay frame
-					if c.newHandling || c.replayIndex == len(c.frames)-1 {
-						controller.ruleset.UpdatePostFor(controller.cursors[i], c.replayTime, processAhead)
-					} else {
-						localIndex := mutils.Clamp(c.replayIndex+1, 0, len(c.frames)-1)
-						localFrame := c.frames[localIndex]
+				cursor.LeftButton = frame.KeyPressed.LeftClick
+				cursor.RightButton = frame.KeyPressed.RightClick
+			}
 
-						// HACK for older replays: update object ends till the next frame
-						for localTime := c.replayTime; localTime < c.replayTime+localFrame.Time; localTime++ {
-							controller.ruleset.UpdatePostFor(controller.cursors[i], localTime, false)
-						}
-					}
+			cursor.SmokeKey = frame.KeyPressed.Smoke
 
-					wasUpdated = true
+			ruleset.UpdateClickFor(cursor, c.replayTime)
+			ruleset.UpdateNormalFor(cursor, c.replayTime, processAhead)
 
-					c.replayIndex++
-				}
+			// New replays (after 20190506) scores object ends only on replay frame
+			if c.newHandling || c.replayIndex == len(c.frames)-1 {
+				ruleset.UpdatePostFor(cursor, c.replayTime, processAhead)
+			} else {
+				localIndex := mutils.Clamp(c.replayIndex+1, 0, len(c.frames)-1)
+				localFrame := c.frames[localIndex]
 
-				if !wasUpdated {
-					if !isAutopilot {
-						localIndex := mutils.Clamp(c.replayIndex, 0, len(c.frames)-1)
+				// HACK for older replays: update object ends till the next frame
+				for localTime := c.replayTime; localTime < c.replayTime+localFrame.Time; localTime++ {
+					ruleset.UpdatePostFor(cursor, localTime, false)
+				}
+			}
 
-						progress := math32.Min(float32(nTime-float64(c.replayTime)), float32(c.frames[localIndex].Time)) / float32(c.frames[localIndex].Time)
+			wasUpdated = true
 
-						prevIndex := mutils.Max(0, localIndex-1)
+			c.replayIndex++
+		}
 
-						mX := (c.frames[localIndex].MouseX-c.frames[prevIndex].MouseX)*progress + c.frames[prevIndex].MouseX
-						mY := (c.frames[localIndex].MouseY-c.frames[prevIndex].MouseY)*progress + c.frames[prevIndex].MouseY
+		if !wasUpdated {
+			if !isAutopilot && !c.mania {
+				localIndex := mutils.Clamp(c.replayIndex, 0, len(c.frames)-1)
 
-						controller.cursors[i].SetPos(vector.NewVec2f(mX, mY))
-					}
+				progress := math32.Min(float32(nTime-float64(c.replayTime)), float32(c.frames[localIndex].Time)) / float32(c.frames[localIndex].Time)
 
-					controller.cursors[i].IsReplayFrame = false
-				}
+				prevIndex := mutils.Max(0, localIndex-1)
 
-				if c.replayIndex >= len(c.frames) {
-					controller.ruleset.PlayerStopped(controller.cursors[i], c.replayTime)
-				}
-			} else {
-				controller.cursors[i].LeftKey = false
-				controller.cursors[i].RightKey = false
-				controller.cursors[i].LeftMouse = false
-				controller.cursors[i].RightMouse = false
-				controller.cursors[i].LeftButton = false
-				controller.cursors[i].RightButton = false
+				mX := (c.frames[localIndex].MouseX-c.frames[prevIndex].MouseX)*progress + c.frames[prevIndex].MouseX
+				mY := (c.frames[localIndex].MouseY-c.frames[prevIndex].MouseY)*progress + c.frames[prevIndex].MouseY
 
-				controller.ruleset.UpdateClickFor(controller.cursors[i], int64(nTime))
-				controller.ruleset.UpdateNormalFor(controller.cursors[i], int64(nTime), false)
-				controller.ruleset.UpdatePostFor(controller.cursors[i], int64(nTime), false)
+				cursor.SetPos(vector.NewVec2f(mX, mY))
 			}
+
+			cursor.IsReplayFrame = false
 		}
-	}
 
-	if int64(nTime) != int64(controller.lastTime) {
-		controller.ruleset.Update(int64(nTime))
+		if c.replayIndex >= len(c.frames) {
+			ruleset.PlayerStopped(cursor, c.replayTime)
+		}
+	} else {
+		cursor.LeftKey = false
+		cursor.RightKey = false
+		cursor.LeftMouse = false
+		cursor.RightMouse = false
+		cursor.LeftButton = false
+		cursor.RightButton = false
+		cursor.ManiaKeys = 0
+
+		ruleset.UpdateClickFor(cursor, int64(nTime))
+		ruleset.UpdateNormalFor(cursor, int64(nTime), false)
+		ruleset.UpdatePostFor(cursor, int64(nTime), false)
 	}
-
-	controller.lastTime = nTime
 }
 
 func (controller *ReplayController) GetCursors() []*graphics.Cursor {
@@ -554,6 +616,11 @@ func (controller *ReplayController) GetRuleset() *osu.OsuRuleSet {
 	return controller.ruleset
 }
 
+// GetIntegrityReport returns the report of player's replay, nil if player isn't using one
+func (controller *ReplayController) GetIntegrityReport(player int) *IntegrityReport {
+	return controller.controllers[player].integrity
+}
+
 func (controller *ReplayController) GetBeatMap() *beatmap.BeatMap {
 	return controller.bMap
 }
@@ -0,0 +1,113 @@
+package dance
+
+import (
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/rplpa"
+)
+
+const (
+	// Interval between replay frames when keys don't change, same as stable's 60Hz
+	recordInterval = 1000.0 / 60
+
+	// Interval between life bar graph samples
+	lifeBarInterval = 2000
+)
+
+// ReplayRecorder captures cursor's position and keys in a form of osu! replay frames
+type ReplayRecorder struct {
+	cursor *graphics.Cursor
+
+	frames  []*rplpa.ReplayData
+	lifeBar []rplpa.LifeBarGraph
+
+	lastTime int64
+	lastKeys int
+
+	lastLifeTime int64
+}
+
+func NewReplayRecorder(cursor *graphics.Cursor) *ReplayRecorder {
+	return &ReplayRecorder{
+		cursor: cursor,
+		frames: []*rplpa.ReplayData{
+			// Frames stable puts at the start of every replay, danser's replay loader expects them
+			{Time: 0, MouseX: 256, MouseY: -500, KeyPressed: &rplpa.KeyPressed{}},
+			{Time: -1, MouseX: 256, MouseY: -500, KeyPressed: &rplpa.KeyPressed{}},
+		},
+		lastTime:     -1,
+		lastKeys:     -1,
+		lastLifeTime: -lifeBarInterval,
+	}
+}
+
+// Update adds a frame if keys changed or enough time passed since the last frame
+func (recorder *ReplayRecorder) Update(time float64) {
+	iTime := int64(time)
+
+	if iTime <= recorder.lastTime && recorder.lastKeys >= 0 {
+		return
+	}
+
+	keys := recorder.getKeys()
+
+	if keys == recorder.lastKeys && float64(iTime-recorder.lastTime) < recordInterval {
+		return
+	}
+
+	recorder.frames = append(recorder.frames, &rplpa.ReplayData{
+		Time:   iTime - recorder.lastTime,
+		MouseX: recorder.cursor.RawPosition.X,
+		MouseY: recorder.cursor.RawPosition.Y,
+		KeyPressed: &rplpa.KeyPressed{
+			LeftClick:  keys&rplpa.LEFTCLICK > 0,
+			RightClick: keys&rplpa.RIGHTCLICK > 0,
+			Key1:       keys&rplpa.KEY1 > 0,
+			Key2:       keys&rplpa.KEY2 > 0,
+			Smoke:      keys&rplpa.SMOKE > 0,
+		},
+	})
+
+	recorder.lastTime = iTime
+	recorder.lastKeys = keys
+}
+
+// UpdateLife samples player's health (0-1) for the life bar graph
+func (recorder *ReplayRecorder) UpdateLife(time float64, hp float64) {
+	if int64(time)-recorder.lastLifeTime < lifeBarInterval {
+		return
+	}
+
+	recorder.lifeBar = append(recorder.lifeBar, rplpa.LifeBarGraph{Time: int32(time), HP: float32(hp)})
+	recorder.lastLifeTime = int64(time)
+}
+
+func (recorder *ReplayRecorder) getKeys() (keys int) {
+	cursor := recorder.cursor
+
+	if cursor.LeftKey {
+		keys |= rplpa.LEFTCLICK | rplpa.KEY1
+	} else if cursor.LeftMouse || cursor.LeftButton {
+		keys |= rplpa.LEFTCLICK
+	}
+
+	if cursor.RightKey {
+		keys |= rplpa.RIGHTCLICK | rplpa.KEY2
+	} else if cursor.RightMouse || cursor.RightButton {
+		keys |= rplpa.RIGHTCLICK
+	}
+
+	if cursor.SmokeKey {
+		keys |= rplpa.SMOKE
+	}
+
+	return
+}
+
+// GetFrames returns recorded frames, including stable's initial frames
+func (recorder *ReplayRecorder) GetFrames() []*rplpa.ReplayData {
+	return recorder.frames
+}
+
+func (recorder *ReplayRecorder) GetLifeBar() []rplpa.LifeBarGraph {
+	return recorder.lifeBar
+}
@@ -0,0 +1,244 @@
+package dance
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/bnch/uleb128"
+	"github.com/itchio/lzma"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/rplpa"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client version written to exported replays. It's new enough to use current slider and spinner handling when loaded back.
+const exportVersion = 20220216
+
+var invalidPathChars = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+
+// newExportReplay creates a replay with given frames, score has to be set with setReplayScore
+func newExportReplay(bMap *beatmap.BeatMap, username string, mods difficulty.Modifier, frames []*rplpa.ReplayData) *rplpa.Replay {
+	return &rplpa.Replay{
+		PlayMode:   rplpa.OSU,
+		OsuVersion: exportVersion,
+		BeatmapMD5: strings.ToLower(bMap.MD5),
+		Username:   username,
+		Mods:       uint32(mods),
+		Timestamp:  time.Now().UTC(),
+		ReplayData: frames,
+	}
+}
+
+func setReplayScore(replay *rplpa.Replay, score osu.Score, failed bool, lifeBar []rplpa.LifeBarGraph) {
+	replay.Count300 = uint16(score.Count300)
+	replay.Count100 = uint16(score.Count100)
+	replay.Count50 = uint16(score.Count50)
+	replay.CountGeki = uint16(score.CountGeki)
+	replay.CountKatu = uint16(score.CountKatu)
+	replay.CountMiss = uint16(score.CountMiss)
+	replay.Score = int32(score.Score)
+	replay.MaxCombo = uint16(score.Combo)
+	replay.Fullcombo = score.PerfectCombo
+	replay.LifebarGraph = lifeBar
+
+	replay.ReplayMD5 = replayHash(replay, stableGrade(score.Grade, failed), !failed)
+}
+
+// replayHash creates the same checksum stable puts in replays, it only depends on the header
+func replayHash(replay *rplpa.Replay, grade string, passed bool) string {
+	str := fmt.Sprintf("%dp%do%do%dt%da%sr%de%sy%so%du%s%d%s",
+		replay.Count100+replay.Count300,
+		replay.Count50,
+		replay.CountGeki,
+		replay.CountKatu,
+		replay.CountMiss,
+		replay.BeatmapMD5,
+		replay.MaxCombo,
+		dotNetBool(replay.Fullcombo),
+		replay.Username,
+		replay.Score,
+		grade,
+		replay.Mods,
+		dotNetBool(passed),
+	)
+
+	hash := md5.Sum([]byte(str))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func stableGrade(grade osu.Grade, failed bool) string {
+	if failed {
+		return "F"
+	}
+
+	switch grade {
+	case osu.SSH:
+		return "XH"
+	case osu.SS:
+		return "X"
+	case osu.NONE:
+		return "N"
+	}
+
+	return grade.String()
+}
+
+func dotNetBool(value bool) string {
+	if value {
+		return "True"
+	}
+
+	return "False"
+}
+
+// WriteReplay encodes replay in osu!'s .osr format
+func WriteReplay(w io.Writer, replay *rplpa.Replay) error {
+	buf := new(bytes.Buffer)
+
+	write := func(values ...any) {
+		for _, v := range values {
+			_ = binary.Write(buf, binary.LittleEndian, v)
+		}
+	}
+
+	writeString := func(s string) {
+		if s == "" {
+			buf.WriteByte(0)
+			return
+		}
+
+		buf.WriteByte(0x0b)
+		buf.Write(uleb128.Marshal(len(s)))
+		buf.WriteString(s)
+	}
+
+	write(replay.PlayMode, replay.OsuVersion)
+
+	writeString(replay.BeatmapMD5)
+	writeString(replay.Username)
+	writeString(replay.ReplayMD5)
+
+	write(replay.Count300, replay.Count100, replay.Count50, replay.CountGeki, replay.CountKatu, replay.CountMiss)
+	write(replay.Score, replay.MaxCombo, replay.Fullcombo, replay.Mods)
+
+	var lifeBar strings.Builder
+
+	for _, l := range replay.LifebarGraph {
+		lifeBar.WriteString(fmt.Sprintf("%d|%s,", l.Time, strconv.FormatFloat(float64(l.HP), 'f', -1, 32)))
+	}
+
+	writeString(lifeBar.String())
+
+	write(toTicks(replay.Timestamp))
+
+	data, err := compressFrames(replay.ReplayData)
+	if err != nil {
+		return err
+	}
+
+	write(int32(len(data)))
+	buf.Write(data)
+
+	write(replay.ScoreID)
+
+	_, err = w.Write(buf.Bytes())
+
+	return err
+}
+
+func compressFrames(frames []*rplpa.ReplayData) ([]byte, error) {
+	var raw strings.Builder
+
+	for _, frame := range frames {
+		keys := 0
+
+		if frame.KeyPressed != nil {
+			if frame.KeyPressed.LeftClick {
+				keys |= rplpa.LEFTCLICK
+			}
+
+			if frame.KeyPressed.RightClick {
+				keys |= rplpa.RIGHTCLICK
+			}
+
+			if frame.KeyPressed.Key1 {
+				keys |= rplpa.KEY1
+			}
+
+			if frame.KeyPressed.Key2 {
+				keys |= rplpa.KEY2
+			}
+
+			if frame.KeyPressed.Smoke {
+				keys |= rplpa.SMOKE
+			}
+		}
+
+		raw.WriteString(fmt.Sprintf("%d|%s|%s|%d,",
+			frame.Time,
+			strconv.FormatFloat(float64(frame.MouseX), 'f', -1, 32),
+			strconv.FormatFloat(float64(frame.MouseY), 'f', -1, 32),
+			keys,
+		))
+	}
+
+	data := []byte(raw.String())
+
+	buf := new(bytes.Buffer)
+
+	writer := lzma.NewWriterSize(buf, int64(len(data)))
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// toTicks converts time to .NET ticks (100ns intervals since 0001-01-01)
+func toTicks(t time.Time) int64 {
+	base := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	return (t.Unix()-base)*10000000 + int64(t.Nanosecond()/100)
+}
+
+// SaveReplay writes replay to danser's replays directory, where knockout mode can pick it up
+func SaveReplay(bMap *beatmap.BeatMap, replay *rplpa.Replay) (string, error) {
+	dir := filepath.Join(env.DataDir(), replaysMaster, strings.ToLower(bMap.MD5))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s - %s - %s [%s] (%s) Osu.osr", replay.Username, bMap.Artist, bMap.Name, bMap.Difficulty, replay.Timestamp.Local().Format("2006-01-02_15-04-05"))
+
+	path := filepath.Join(dir, invalidPathChars.ReplaceAllString(name, "_"))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	if err = WriteReplay(file, replay); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,208 @@
+package dance
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"github.com/wieku/rplpa"
+	"log"
+)
+
+// How long after the last object simulation is allowed to run if ruleset doesn't finish on its own
+const simulationTail = 10000
+
+type HitEvent struct {
+	Time   int64
+	Object int64
+	X, Y   float64
+	Result string
+	Combo  int64
+	Score  int64
+}
+
+type SimulationResult struct {
+	Player string
+	Mods   string
+
+	Score     int64
+	Accuracy  float64
+	Grade     string
+	MaxCombo  uint
+	Perfect   bool
+	Count300  uint
+	CountGeki uint
+	Count100  uint
+	CountKatu uint
+	Count50   uint
+	CountMiss uint
+	CountSB   uint
+	PP        performance.PPv2Results
+
+	Failed   bool
+	FailTime int64
+
+	Timeline []HitEvent
+
+	LifeBar []rplpa.LifeBarGraph `json:"-"`
+
+	Integrity    *IntegrityReport
+	Verification *Verification
+
+	// Simulated health at times of replay's LifebarGraph points
+	replayLifeBar []float64
+
+	score osu.Score
+}
+
+// SimulateReplay runs replay frames through OsuRuleSet without creating any graphics or audio resources.
+// Beatmap has to have its objects parsed but they don't need to have difficulty (and graphics) set.
+func SimulateReplay(beatMap *beatmap.BeatMap, replay *rplpa.Replay) *SimulationResult {
+	if len(beatMap.HitObjects) == 0 {
+		panic("Beatmap has no hit objects")
+	}
+
+	log.Println(fmt.Sprintf("Simulating replay of \"%s\":", replay.Username))
+
+	control := newReplaySubControl(replay)
+
+	cursor := graphics.NewHeadlessCursor()
+	cursor.Name = replay.Username
+	cursor.ScoreID = replay.ScoreID
+	cursor.ScoreTime = replay.Timestamp
+	cursor.OldSpinnerScoring = control.oldSpinners
+	cursor.IsReplay = true
+
+	control.initReplayCursor(cursor)
+
+	ruleset := osu.NewOsuRuleset(beatMap, []*graphics.Cursor{cursor}, []difficulty.Modifier{control.mods})
+	ruleset.SetHeadless(true)
+
+	control.initInputProcessors(ruleset, cursor, beatMap)
+
+	result := &SimulationResult{
+		Player:    replay.Username,
+		Mods:      control.mods.String(),
+		FailTime:  -1,
+		Integrity: control.integrity,
+	}
+
+	combo := int64(0)
+
+	ruleset.AddListener(func(_ *graphics.Cursor, time int64, number int64, position vector.Vector2d, hResult osu.HitResult, comboResult osu.ComboResult, _ performance.PPv2Results, score int64) {
+		switch comboResult {
+		case osu.Reset:
+			combo = 0
+		case osu.Increase:
+			combo++
+		}
+
+		object := beatMap.HitObjects[number]
+
+		_, isCircle := object.(*objects.Circle)
+		_, isSlider := object.(*objects.Slider)
+
+		// Hold's head and tail can't be told apart here, so only single notes are counted
+		note, isNote := object.(*objects.ManiaNote)
+		isNote = isNote && !note.IsHold()
+
+		if ((isCircle || isNote) && hResult&osu.BaseHits > 0) || (isSlider && hResult&osu.SliderStart > 0) {
+			result.Integrity.AddHitError(float64(time) - object.GetStartTime())
+		}
+
+		if hResult&osu.BaseHitsM == 0 {
+			return
+		}
+
+		result.Timeline = append(result.Timeline, HitEvent{
+			Time:   time,
+			Object: number,
+			X:      position.X,
+			Y:      position.Y,
+			Result: resultName(hResult, control.mania),
+			Combo:  combo,
+			Score:  score,
+		})
+	})
+
+	ruleset.AddFailListener(func(_ *graphics.Cursor) {
+		result.Failed = true
+		result.FailTime = control.replayTime
+	})
+
+	endTime := int64(beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime()) + simulationTail
+
+	lastLifeTime := int64(-lifeBarInterval)
+
+	for time := mutils.Min(control.replayTime, 0); !ruleset.IsEnded() && time <= endTime; time++ {
+		control.updateReplay(ruleset, cursor, float64(time))
+		ruleset.Update(time)
+
+		if time >= 0 && time-lastLifeTime >= lifeBarInterval {
+			result.LifeBar = append(result.LifeBar, rplpa.LifeBarGraph{Time: int32(time), HP: float32(ruleset.GetHP(cursor))})
+			lastLifeTime = time
+		}
+
+		for len(result.replayLifeBar) < len(replay.LifebarGraph) && int64(replay.LifebarGraph[len(result.replayLifeBar)].Time) <= time {
+			result.replayLifeBar = append(result.replayLifeBar, ruleset.GetHP(cursor))
+		}
+	}
+
+	score := ruleset.GetScore(cursor)
+
+	result.score = score
+
+	result.Score = score.Score
+	result.Accuracy = score.Accuracy
+	result.Grade = score.Grade.String()
+	result.MaxCombo = score.Combo
+	result.Perfect = score.PerfectCombo
+	result.Count300 = score.Count300
+	result.CountGeki = score.CountGeki
+	result.Count100 = score.Count100
+	result.CountKatu = score.CountKatu
+	result.Count50 = score.Count50
+	result.CountMiss = score.CountMiss
+	result.CountSB = score.CountSB
+	result.PP = score.PP
+
+	result.Integrity.SetScore(score)
+
+	result.Verification = VerifyScore(beatMap, replay, result, verificationWindow)
+
+	if result.Verification.Matches {
+		log.Println("Simulated score matches the replay")
+	} else {
+		log.Println(fmt.Sprintf("Simulated score differs from the replay, reporting %d judgements around the first difference", len(result.Verification.Suspects)))
+	}
+
+	return result
+}
+
+// resultName returns judgement's name, in osu!mania MAX and 200 are separate judgements
+func resultName(result osu.HitResult, mania bool) string {
+	if mania {
+		switch result {
+		case osu.Hit300g:
+			return "MAX"
+		case osu.Hit100k:
+			return "200"
+		}
+	}
+
+	switch result & osu.BaseHitsM {
+	case osu.Hit300:
+		return "300"
+	case osu.Hit100:
+		return "100"
+	case osu.Hit50:
+		return "50"
+	}
+
+	return "Miss"
+}
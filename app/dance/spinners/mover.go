@@ -1,6 +1,8 @@
 package spinners
 
 import (
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/math/vector"
 	"strings"
 )
@@ -14,23 +16,37 @@ type SpinnerMover interface {
 	GetPositionAt(time float64) vector.Vector2f
 }
 
-func GetMoverByName(name string) SpinnerMover {
-	switch strings.ToLower(name) {
-	case "heart":
-		return NewHeartMover()
-	case "triangle":
-		return NewTriangleMover()
-	case "square":
-		return NewSquareMover()
-	case "cube":
-		return NewCubeMover()
-	default:
-		return NewCircleMover()
+var registry = make(map[string]func() SpinnerMover)
+
+func init() {
+	Register("heart", func() SpinnerMover { return NewHeartMover() })
+	Register("triangle", func() SpinnerMover { return NewTriangleMover() })
+	Register("square", func() SpinnerMover { return NewSquareMover() })
+	Register("cube", func() SpinnerMover { return NewCubeMover() })
+	Register("circle", func() SpinnerMover { return NewCircleMover() })
+}
+
+// Register adds a spinner mover under the given name, making it available in settings
+func Register(name string, ctor func() SpinnerMover) {
+	name = strings.ToLower(name)
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("Spinner mover \"%s\" is already registered", name))
 	}
+
+	registry[name] = ctor
+
+	settings.RegisterSpinnerMover(name)
+}
+
+func GetMoverByName(name string) SpinnerMover {
+	return GetMoverCtorByName(name)()
 }
 
 func GetMoverCtorByName(name string) func() SpinnerMover {
-	return func() SpinnerMover {
-		return GetMoverByName(name)
+	if ctor, ok := registry[strings.ToLower(name)]; ok {
+		return ctor
 	}
+
+	return registry["circle"]
 }
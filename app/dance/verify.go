@@ -0,0 +1,213 @@
+package dance
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/rplpa"
+	"math"
+)
+
+// Number of judgements reported by SimulateReplay before and after the first one that differs
+const verificationWindow = 4
+
+// Difference between replay's and simulated health (0 to 1) that means some judgement was different
+const lifeBarTolerance = 0.1
+
+type ScoreMismatch struct {
+	Field     string
+	Replay    int64
+	Simulated int64
+}
+
+// SuspectObject is a judgement where danser probably went a different way than osu!, or one of its neighbours
+type SuspectObject struct {
+	Object int64
+	Time   int64
+	Type   string
+	TwoB   bool // Object starts before the previous one ends
+	Result string
+	Combo  int64
+	Reason string
+}
+
+type Verification struct {
+	Matches    bool
+	Mismatches []ScoreMismatch
+	Suspects   []SuspectObject
+}
+
+// compareScore returns the values that differ between the replay header and simulated score
+func compareScore(replay *rplpa.Replay, score osu.Score) (mismatches []ScoreMismatch) {
+	check := func(field string, replay, simulated int64) {
+		if replay != simulated {
+			mismatches = append(mismatches, ScoreMismatch{
+				Field:     field,
+				Replay:    replay,
+				Simulated: simulated,
+			})
+		}
+	}
+
+	boolToInt := func(b bool) int64 {
+		if b {
+			return 1
+		}
+
+		return 0
+	}
+
+	check("Score", int64(replay.Score), score.Score)
+	check("MaxCombo", int64(replay.MaxCombo), int64(score.Combo))
+	check("Perfect", boolToInt(replay.Fullcombo), boolToInt(score.PerfectCombo))
+	check("Count300", int64(replay.Count300), int64(score.Count300))
+	check("CountGeki", int64(replay.CountGeki), int64(score.CountGeki))
+	check("Count100", int64(replay.Count100), int64(score.Count100))
+	check("CountKatu", int64(replay.CountKatu), int64(score.CountKatu))
+	check("Count50", int64(replay.Count50), int64(score.Count50))
+	check("CountMiss", int64(replay.CountMiss), int64(score.CountMiss))
+
+	return
+}
+
+// VerifyScore compares simulated score with the replay header. If they differ, it finds the first judgement where the
+// simulation surely went a different way and reports it as a suspect together with window judgements before and after it.
+func VerifyScore(beatMap *beatmap.BeatMap, replay *rplpa.Replay, result *SimulationResult, window int) *Verification {
+	verification := &Verification{
+		Mismatches: compareScore(replay, result.score),
+	}
+
+	verification.Matches = len(verification.Mismatches) == 0
+
+	if verification.Matches {
+		return verification
+	}
+
+	newSuspect := func(event HitEvent, reason string) SuspectObject {
+		return SuspectObject{
+			Object: event.Object,
+			Time:   event.Time,
+			Type:   objectTypeName(beatMap.HitObjects[event.Object]),
+			TwoB:   isTwoB(beatMap, event.Object),
+			Result: event.Result,
+			Combo:  event.Combo,
+			Reason: reason,
+		}
+	}
+
+	index, reason := findDivergence(beatMap, replay, result)
+
+	if index == -1 {
+		if result.Score != int64(replay.Score) { // Judgements match so it's probably spinner scoring
+			for _, event := range result.Timeline {
+				if _, ok := beatMap.HitObjects[event.Object].(*objects.Spinner); ok && len(verification.Suspects) < 2*window+1 {
+					verification.Suspects = append(verification.Suspects, newSuspect(event, "score differs while judgements match"))
+				}
+			}
+		}
+
+		return verification
+	}
+
+	for i := mutils.Max(index-window, 0); i <= mutils.Min(index+window, len(result.Timeline)-1); i++ {
+		eReason := ""
+		if i == index {
+			eReason = reason
+		}
+
+		verification.Suspects = append(verification.Suspects, newSuspect(result.Timeline[i], eReason))
+	}
+
+	return verification
+}
+
+// findDivergence returns the index of the first judgement in the timeline after which simulation can't match the replay:
+// count of a judgement or combo goes over the replay's totals, or it's the first judgement after replay's and simulated
+// life bars stopped matching. It returns -1 if there's no such judgement.
+func findDivergence(beatMap *beatmap.BeatMap, replay *rplpa.Replay, result *SimulationResult) (index int, reason string) {
+	totals := map[string]uint16{
+		"300":  replay.Count300,
+		"100":  replay.Count100,
+		"50":   replay.Count50,
+		"Miss": replay.CountMiss,
+	}
+
+	if beatMap.Mode == beatmap.ModeMania {
+		totals["MAX"] = replay.CountGeki
+		totals["200"] = replay.CountKatu
+	}
+
+	counts := make(map[string]uint16)
+
+	index = -1
+
+	for i, event := range result.Timeline {
+		counts[event.Result]++
+
+		if total, ok := totals[event.Result]; ok && counts[event.Result] > total {
+			index, reason = i, fmt.Sprintf("%s number %d, replay has %d in total", event.Result, counts[event.Result], total)
+			break
+		}
+
+		if event.Combo > int64(replay.MaxCombo) {
+			index, reason = i, fmt.Sprintf("combo %d is higher than replay's max combo %d", event.Combo, replay.MaxCombo)
+			break
+		}
+	}
+
+	lastMatch := int64(math.MinInt64)
+
+	for i, hp := range result.replayLifeBar {
+		point := replay.LifebarGraph[i]
+
+		if math.Abs(hp-float64(point.HP)) <= lifeBarTolerance {
+			lastMatch = int64(point.Time)
+			continue
+		}
+
+		// Judgement that changed the health happened after the last matching point
+		for j, event := range result.Timeline {
+			if index != -1 && j >= index {
+				break
+			}
+
+			if event.Time > lastMatch {
+				index, reason = j, fmt.Sprintf("life bar differs at %dms, replay has %.2f, danser %.2f", point.Time, point.HP, hp)
+				break
+			}
+		}
+
+		break
+	}
+
+	return
+}
+
+func objectTypeName(object objects.IHitObject) string {
+	switch o := object.(type) {
+	case *objects.Circle:
+		return "Circle"
+	case *objects.Slider:
+		return "Slider"
+	case *objects.Spinner:
+		return "Spinner"
+	case *objects.ManiaNote:
+		if o.IsHold() {
+			return "Hold"
+		}
+
+		return "Note"
+	}
+
+	return "Unknown"
+}
+
+func isTwoB(beatMap *beatmap.BeatMap, number int64) bool {
+	if number == 0 {
+		return false
+	}
+
+	return beatMap.HitObjects[number].GetStartTime() < beatMap.HitObjects[number-1].GetEndTime()
+}
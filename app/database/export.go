@@ -0,0 +1,398 @@
+package database
+
+import (
+	"crypto/md5"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Fields restored for beatmaps which are already in the database
+var restoredFields = []string{"localOffset", "playCount", "lastPlayed"}
+
+type beatmapRecord map[string]any
+
+func getFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json", ".csv":
+		return ext[1:], nil
+	default:
+		return "", fmt.Errorf("unsupported format \"%s\", use .json or .csv", ext)
+	}
+}
+
+func getColumns() ([]string, error) {
+	res, err := dbFile.Query("SELECT * FROM beatmaps LIMIT 0")
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	return res.Columns()
+}
+
+// ExportBeatmaps saves all rows of beatmaps table to a .json or .csv file, so it can be shared with ImportBeatmaps
+func ExportBeatmaps(path string) error {
+	format, err := getFormat(path)
+	if err != nil {
+		return err
+	}
+
+	log.Println("DatabaseManager: Exporting beatmaps to:", path)
+
+	res, err := dbFile.Query("SELECT * FROM beatmaps")
+	if err != nil {
+		return err
+	}
+
+	defer res.Close()
+
+	columns, err := res.Columns()
+	if err != nil {
+		return err
+	}
+
+	records := make([]beatmapRecord, 0)
+
+	for res.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err = res.Scan(pointers...); err != nil {
+			return err
+		}
+
+		record := make(beatmapRecord)
+
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+
+			record[column] = values[i]
+		}
+
+		records = append(records, record)
+	}
+
+	if err = res.Err(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "\t")
+
+		err = encoder.Encode(records)
+	} else {
+		err = writeCSV(file, columns, records)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	log.Println("DatabaseManager: Exported", len(records), "beatmaps.")
+
+	return nil
+}
+
+func writeCSV(w io.Writer, columns []string, records []beatmapRecord) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+
+	for _, record := range records {
+		for i, column := range columns {
+			switch v := record[column].(type) {
+			case nil:
+				row[i] = ""
+			case float64:
+				row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				row[i] = fmt.Sprint(v)
+			}
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func readRecords(path string) ([]beatmapRecord, error) {
+	format, err := getFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var records []beatmapRecord
+
+	if format == "json" {
+		decoder := json.NewDecoder(file)
+		decoder.UseNumber() // Keep big integers like timestamps intact
+
+		err = decoder.Decode(&records)
+
+		// Let sqlite convert numbers using column types
+		for _, record := range records {
+			for k, v := range record {
+				if n, ok := v.(json.Number); ok {
+					record[k] = n.String()
+				}
+			}
+		}
+
+		return records, err
+	}
+
+	reader := csv.NewReader(file)
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("missing csv header")
+	}
+
+	for _, row := range rows[1:] {
+		record := make(beatmapRecord)
+
+		for i, column := range rows[0] {
+			if row[i] == "" { // Empty cells are missing values, the same as nulls in json
+				record[column] = nil
+			} else {
+				record[column] = row[i]
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ImportBeatmaps loads beatmaps exported by ExportBeatmaps.
+// Local offsets, play stats and star ratings are restored for beatmaps already in the database if .osu files are the same.
+// Beatmaps missing from the database are inserted if their .osu files exist in Songs directory and are the same,
+// so they don't have to be imported again.
+func ImportBeatmaps(path string) error {
+	log.Println("DatabaseManager: Importing beatmaps from:", path)
+
+	records, err := readRecords(path)
+	if err != nil {
+		return err
+	}
+
+	columns, err := getColumns()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		for k := range record {
+			if !containsFold(columns, k) {
+				return fmt.Errorf("unknown column \"%s\"", k)
+			}
+		}
+
+		for _, k := range []string{"dir", "file", "md5"} {
+			if _, ok := record[k].(string); !ok {
+				return fmt.Errorf("column \"%s\" is missing", k)
+			}
+		}
+	}
+
+	localMD5 := make(map[mapLocation]string)
+
+	res, err := dbFile.Query("SELECT dir, file, md5 FROM beatmaps")
+	if err != nil {
+		return err
+	}
+
+	for res.Next() {
+		var location mapLocation
+		var hash string
+
+		if err = res.Scan(&location.dir, &location.file, &hash); err != nil {
+			res.Close()
+			return err
+		}
+
+		localMD5[location] = hash
+	}
+
+	res.Close()
+
+	tx, err := dbFile.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	setters := make([]string, 0, len(restoredFields))
+	for _, field := range restoredFields {
+		setters = append(setters, fmt.Sprintf("%[1]s = COALESCE(?, %[1]s)", field))
+	}
+
+	restoreStmt, err := tx.Prepare(fmt.Sprintf("UPDATE beatmaps SET %s WHERE dir = ? AND file = ?", strings.Join(setters, ", ")))
+	if err != nil {
+		return err
+	}
+
+	defer restoreStmt.Close()
+
+	starsStmt, err := tx.Prepare("UPDATE beatmaps SET stars = ?, starsVersion = ? WHERE dir = ? AND file = ? AND (stars < 0 OR starsVersion <= ?)")
+	if err != nil {
+		return err
+	}
+
+	defer starsStmt.Close()
+
+	var restored, inserted, skipped int
+
+	for _, record := range records {
+		location := mapLocation{dir: record["dir"].(string), file: record["file"].(string)}
+		hash := record["md5"].(string)
+
+		if local, ok := localMD5[location]; ok {
+			if !strings.EqualFold(local, hash) {
+				skipped++
+				continue
+			}
+
+			values := make([]any, 0, len(restoredFields)+2)
+
+			for _, field := range restoredFields {
+				values = append(values, record[field])
+			}
+
+			if _, err = restoreStmt.Exec(append(values, location.dir, location.file)...); err != nil {
+				return err
+			}
+
+			if record["stars"] != nil && record["starsVersion"] != nil {
+				if _, err = starsStmt.Exec(record["stars"], record["starsVersion"], location.dir, location.file, record["starsVersion"]); err != nil {
+					return err
+				}
+			}
+
+			restored++
+
+			continue
+		}
+
+		lastModified, ok := checkLocalFile(location, hash)
+		if !ok {
+			skipped++
+			continue
+		}
+
+		// Modification time differs between machines, use the local one so the file is not imported again
+		record["lastModified"] = lastModified
+
+		keys := make([]string, 0, len(record))
+		values := make([]any, 0, len(record))
+
+		for k, v := range record {
+			if v == nil { // Missing values get column defaults
+				continue
+			}
+
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+
+		query := fmt.Sprintf("INSERT INTO beatmaps (%s) VALUES (?%s)", strings.Join(keys, ", "), strings.Repeat(", ?", len(keys)-1))
+
+		if _, err = tx.Exec(query, values...); err != nil {
+			return err
+		}
+
+		localMD5[location] = hash
+
+		inserted++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Println(fmt.Sprintf("DatabaseManager: Restored %d, inserted %d, skipped %d beatmaps.", restored, inserted, skipped))
+
+	return nil
+}
+
+// checkLocalFile checks whether .osu file exists in Songs directory and has the given md5, returns its modification time
+func checkLocalFile(location mapLocation, hash string) (int64, bool) {
+	file, err := os.Open(filepath.Join(songsDir, location.dir, location.file))
+	if err != nil {
+		return 0, false
+	}
+
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, false
+	}
+
+	md5Hash := md5.New()
+	if _, err = io.Copy(md5Hash, file); err != nil {
+		return 0, false
+	}
+
+	if !strings.EqualFold(hex.EncodeToString(md5Hash.Sum(nil)), hash) {
+		return 0, false
+	}
+
+	return stat.ModTime().UnixNano() / 1000000, true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
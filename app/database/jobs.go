@@ -0,0 +1,43 @@
+package database
+
+// RenderJob is a job submitted to render server, kept as history
+type RenderJob struct {
+	ID       int64
+	Status   string
+	Request  string // JSON encoded request
+	Created  int64  // Unix time in milliseconds
+	Started  int64
+	Finished int64
+	Output   string
+	Error    string
+}
+
+func SaveRenderJob(job RenderJob) error {
+	_, err := dbFile.Exec("REPLACE INTO renderJobs (id, status, request, created, started, finished, output, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		job.ID, job.Status, job.Request, job.Created, job.Started, job.Finished, job.Output, job.Error)
+
+	return err
+}
+
+func LoadRenderJobs() ([]RenderJob, error) {
+	res, err := dbFile.Query("SELECT id, status, request, created, started, finished, output, error FROM renderJobs ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	var jobs []RenderJob
+
+	for res.Next() {
+		var job RenderJob
+
+		if err = res.Scan(&job.ID, &job.Status, &job.Request, &job.Created, &job.Started, &job.Finished, &job.Output, &job.Error); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, res.Err()
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+)
+
+type M20221018 struct{}
+
+func (m *M20221018) RequiredSections() []string {
+	return nil
+}
+
+func (m *M20221018) FieldsToMigrate() []string {
+	return nil
+}
+
+func (m *M20221018) GetValues(_ *beatmap.BeatMap) []interface{} {
+	return nil
+}
+
+func (m *M20221018) Date() int {
+	return 20221018
+}
+
+func (m *M20221018) GetMigrationStmts() string {
+	return "CREATE TABLE IF NOT EXISTS renderJobs (id INTEGER PRIMARY KEY, status TEXT, request TEXT, created INTEGER, started INTEGER, finished INTEGER, output TEXT, error TEXT);"
+}
@@ -1,14 +1,12 @@
 package database
 
 import (
-	"crypto/md5"
 	"database/sql"
-	"encoding/hex"
 	"fmt"
 	"github.com/karrick/godirwalk"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/wieku/danser-go/app/beatmap"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/utils"
 	"github.com/wieku/danser-go/framework/env"
@@ -16,7 +14,6 @@ import (
 	"github.com/wieku/danser-go/framework/math/mutils"
 	"github.com/wieku/danser-go/framework/util"
 	"golang.org/x/exp/slices"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,7 +24,7 @@ import (
 
 var dbFile *sql.DB
 
-const databaseVersion = 20220622
+const databaseVersion = 20221018
 
 var currentPreVersion = databaseVersion
 var currentSchemaPreVersion = databaseVersion
@@ -37,7 +34,19 @@ type mapLocation struct {
 	file string
 }
 
-var migrations []Migration
+var migrations = []Migration{
+	&M20181111{},
+	&M20201027{},
+	&M20201112{},
+	&M20201117{},
+	&M20201118{},
+	&M20210104{},
+	&M20210326{},
+	&M20210423{},
+	&M20220605{},
+	&M20220622{},
+	&M20221018{},
+}
 
 var songsDir string
 
@@ -56,20 +65,8 @@ func Init() error {
 		return fmt.Errorf("%s does not exist", songsDir)
 	}
 
-	migrations = []Migration{
-		&M20181111{},
-		&M20201027{},
-		&M20201112{},
-		&M20201117{},
-		&M20201118{},
-		&M20210104{},
-		&M20210326{},
-		&M20210423{},
-		&M20220605{},
-		&M20220622{},
-	}
-
-	dbFile, err = sql.Open("sqlite3", filepath.Join(env.DataDir(), "danser.db"))
+	// Render server keeps the database open while danser processes it started import new beatmaps, so wait for locks instead of failing
+	dbFile, err = sql.Open("sqlite3", getDatabasePath()+"?_busy_timeout=5000")
 	if err != nil {
 		return err
 	}
@@ -78,65 +75,22 @@ func Init() error {
 		CREATE TABLE IF NOT EXISTS beatmaps (dir TEXT, file TEXT, lastModified INTEGER, title TEXT, titleUnicode TEXT, artist TEXT, artistUnicode TEXT, creator TEXT, version TEXT, source TEXT, tags TEXT, cs REAL, ar REAL, sliderMultiplier REAL, sliderTickRate REAL, audioFile TEXT, previewTime INTEGER, sampleSet INTEGER, stackLeniency REAL, mode INTEGER, bg TEXT, md5 TEXT, dateAdded INTEGER, playCount INTEGER, lastPlayed INTEGER, hpdrain REAL, od REAL, stars REAL DEFAULT -1, bpmMin REAL, bpmMax REAL, circles INTEGER, sliders INTEGER, spinners INTEGER, endTime INTEGER, setID INTEGER, mapID INTEGER, starsVersion INTEGER DEFAULT 0, localOffset INTEGER DEFAULT 0);
 		CREATE INDEX IF NOT EXISTS idx ON beatmaps (dir, file);
 		CREATE TABLE IF NOT EXISTS info (key TEXT NOT NULL UNIQUE, value TEXT);
+		CREATE TABLE IF NOT EXISTS renderJobs (id INTEGER PRIMARY KEY, status TEXT, request TEXT, created INTEGER, started INTEGER, finished INTEGER, output TEXT, error TEXT);
 	`)
 
 	if err != nil {
 		return err
 	}
 
-	schemaVersionExists := false
-
-	res, err := dbFile.Query("SELECT key, value FROM info")
+	currentPreVersion, currentSchemaPreVersion, err = readVersions()
 	if err != nil {
 		return err
 	}
 
-	for res.Next() {
-		var key, value string
-
-		err = res.Scan(&key, &value)
-		if err != nil {
-			return err
-		}
-
-		if key == "version" {
-			currentPreVersion, _ = strconv.Atoi(value)
-		}
-
-		if key == "schema_version" {
-			schemaVersionExists = true
-			currentSchemaPreVersion, _ = strconv.Atoi(value)
-		}
-	}
-
-	if !schemaVersionExists {
-		currentSchemaPreVersion = currentPreVersion
-	}
-
 	log.Println("DatabaseManager: Database schema version:", currentSchemaPreVersion)
 	log.Println("DatabaseManager: Database data version:", currentPreVersion)
 
-	if currentSchemaPreVersion != databaseVersion {
-		log.Println("DatabaseManager: Database schema is too old! Updating...")
-
-		statement := ""
-
-		for _, m := range migrations {
-			if currentPreVersion < m.Date() {
-				statement += m.GetMigrationStmts()
-			}
-		}
-
-		_, err = dbFile.Exec(statement)
-		if err != nil {
-			panic(err)
-		}
-
-		log.Println("DatabaseManager: Schema has been updated!")
-	}
-
-	_, err = dbFile.Exec("REPLACE INTO info (key, value) VALUES ('schema_version', ?)", strconv.FormatInt(databaseVersion, 10))
-	if err != nil {
+	if err = migrateSchema(); err != nil {
 		return err
 	}
 
@@ -152,6 +106,10 @@ func Init() error {
 	return nil
 }
 
+func getDatabasePath() string {
+	return filepath.Join(env.DataDir(), "danser.db")
+}
+
 func LoadBeatmaps(skipDatabaseCheck bool, importListener ImportListener) []*beatmap.BeatMap {
 	var unpackedMaps []string
 	if settings.General.UnpackOszFiles {
@@ -164,17 +122,18 @@ func LoadBeatmaps(skipDatabaseCheck bool, importListener ImportListener) []*beat
 
 	allMaps := loadBeatmapsFromDatabase()
 
-	stdMaps := make([]*beatmap.BeatMap, 0, len(allMaps)/2)
+	supportedMaps := make([]*beatmap.BeatMap, 0, len(allMaps)/2)
 
 	for _, b := range allMaps {
-		if b.Mode == 0 {
-			stdMaps = append(stdMaps, b)
+		// osu!mania maps can be watched with replays
+		if b.Mode == beatmap.ModeStandard || b.Mode == beatmap.ModeMania {
+			supportedMaps = append(supportedMaps, b)
 		}
 	}
 
-	log.Println("DatabaseManager: Loaded", len(stdMaps), "total.")
+	log.Println("DatabaseManager: Loaded", len(supportedMaps), "total.")
 
-	return stdMaps
+	return supportedMaps
 }
 
 func unpackMaps() (dirs []string) {
@@ -362,26 +321,21 @@ func importMaps(skipDatabaseCheck bool, mustCheckDirs []string, importListener I
 				log.Println("DatabaseManager: Importing:", partialPath)
 			}
 
-			if bMap := beatmap.ParseBeatMapFile(file); bMap != nil {
-				stat, _ := file.Stat()
-				bMap.LastModified = stat.ModTime().UnixNano() / 1000000
-				bMap.TimeAdded = time.Now().UnixNano() / 1000000
-
-				hash := md5.New()
-				if _, err := io.Copy(hash, file); err == nil {
-					bMap.MD5 = hex.EncodeToString(hash.Sum(nil))
-				}
+			bMap, err := beatmap.ParseBeatMapFile(file)
+			if err != nil {
+				log.Println("DatabaseManager: Failed to import:", partialPath, "Error:", err)
+				return nil
+			}
 
-				if settings.General.VerboseImportLogs {
-					log.Println("DatabaseManager: Imported:", partialPath)
-				}
+			stat, _ := file.Stat()
+			bMap.LastModified = stat.ModTime().UnixNano() / 1000000
+			bMap.TimeAdded = time.Now().UnixNano() / 1000000
 
-				return bMap
-			} else {
-				log.Println("DatabaseManager: Failed to import:", partialPath)
+			if settings.General.VerboseImportLogs {
+				log.Println("DatabaseManager: Imported:", partialPath)
 			}
 
-			return nil
+			return bMap
 		})
 
 		close(receive)
@@ -425,10 +379,12 @@ func trySendStatus(listener ImportListener, stage ImportStage, progress, target
 func UpdateStarRating(maps []*beatmap.BeatMap, progressListener func(processed, target int)) {
 	const workers = 1 // For now using only one thread because calculating 4 aspire maps at once can OOM since (de)allocation can't keep up with many complex sliders
 
+	calculator := performance.GetCurrent()
+
 	var toCalculate []*beatmap.BeatMap
 
 	for _, b := range maps {
-		if b.Mode == 0 && (b.Stars < 0 || b.StarsVersion < pp220930.CurrentVersion) {
+		if b.Mode == 0 && (b.Stars < 0 || b.StarsVersion != calculator.Version()) { // Recalculate if different pp version was selected
 			toCalculate = append(toCalculate, b)
 		}
 	}
@@ -448,7 +404,7 @@ func UpdateStarRating(maps []*beatmap.BeatMap, progressListener func(processed,
 			ret = bMap // HACK: still return the beatmap even if execution panics: https://golangbyexample.com/return-value-function-panic-recover-go/
 
 			defer func() {
-				bMap.StarsVersion = pp220930.CurrentVersion
+				bMap.StarsVersion = calculator.Version()
 				bMap.Clear() //Clear objects and timing to avoid OOM
 
 				if err := recover(); err != nil { //TODO: Technically should be fixed but unexpected parsing problem won't crash whole process
@@ -464,7 +420,7 @@ func UpdateStarRating(maps []*beatmap.BeatMap, progressListener func(processed,
 				log.Println("DatabaseManager:", bMap.Dir+"/"+bMap.File, "doesn't have enough hitobjects")
 				bMap.Stars = 0
 			} else {
-				attr := pp220930.CalculateSingle(bMap.HitObjects, bMap.Diff)
+				attr := calculator.CalculateSingle(bMap.HitObjects, bMap.Diff)
 				bMap.Stars = attr.Total
 			}
 
@@ -608,9 +564,10 @@ func migrateBeatmaps() {
 					continue
 				}
 
-				bMap := beatmap.ParseBeatMapFile(file)
-				if bMap == nil {
+				bMap, err := beatmap.ParseBeatMapFile(file)
+				if err != nil {
 					log.Println("Corrupted cached beatmap found. Removing from database:", location.file)
+					log.Println("Error:", err)
 
 					removeList = append(removeList, location)
 
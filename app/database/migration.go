@@ -1,7 +1,15 @@
 package database
 
 import (
+	"database/sql"
+	"fmt"
 	"github.com/wieku/danser-go/app/beatmap"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Migration interface {
@@ -20,3 +28,191 @@ type Migration interface {
 	// Returns database version on which changes were made
 	Date() int
 }
+
+// readVersions reads data and schema versions from info table. Database is assumed to be up-to-date if they are missing.
+func readVersions() (dataVersion, schemaVersion int, err error) {
+	dataVersion = databaseVersion
+	schemaVersion = -1
+
+	res, err := dbFile.Query("SELECT key, value FROM info")
+	if err != nil {
+		return
+	}
+
+	defer res.Close()
+
+	for res.Next() {
+		var key, value string
+
+		if err = res.Scan(&key, &value); err != nil {
+			return
+		}
+
+		if key == "version" {
+			dataVersion, _ = strconv.Atoi(value)
+		}
+
+		if key == "schema_version" {
+			schemaVersion, _ = strconv.Atoi(value)
+		}
+	}
+
+	if schemaVersion == -1 {
+		schemaVersion = dataVersion
+	}
+
+	return dataVersion, schemaVersion, res.Err()
+}
+
+func getBackupPath(version int) string {
+	return fmt.Sprintf("%s.%d.bak", getDatabasePath(), version)
+}
+
+// migrateSchema applies each pending migration in its own transaction, so a failed migration doesn't leave the database half-migrated.
+// Database is backed up before any change is made.
+func migrateSchema() error {
+	if currentSchemaPreVersion == databaseVersion {
+		return nil
+	}
+
+	log.Println("DatabaseManager: Database schema is too old! Updating...")
+
+	backupPath := getBackupPath(currentSchemaPreVersion)
+
+	log.Println("DatabaseManager: Backing up database to:", backupPath)
+
+	_ = os.Remove(backupPath)
+
+	if _, err := dbFile.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	for _, m := range migrations {
+		if currentSchemaPreVersion >= m.Date() {
+			continue
+		}
+
+		log.Println("DatabaseManager: Applying", m.Date(), "schema migration...")
+
+		if err := applyMigration(m); err != nil {
+			return fmt.Errorf("schema migration %d failed, database stays at version %d (backup: %s): %w", m.Date(), currentSchemaPreVersion, backupPath, err)
+		}
+
+		currentSchemaPreVersion = m.Date()
+	}
+
+	if _, err := dbFile.Exec("REPLACE INTO info (key, value) VALUES ('schema_version', ?)", strconv.FormatInt(databaseVersion, 10)); err != nil {
+		return err
+	}
+
+	currentSchemaPreVersion = databaseVersion
+
+	// Can't be done inside a transaction, it only reclaims space so failure is not fatal
+	if _, err := dbFile.Exec("VACUUM"); err != nil {
+		log.Println("DatabaseManager: Failed to vacuum the database:", err)
+	}
+
+	log.Println("DatabaseManager: Schema has been updated!")
+
+	return nil
+}
+
+func applyMigration(m Migration) error {
+	tx, err := dbFile.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if stmts := m.GetMigrationStmts(); stmts != "" {
+		if _, err = tx.Exec(stmts); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec("REPLACE INTO info (key, value) VALUES (?, ?)", fmt.Sprintf("migration_%d", m.Date()), time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("REPLACE INTO info (key, value) VALUES ('schema_version', ?)", strconv.Itoa(m.Date())); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DryRunMigrations prints migrations that would be applied to the database without changing it
+func DryRunMigrations() error {
+	path := getDatabasePath()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Println("Database doesn't exist, it will be created with version", databaseVersion)
+		return nil
+	}
+
+	var err error
+
+	dbFile, err = sql.Open("sqlite3", "file:"+filepath.ToSlash(path)+"?mode=ro")
+	if err != nil {
+		return err
+	}
+
+	defer Close()
+
+	dataVersion, schemaVersion, err := readVersions()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Database:", path)
+	fmt.Println("Schema version:", schemaVersion)
+	fmt.Println("Data version:", dataVersion)
+	fmt.Println("Target version:", databaseVersion)
+
+	if schemaVersion == databaseVersion && dataVersion == databaseVersion {
+		fmt.Println("Database is up-to-date, nothing to do.")
+		return nil
+	}
+
+	if schemaVersion != databaseVersion {
+		fmt.Println("Database would be backed up to:", getBackupPath(schemaVersion))
+	}
+
+	var count int
+	if err = dbFile.QueryRow("SELECT COUNT(*) FROM beatmaps").Scan(&count); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		stmts := strings.TrimSpace(m.GetMigrationStmts())
+		fields := m.FieldsToMigrate()
+
+		schemaPending := schemaVersion < m.Date()
+		dataPending := dataVersion < m.Date() && fields != nil
+
+		if !schemaPending && !dataPending {
+			continue
+		}
+
+		fmt.Println(fmt.Sprintf("Migration %d:", m.Date()))
+
+		if schemaPending && stmts != "" {
+			for _, stmt := range strings.Split(stmts, ";") {
+				if stmt = strings.TrimSpace(stmt); stmt != "" {
+					fmt.Println("\tSchema:", stmt+";")
+				}
+			}
+		}
+
+		if dataPending {
+			fmt.Println(fmt.Sprintf("\tData: %d cached beatmaps would be parsed again to update: %s", count, strings.Join(fields, ", ")))
+		}
+
+		if schemaPending {
+			fmt.Println(fmt.Sprintf("\tInfo: migration_%d would be recorded", m.Date()))
+		}
+	}
+
+	return nil
+}
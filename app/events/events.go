@@ -0,0 +1,194 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeStage    = "stage"
+	TypeProgress = "progress"
+	TypeOutput   = "output"
+	TypeWarning  = "warning"
+	TypeError    = "error"
+	TypeCommand  = "command"
+)
+
+// Stages of danser's run
+const (
+	StageLoading    = "loading"
+	StagePlaying    = "playing"
+	StageRecording  = "recording"
+	StageFinalizing = "finalizing"
+	StageComposing  = "composing"
+	StageFinished   = "finished"
+)
+
+// Output kinds
+const (
+	OutputVideo      = "video"
+	OutputSequence   = "sequence" // Directory with frames and audio
+	OutputScreenshot = "screenshot"
+)
+
+// Commands sent to the launcher
+const (
+	CommandOpenSettings = "open-settings"
+)
+
+// Event is a single line of the event stream. Fields not used by event's type are omitted.
+type Event struct {
+	Type string
+	Time int64 // Unix time in milliseconds
+
+	Stage string `json:",omitempty"`
+
+	Progress float64 `json:",omitempty"` // Percent
+	Speed    float64 `json:",omitempty"` // Rendering speed compared to real time
+	FPS      float64 `json:",omitempty"`
+	ETA      float64 `json:",omitempty"` // Seconds
+
+	Kind string `json:",omitempty"`
+	Path string `json:",omitempty"`
+
+	Message string   `json:",omitempty"`
+	Context []string `json:",omitempty"`
+}
+
+var mutex sync.Mutex
+
+var writer io.Writer
+var closer io.Closer
+
+var listener func(event Event)
+
+// Open starts the event stream. Target can be stdout, stderr, fd:N (an already opened file descriptor) or a path to a file or named pipe.
+func Open(target string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	switch {
+	case target == "stdout":
+		writer = os.Stdout
+	case target == "stderr":
+		writer = os.Stderr
+	case strings.HasPrefix(target, "fd:"):
+		fd, err := strconv.Atoi(strings.TrimPrefix(target, "fd:"))
+		if err != nil || fd < 0 {
+			return fmt.Errorf("invalid file descriptor \"%s\"", target)
+		}
+
+		file := os.NewFile(uintptr(fd), target)
+		if file == nil {
+			return fmt.Errorf("invalid file descriptor \"%s\"", target)
+		}
+
+		writer, closer = file, file
+	default:
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+
+		writer, closer = file, file
+	}
+
+	return nil
+}
+
+// Close closes the stream if it was opened by Open
+func Close() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if closer != nil {
+		_ = closer.Close()
+	}
+
+	writer, closer = nil, nil
+}
+
+// IsOpen checks whether events are emitted
+func IsOpen() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return writer != nil
+}
+
+// SetListener sets a function called with every emitted event, even if the stream is not open. Nil removes the listener.
+func SetListener(l func(event Event)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	listener = l
+}
+
+// Emit writes the event as a single JSON line, it's a no-op if the stream is not open and there's no listener
+func Emit(event Event) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	event.Time = time.Now().UnixMilli()
+
+	if listener != nil {
+		listener(event)
+	}
+
+	if writer == nil {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	_, _ = writer.Write(append(data, '\n'))
+}
+
+func EmitStage(stage string) {
+	Emit(Event{Type: TypeStage, Stage: stage})
+}
+
+func EmitProgress(progress, speed, fps, eta float64) {
+	Emit(Event{Type: TypeProgress, Progress: progress, Speed: speed, FPS: fps, ETA: eta})
+}
+
+func EmitOutput(kind, path string) {
+	Emit(Event{Type: TypeOutput, Kind: kind, Path: path})
+}
+
+func EmitWarning(message string) {
+	Emit(Event{Type: TypeWarning, Message: message})
+}
+
+func EmitError(message string, context []string) {
+	Emit(Event{Type: TypeError, Message: message, Context: context})
+}
+
+func EmitCommand(command string) {
+	Emit(Event{Type: TypeCommand, Message: command})
+}
+
+// Parse parses a line of the event stream, false is returned if line is not an event
+func Parse(line string) (event Event, ok bool) {
+	line = strings.TrimSpace(line)
+
+	if !strings.HasPrefix(line, "{") {
+		return event, false
+	}
+
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type == "" {
+		return event, false
+	}
+
+	return event, true
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// exportBeatmap saves the beatmap as .osu file with mods, speed and difficulty overrides applied
+func exportBeatmap(beatMap *beatmap.BeatMap, path string, mods difficulty.Modifier, speed, ar, od, cs, hp float64) {
+	log.Println("Exporting beatmap:", beatMap.Artist, "-", beatMap.Name, "["+beatMap.Difficulty+"]")
+
+	file, err := os.Open(beatMap.GetPath())
+	if err != nil {
+		panic(fmt.Sprintf("Failed to open beatmap: %s", err))
+	}
+
+	// Fresh copy so the map from the database is not affected
+	exported := beatmap.NewBeatMap()
+
+	err = beatmap.ParseFromReader(file, exported, beatmap.ParseOptions{DiffCalcOnly: true})
+
+	file.Close()
+
+	if err != nil {
+		panic(fmt.Sprintf("Failed to parse beatmap: %s", err))
+	}
+
+	if !math.IsNaN(ar) {
+		exported.Diff.SetARCustom(ar)
+	}
+
+	if !math.IsNaN(od) {
+		exported.Diff.SetODCustom(od)
+	}
+
+	if !math.IsNaN(cs) {
+		exported.Diff.SetCSCustom(cs)
+	}
+
+	if !math.IsNaN(hp) {
+		exported.Diff.SetHPCustom(hp)
+	}
+
+	exported.Diff.SetCustomSpeed(speed)
+	exported.Diff.SetMods(mods)
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			panic(fmt.Sprintf("Failed to create directory: %s", err))
+		}
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create file: %s", err))
+	}
+
+	defer out.Close()
+
+	if err = beatmap.Encode(out, exported, true); err != nil {
+		panic(fmt.Sprintf("Failed to export beatmap: %s", err))
+	}
+
+	log.Println("Beatmap exported to:", path)
+}
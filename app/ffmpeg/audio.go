@@ -67,7 +67,7 @@ func startAudio(audioFPS float64) {
 		options = append(options, encOptions...)
 	}
 
-	options = append(options, filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container))
+	options = append(options, filepath.Join(getTempDir(), "audio."+settings.Recording.GetContainer()))
 
 	log.Println("Running ffmpeg with options:", options)
 
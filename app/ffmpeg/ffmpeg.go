@@ -2,12 +2,14 @@ package ffmpeg
 
 import (
 	"fmt"
+	"github.com/wieku/danser-go/app/events"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/files"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,8 +18,11 @@ var ffmpegExec string
 
 var output string
 
-// check used encoders exist
-func preCheck() {
+// SkipEncoderCheck skips checking encoders in PreCheck, used when they were already checked by the parent process
+var SkipEncoderCheck bool
+
+// PreCheck locates ffmpeg and checks that used encoders exist
+func PreCheck() {
 	var err error
 
 	ffmpegExec, err = files.GetCommandExec("ffmpeg", "ffmpeg")
@@ -27,6 +32,10 @@ func preCheck() {
 
 	log.Println("FFmpeg exec location:", ffmpegExec)
 
+	if SkipEncoderCheck {
+		return
+	}
+
 	out, err := exec.Command(ffmpegExec, "-encoders").Output()
 	if err != nil {
 		if strings.Contains(err.Error(), "127") || strings.Contains(strings.ToLower(err.Error()), "0xc0000135") {
@@ -44,7 +53,7 @@ func preCheck() {
 		}
 	}
 
-	vcodec := settings.Recording.Encoder
+	vcodec := getVideoEncoder()
 	acodec := settings.Recording.AudioCodec
 	vfound := false
 	afound := false
@@ -71,10 +80,31 @@ func preCheck() {
 	if !afound {
 		panic(fmt.Sprintf("Audio codec %q does not exist", acodec))
 	}
+
+	if settings.Recording.OutputType == "exr" && !hasFilter("zscale") {
+		panic("ffmpeg's zscale filter is needed to convert EXR frames to linear light. Please use ffmpeg built with libzimg")
+	}
+}
+
+// hasFilter checks whether ffmpeg has the video filter with given name
+func hasFilter(name string) bool {
+	out, err := exec.Command(ffmpegExec, "-hide_banner", "-filters").Output()
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[1] == name {
+			return true
+		}
+	}
+
+	return false
 }
 
-func StartFFmpeg(fps, _w, _h int, audioFPS float64, _output string) {
-	preCheck()
+// StartFFmpeg starts encoding. Key identifies parameters of the recording, unfinished recording with the same output name and key is resumed.
+func StartFFmpeg(fps, _w, _h int, audioFPS float64, _output, key string) {
+	PreCheck()
 
 	if strings.TrimSpace(_output) == "" {
 		_output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
@@ -82,15 +112,19 @@ func StartFFmpeg(fps, _w, _h int, audioFPS float64, _output string) {
 
 	output = _output
 
+	markers = nil
+
 	log.Println("Starting encoding!")
 
-	_ = os.RemoveAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"))
+	events.EmitStage(events.StageRecording)
 
-	err := os.MkdirAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"), 0755)
-	if err != nil && !os.IsExist(err) {
-		panic(err)
+	outputFPS := fps
+	if settings.Recording.MotionBlur.Enabled {
+		outputFPS /= settings.Recording.MotionBlur.OversampleMultiplier
 	}
 
+	prepareTempDir(key, outputFPS)
+
 	startVideo(fps, _w, _h)
 	startAudio(audioFPS)
 }
@@ -98,6 +132,8 @@ func StartFFmpeg(fps, _w, _h int, audioFPS float64, _output string) {
 func StopFFmpeg() {
 	log.Println("Finishing rendering...")
 
+	events.EmitStage(events.StageFinalizing)
+
 	stopVideo()
 	stopAudio()
 
@@ -107,23 +143,61 @@ func StopFFmpeg() {
 }
 
 func combine() {
+	if settings.Recording.IsImageSequence() {
+		moveSequence()
+		return
+	}
+
+	chaptersPath, subtitlesPath := writeMarkers()
+
 	options := []string{
 		"-y",
-		"-i", filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container),
-		"-i", filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container),
+		"-f", "concat",
+		"-safe", "0",
+		"-i", currentManifest.writeConcatList(),
+		"-i", filepath.Join(getTempDir(), "audio."+settings.Recording.GetContainer()),
+	}
+
+	mappings := []string{
+		"-map", "0:v",
+		"-map", "1:a",
 		"-c:v", "copy",
 		"-c:a", "copy", "-strict", "-2",
 	}
 
-	if settings.Recording.Container == "mp4" {
+	inputs := 2
+
+	if chaptersPath != "" {
+		options = append(options, "-i", chaptersPath)
+		mappings = append(mappings, "-map_chapters", strconv.Itoa(inputs))
+
+		inputs++
+	}
+
+	if subtitlesPath != "" {
+		subtitleCodec := "srt"
+		if settings.Recording.GetContainer() == "mp4" {
+			subtitleCodec = "mov_text"
+		}
+
+		options = append(options, "-i", subtitlesPath)
+		mappings = append(mappings, "-map", strconv.Itoa(inputs)+":s", "-c:s", subtitleCodec)
+	}
+
+	options = append(options, mappings...)
+
+	if settings.Recording.GetContainer() == "mp4" {
 		options = append(options, "-movflags", "+faststart")
 	}
 
-	finalOutputPath := filepath.Join(settings.Recording.GetOutputDir(), output+"."+settings.Recording.Container)
+	finalOutputPath := filepath.Join(settings.Recording.GetOutputDir(), output+"."+settings.Recording.GetContainer())
 
 	options = append(options, finalOutputPath)
 
 	log.Println("Starting composing audio and video into one file...")
+
+	events.EmitStage(events.StageComposing)
+
 	log.Println("Running ffmpeg with options:", options)
 	cmd2 := exec.Command(ffmpegExec, options...)
 
@@ -134,22 +208,58 @@ func combine() {
 
 	if err := cmd2.Start(); err != nil {
 		log.Println("Failed to start ffmpeg:", err)
+		events.EmitWarning("Failed to start ffmpeg: " + err.Error())
 	} else {
 		if err = cmd2.Wait(); err != nil {
 			panic(fmt.Sprintf("ffmpeg finished abruptly! Please check if you have enough storage. Error: %s", err))
 		} else {
 			log.Println("Finished!")
 			log.Println("Video is available at:", finalOutputPath)
+
+			events.EmitOutput(events.OutputVideo, finalOutputPath)
+		}
+	}
+
+	cleanup()
+}
+
+// moveSequence moves frames, audio, chapters and subtitles from temp directory to the final one
+func moveSequence() {
+	finalOutputPath := filepath.Join(settings.Recording.GetOutputDir(), output)
+
+	log.Println("Moving image sequence to:", finalOutputPath)
+
+	_ = os.RemoveAll(finalOutputPath)
+
+	if err := os.Rename(filepath.Join(getTempDir(), "frames"), finalOutputPath); err != nil {
+		panic(fmt.Sprintf("Failed to move image sequence: %s", err))
+	}
+
+	chaptersPath, subtitlesPath := writeMarkers()
+
+	for _, path := range []string{filepath.Join(getTempDir(), "audio."+settings.Recording.GetContainer()), chaptersPath, subtitlesPath} {
+		if path == "" {
+			continue
+		}
+
+		if err := os.Rename(path, filepath.Join(finalOutputPath, filepath.Base(path))); err != nil {
+			log.Println("Failed to move file:", err)
+			events.EmitWarning("Failed to move file: " + err.Error())
 		}
 	}
 
+	log.Println("Finished!")
+	log.Println("Image sequence is available at:", finalOutputPath)
+
+	events.EmitOutput(events.OutputSequence, finalOutputPath)
+
 	cleanup()
 }
 
 func cleanup() {
 	log.Println("Cleaning up intermediate files...")
 
-	_ = os.RemoveAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"))
+	_ = os.RemoveAll(getTempDir())
 
 	log.Println("Finished.")
 }
@@ -0,0 +1,87 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/util/pixconv"
+	"strconv"
+	"strings"
+)
+
+// getVideoEncoder returns ffmpeg's encoder used by selected output type
+func getVideoEncoder() string {
+	switch settings.Recording.OutputType {
+	case "lossless":
+		return "ffv1"
+	case "png", "exr":
+		return settings.Recording.OutputType
+	default:
+		return strings.ToLower(settings.Recording.Encoder)
+	}
+}
+
+// getIntermediateOptions returns ffmpeg's options for lossless intermediate and image sequences.
+// Frames are read as RGB(A) and converted only by ffmpeg, encoder settings are ignored.
+func getIntermediateOptions(fps int) []string {
+	parsedFormat = pixconv.ARGB
+
+	inputPixFmt := "rgb24"
+	if readAlpha {
+		inputPixFmt = "rgba"
+	}
+
+	videoFilters := "vflip"
+
+	if filters := strings.TrimSpace(settings.Recording.Filters); len(filters) > 0 {
+		videoFilters += "," + filters
+	}
+
+	// danser draws with premultiplied alpha, PNG and most editors expect straight alpha
+	if readAlpha {
+		videoFilters += ",format=gbrap,unpremultiply=inplace=1"
+	}
+
+	// EXR stores linear light with premultiplied alpha, so sRGB colors are linearized before alpha is applied again
+	if settings.Recording.OutputType == "exr" {
+		videoFilters += ",format=" + pickFormat("gbrapf32le", "gbrpf32le") + ",zscale=transferin=iec61966-2-1:transfer=linear"
+
+		if readAlpha {
+			videoFilters += ",premultiply=inplace=1"
+		}
+	}
+
+	options := []string{
+		"-y",
+
+		"-f", "rawvideo",
+		"-vcodec", "rawvideo",
+		"-s", fmt.Sprintf("%dx%d", w, h),
+		"-pix_fmt", inputPixFmt,
+		"-r", strconv.Itoa(fps),
+		"-i", "-",
+
+		"-an",
+
+		"-vf", videoFilters,
+		"-c:v", getVideoEncoder(),
+	}
+
+	switch settings.Recording.OutputType {
+	case "lossless":
+		options = append(options, "-level", "3", "-slicecrc", "1", "-pix_fmt", pickFormat("bgra", "bgr0"))
+	case "png":
+		options = append(options, "-pix_fmt", pickFormat("rgba", "rgb24"))
+	case "exr":
+		options = append(options, "-pix_fmt", pickFormat("gbrapf32le", "gbrpf32le"))
+	}
+
+	return options
+}
+
+func pickFormat(alpha, opaque string) string {
+	if readAlpha {
+		return alpha
+	}
+
+	return opaque
+}
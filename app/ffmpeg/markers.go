@@ -0,0 +1,133 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	chaptersName  = "chapters.txt"
+	subtitlesName = "subtitles.srt"
+)
+
+type marker struct {
+	time     float64 // in seconds
+	title    string
+	subtitle bool
+}
+
+var markers []marker
+
+// AddMarker adds a chapter at the time of the next frame. If showSubtitle is true, title is also shown in the subtitle track.
+func AddMarker(title string, showSubtitle bool) {
+	markers = append(markers, marker{
+		time:     getVideoTime(),
+		title:    title,
+		subtitle: showSubtitle,
+	})
+}
+
+// getVideoTime returns the time of the next frame in seconds
+func getVideoTime() float64 {
+	frames := float64(frameNumber + 1)
+
+	if settings.Recording.MotionBlur.Enabled {
+		frames /= float64(settings.Recording.MotionBlur.OversampleMultiplier)
+	}
+
+	return frames / float64(currentManifest.FPS)
+}
+
+// writeMarkers saves chapters and subtitles to the temp directory, empty paths are returned if they are disabled
+func writeMarkers() (chaptersPath, subtitlesPath string) {
+	if len(markers) == 0 {
+		return
+	}
+
+	duration := getVideoTime()
+
+	if settings.Recording.Markers.Chapters {
+		chaptersPath = filepath.Join(getTempDir(), chaptersName)
+
+		if err := os.WriteFile(chaptersPath, []byte(formatChapters(duration)), 0644); err != nil {
+			log.Println("Failed to save chapters:", err)
+			chaptersPath = ""
+		}
+	}
+
+	if settings.Recording.Markers.Subtitles {
+		subtitlesPath = filepath.Join(getTempDir(), subtitlesName)
+
+		if err := os.WriteFile(subtitlesPath, []byte(formatSubtitles(duration)), 0644); err != nil {
+			log.Println("Failed to save subtitles:", err)
+			subtitlesPath = ""
+		}
+	}
+
+	return
+}
+
+// formatChapters creates FFmpeg's metadata file, each chapter lasts until the next one
+func formatChapters(duration float64) string {
+	var sb strings.Builder
+
+	sb.WriteString(";FFMETADATA1\n")
+
+	chapters := markers
+	if markers[0].time > 0 {
+		chapters = append([]marker{{title: "Start"}}, markers...)
+	}
+
+	for i, c := range chapters {
+		end := duration
+		if i < len(chapters)-1 {
+			end = chapters[i+1].time
+		}
+
+		sb.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+		sb.WriteString(fmt.Sprintf("START=%d\n", int64(math.Round(c.time*1000))))
+		sb.WriteString(fmt.Sprintf("END=%d\n", int64(math.Round(end*1000))))
+		sb.WriteString(fmt.Sprintf("title=%s\n", escapeMetadata(c.title)))
+	}
+
+	return sb.String()
+}
+
+// formatSubtitles creates a SubRip file, a subtitle is hidden earlier if the next one appears
+func formatSubtitles(duration float64) string {
+	var sb strings.Builder
+
+	subtitles := make([]marker, 0, len(markers))
+
+	for _, m := range markers {
+		if m.subtitle {
+			subtitles = append(subtitles, m)
+		}
+	}
+
+	for i, s := range subtitles {
+		end := math.Min(s.time+settings.Recording.Markers.SubtitleDuration, duration)
+		if i < len(subtitles)-1 {
+			end = math.Min(end, subtitles[i+1].time)
+		}
+
+		sb.WriteString(fmt.Sprintf("%d\n%s --> %s\n%s\n\n", i+1, formatSRTTime(s.time), formatSRTTime(end), s.title))
+	}
+
+	return sb.String()
+}
+
+func formatSRTTime(time float64) string {
+	millis := int64(math.Round(time * 1000))
+
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
+
+func escapeMetadata(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "=", "\\=", ";", "\\;", "#", "\\#", "\n", "\\\n").Replace(value)
+}
@@ -0,0 +1,153 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const manifestName = "manifest.json"
+
+type segment struct {
+	Index  int
+	Frames int
+	File   string
+}
+
+// manifest keeps track of finished video segments, so a recording with the same key can be resumed after a crash
+type manifest struct {
+	Key           string
+	FPS           int
+	SegmentFrames int // 0 means that video is not split
+	Segments      []segment
+}
+
+var currentManifest *manifest
+
+// skipFrames is the number of frames already encoded in finished segments
+var skipFrames int64
+
+func getTempDir() string {
+	return filepath.Join(settings.Recording.GetOutputDir(), output+"_temp")
+}
+
+// getSegmentName returns the name of segment's file, for image sequences it's segment's first frame
+func getSegmentName(index int) string {
+	if settings.Recording.IsImageSequence() {
+		return filepath.Join("frames", fmt.Sprintf("frame_%06d.%s", index*currentManifest.SegmentFrames, settings.Recording.OutputType))
+	}
+
+	return fmt.Sprintf("video_%05d.%s", index, settings.Recording.GetContainer())
+}
+
+// prepareTempDir loads the manifest of a previous recording with the same key and checks its segments.
+// Temp directory is cleared if there's nothing to resume.
+func prepareTempDir(key string, fps int) {
+	segmentFrames := settings.Recording.SegmentLength * fps
+
+	currentManifest = loadManifest(key, fps, segmentFrames)
+
+	if currentManifest == nil {
+		currentManifest = &manifest{
+			Key:           key,
+			FPS:           fps,
+			SegmentFrames: segmentFrames,
+		}
+
+		_ = os.RemoveAll(getTempDir())
+	}
+
+	err := os.MkdirAll(getTempDir(), 0755)
+	if err != nil && !os.IsExist(err) {
+		panic(err)
+	}
+
+	skipFrames = 0
+
+	for _, s := range currentManifest.Segments {
+		skipFrames += int64(s.Frames)
+	}
+
+	if len(currentManifest.Segments) > 0 {
+		log.Println(fmt.Sprintf("Resuming recording after %d finished segments, skipping %d frames", len(currentManifest.Segments), skipFrames))
+	}
+}
+
+func loadManifest(key string, fps, segmentFrames int) *manifest {
+	data, err := os.ReadFile(filepath.Join(getTempDir(), manifestName))
+	if err != nil {
+		return nil
+	}
+
+	m := new(manifest)
+
+	if err = json.Unmarshal(data, m); err != nil {
+		log.Println("Failed to read recording manifest:", err)
+		return nil
+	}
+
+	if m.Key != key || m.FPS != fps || m.SegmentFrames != segmentFrames || segmentFrames == 0 {
+		log.Println("Found unfinished recording with different parameters, starting from the beginning")
+		return nil
+	}
+
+	for i, s := range m.Segments {
+		stat, err := os.Stat(filepath.Join(getTempDir(), s.File))
+
+		// Only full segments are saved to the manifest
+		if s.Index != i || s.Frames != segmentFrames || err != nil || stat.Size() == 0 {
+			log.Println(fmt.Sprintf("Segment %d of unfinished recording is invalid, resuming from the previous one", i))
+
+			m.Segments = m.Segments[:i]
+
+			break
+		}
+	}
+
+	return m
+}
+
+func (m *manifest) addSegment(index, frames int) {
+	m.Segments = append(m.Segments, segment{
+		Index:  index,
+		Frames: frames,
+		File:   getSegmentName(index),
+	})
+
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+
+	path := filepath.Join(getTempDir(), manifestName)
+
+	// Write to a temporary file first, so a crash while saving doesn't corrupt the manifest
+	if err = os.WriteFile(path+".tmp", data, 0644); err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+
+	if err != nil {
+		log.Println("Failed to save recording manifest:", err)
+	}
+}
+
+// writeConcatList writes a list of segments for ffmpeg's concat demuxer
+func (m *manifest) writeConcatList() string {
+	var sb strings.Builder
+
+	for _, s := range m.Segments {
+		sb.WriteString(fmt.Sprintf("file '%s'\n", s.File))
+	}
+
+	path := filepath.Join(getTempDir(), "segments.txt")
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		panic(err)
+	}
+
+	return path
+}
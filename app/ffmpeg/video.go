@@ -12,6 +12,7 @@ import (
 	"github.com/wieku/danser-go/framework/graphics/effects"
 	"github.com/wieku/danser-go/framework/graphics/texture"
 	"github.com/wieku/danser-go/framework/util/pixconv"
+	"golang.org/x/exp/slices"
 	"io"
 	"log"
 	"os"
@@ -30,10 +31,16 @@ var cmdVideo *exec.Cmd
 
 var videoPipe io.WriteCloser
 
+var videoOptions []string
+
+var segmentIndex int
+var segmentFrames int
+
 var videoWriteQueue chan *PBO
 var endSyncVideo *sync.WaitGroup
 
 var videoError string
+var videoLogPipe *os.File
 var videoErrorWait *sync.WaitGroup
 
 var freePBOPool chan *PBO
@@ -48,6 +55,9 @@ var limiter *frame.Limiter
 
 var parsedFormat pixconv.PixFmt
 
+// readAlpha is true if frames are read as RGBA
+var readAlpha bool
+
 type PBO struct {
 	handle     uint32
 	memPointer unsafe.Pointer
@@ -67,6 +77,10 @@ func createPBO(format pixconv.PixFmt) *PBO {
 
 	glSize := w * h * 3
 
+	if readAlpha {
+		glSize = w * h * 4
+	}
+
 	if pbo.convFormat == pixconv.I420 || pbo.convFormat == pixconv.NV12 || pbo.convFormat == pixconv.NV21 {
 		glSize = w * h * 3 / 2
 
@@ -99,6 +113,74 @@ func startVideo(fps, _w, _h int) {
 		fps /= settings.Recording.MotionBlur.OversampleMultiplier
 	}
 
+	readAlpha = settings.Recording.HasAlpha()
+
+	if settings.Recording.OutputType == "video" {
+		videoOptions = getVideoOptions(fps)
+	} else {
+		videoOptions = getIntermediateOptions(fps)
+	}
+
+	segmentIndex = len(currentManifest.Segments)
+	segmentFrames = 0
+	cmdVideo = nil
+
+	freePBOPool = make(chan *PBO, MaxVideoBuffers)
+
+	frameNumber = -1
+
+	rgbToYuvConverter = nil
+	blend = nil
+
+	mainthread.Call(func() {
+		if parsedFormat != pixconv.ARGB {
+			rgbToYuvConverter = effects.NewRGBYUV(w, h, parsedFormat != pixconv.I444 && parsedFormat != pixconv.I422)
+		}
+
+		for i := 0; i < MaxVideoBuffers; i++ {
+			freePBOPool <- createPBO(parsedFormat)
+		}
+
+		if settings.Recording.MotionBlur.Enabled {
+			bFrames := settings.Recording.MotionBlur.BlendFrames
+			blend = effects.NewBlend(w, h, bFrames, calculateWeights(bFrames), readAlpha)
+		}
+	})
+
+	videoWriteQueue = make(chan *PBO, MaxVideoBuffers)
+
+	limiter = frame.NewLimiter(settings.Recording.EncodingFPSCap)
+
+	endSyncVideo = &sync.WaitGroup{}
+	endSyncVideo.Add(1)
+
+	goroutines.RunOS(func() {
+		for pbo := range videoWriteQueue {
+			pbo.convertSync.Wait() // Wait for conversion to end
+
+			if cmdVideo == nil { // Segments are started lazily so there are no empty ones
+				startVideoSegment()
+			}
+
+			if _, err := videoPipe.Write(pbo.convData); err != nil {
+				panic(fmt.Sprintf("ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", getVideoError(err)))
+			}
+
+			freePBOPool <- pbo
+
+			segmentFrames++
+
+			if segmentFrames == currentManifest.SegmentFrames {
+				finishVideoSegment()
+			}
+		}
+
+		endSyncVideo.Done()
+	})
+}
+
+// getVideoOptions returns ffmpeg's options for video encoded by selected encoder
+func getVideoOptions(fps int) []string {
 	encoder := strings.ToLower(settings.Recording.Encoder)
 	outputFormat := strings.ToLower(settings.Recording.PixelFormat)
 
@@ -131,18 +213,6 @@ func startVideo(fps, _w, _h int) {
 		videoFilters = "," + videoFilters
 	}
 
-	inputName := "-"
-
-	if runtime.GOOS != "windows" {
-		pipe, err := files.NewNamedPipe("")
-		if err != nil {
-			panic(err)
-		}
-
-		inputName = pipe.Name()
-		videoPipe = pipe
-	}
-
 	options := []string{
 		"-y", //(optional) overwrite output file if it exists
 
@@ -151,7 +221,7 @@ func startVideo(fps, _w, _h int) {
 		"-s", fmt.Sprintf("%dx%d", w, h), //size of one frame
 		"-pix_fmt", inputPixFmt,
 		"-r", strconv.Itoa(fps), //frames per second
-		"-i", inputName, //The input comes from a videoPipe
+		"-i", "-", //The input comes from a videoPipe, replaced by named pipe's name outside of Windows
 
 		"-an",
 
@@ -175,12 +245,67 @@ func startVideo(fps, _w, _h int) {
 		options = append(options, encOptions...)
 	}
 
-	options = append(options, filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container))
+	return options
+}
+
+func stopVideo() {
+	log.Println("Waiting for video to finish writing...")
+
+	checkData(true, true)
+
+	close(videoWriteQueue)
+
+	endSyncVideo.Wait()
+
+	if cmdVideo != nil {
+		finishVideoSegment()
+	}
+
+	// All PBOs are back in the pool after the write queue is drained
+	for len(freePBOPool) > 0 {
+		pbo := <-freePBOPool
+
+		gl.UnmapNamedBuffer(pbo.handle)
+		gl.DeleteBuffers(1, &pbo.handle)
+	}
+
+	log.Println("Video process finished.")
+}
+
+// startVideoSegment starts ffmpeg process encoding the next segment of the video.
+// Each segment is encoded separately, so it starts with a keyframe and segments can be concatenated without re-encoding.
+func startVideoSegment() {
+	options := slices.Clone(videoOptions)
+
+	if runtime.GOOS != "windows" {
+		pipe, err := files.NewNamedPipe("")
+		if err != nil {
+			panic(err)
+		}
+
+		options[slices.Index(options, "-i")+1] = pipe.Name()
+		videoPipe = pipe
+	}
+
+	if settings.Recording.IsImageSequence() {
+		if err := os.MkdirAll(filepath.Join(getTempDir(), "frames"), 0755); err != nil {
+			panic(err)
+		}
+
+		options = append(options,
+			"-start_number", strconv.Itoa(segmentIndex*currentManifest.SegmentFrames),
+			filepath.Join(getTempDir(), "frames", "frame_%06d."+settings.Recording.OutputType),
+		)
+	} else {
+		options = append(options, filepath.Join(getTempDir(), getSegmentName(segmentIndex)))
+	}
 
 	log.Println("Running ffmpeg with options:", options)
 
 	cmdVideo = exec.Command(ffmpegExec, options...)
 
+	var err error
+
 	if runtime.GOOS == "windows" {
 		videoPipe, err = cmdVideo.StdinPipe()
 		if err != nil {
@@ -209,29 +334,15 @@ func startVideo(fps, _w, _h int) {
 		panic(fmt.Sprintf("ffmpeg's video process failed to start! Please check if video parameters are entered correctly or video codec is supported by provided container. Error: %s", err))
 	}
 
-	freePBOPool = make(chan *PBO, MaxVideoBuffers)
+	videoLogPipe = oFile
+	videoError = ""
 
-	mainthread.Call(func() {
-		if parsedFormat != pixconv.ARGB {
-			rgbToYuvConverter = effects.NewRGBYUV(w, h, parsedFormat != pixconv.I444 && parsedFormat != pixconv.I422)
-		}
+	errorWait := &sync.WaitGroup{}
+	errorWait.Add(1)
 
-		for i := 0; i < MaxVideoBuffers; i++ {
-			freePBOPool <- createPBO(parsedFormat)
-		}
-
-		if settings.Recording.MotionBlur.Enabled {
-			bFrames := settings.Recording.MotionBlur.BlendFrames
-			blend = effects.NewBlend(w, h, bFrames, calculateWeights(bFrames))
-		}
-	})
-
-	videoWriteQueue = make(chan *PBO, MaxVideoBuffers)
+	videoErrorWait = errorWait
 
-	limiter = frame.NewLimiter(settings.Recording.EncodingFPSCap)
-
-	videoErrorWait = &sync.WaitGroup{}
-	videoErrorWait.Add(1)
+	encoder := getVideoEncoder()
 
 	goroutines.Run(func() {
 		sc := bufio.NewScanner(rFile)
@@ -262,53 +373,41 @@ func startVideo(fps, _w, _h int) {
 			}
 		}
 
-		videoErrorWait.Done()
-	})
-
-	endSyncVideo = &sync.WaitGroup{}
-	endSyncVideo.Add(1)
-
-	goroutines.RunOS(func() {
-		for pbo := range videoWriteQueue {
-			pbo.convertSync.Wait() // Wait for conversion to end
-
-			if _, err := videoPipe.Write(pbo.convData); err != nil {
-				errorMsg := err.Error()
-
-				videoErrorWait.Wait()
+		rFile.Close()
 
-				if videoError != "" {
-					errorMsg = videoError
-				}
-
-				panic(fmt.Sprintf("ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", errorMsg))
-			}
-
-			freePBOPool <- pbo
-		}
-
-		endSyncVideo.Done()
+		errorWait.Done()
 	})
 }
 
-func stopVideo() {
-	log.Println("Waiting for video to finish writing...")
+// finishVideoSegment waits for ffmpeg to finish encoding current segment and saves it in the manifest
+func finishVideoSegment() {
+	log.Println(fmt.Sprintf("Finishing video segment %d...", segmentIndex))
 
-	checkData(true, true)
+	_ = videoPipe.Close()
 
-	close(videoWriteQueue)
+	err := cmdVideo.Wait()
 
-	endSyncVideo.Wait()
+	_ = videoLogPipe.Close()
 
-	log.Println("Finished! Stopping video pipe...")
+	if err != nil {
+		panic(fmt.Sprintf("ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", getVideoError(err)))
+	}
 
-	_ = videoPipe.Close()
+	currentManifest.addSegment(segmentIndex, segmentFrames)
 
-	log.Println("Video pipe closed. Waiting for video ffmpeg process to finish...")
+	segmentIndex++
+	segmentFrames = 0
+	cmdVideo = nil
+}
 
-	_ = cmdVideo.Wait()
+func getVideoError(err error) string {
+	videoErrorWait.Wait()
 
-	log.Println("Video process finished.")
+	if videoError != "" {
+		return videoError
+	}
+
+	return err.Error()
 }
 
 func PreFrame() {
@@ -321,6 +420,24 @@ func PreFrame() {
 
 var frameNumber = int64(-1)
 
+// SkipFrame skips the next frame if it's already encoded in a finished segment and isn't blended into frames that aren't.
+// If it returns false, the frame has to be drawn and passed to MakeFrame.
+func SkipFrame() bool {
+	firstNeeded := skipFrames
+
+	if settings.Recording.MotionBlur.Enabled {
+		firstNeeded = skipFrames*int64(settings.Recording.MotionBlur.OversampleMultiplier) - int64(settings.Recording.MotionBlur.BlendFrames) + 1
+	}
+
+	if frameNumber+1 >= firstNeeded {
+		return false
+	}
+
+	frameNumber++
+
+	return true
+}
+
 func MakeFrame() {
 	frameNumber++
 
@@ -346,6 +463,15 @@ func MakeFrame() {
 		yuvFull, yuvHalf = rgbToYuvConverter.Draw()
 	}
 
+	outputFrame := frameNumber
+	if settings.Recording.MotionBlur.Enabled {
+		outputFrame /= int64(settings.Recording.MotionBlur.OversampleMultiplier)
+	}
+
+	if outputFrame < skipFrames { // Frame is already encoded in a finished segment
+		return
+	}
+
 	checkData(len(freePBOPool) == 0, false) // Force wait for at least one frame to be retrieved if pbo pool is empty
 
 	pbo := <-freePBOPool // Wait for free PBO
@@ -366,7 +492,12 @@ func MakeFrame() {
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.GREEN, gl.UNSIGNED_BYTE, int32(w*h), gl.PtrOffset(w*h))
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.BLUE, gl.UNSIGNED_BYTE, int32(w*h), gl.PtrOffset(w*h*2))
 	} else {
-		gl.ReadPixels(0, 0, int32(w), int32(h), uint32(gl.RGB), gl.UNSIGNED_BYTE, gl.Ptr(nil))
+		readFormat := uint32(gl.RGB)
+		if readAlpha {
+			readFormat = gl.RGBA
+		}
+
+		gl.ReadPixels(0, 0, int32(w), int32(h), readFormat, gl.UNSIGNED_BYTE, gl.Ptr(nil))
 	}
 
 	pbo.sync = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
@@ -68,6 +68,8 @@ type Cursor struct {
 	LeftKey, RightKey       bool
 	LeftMouse, RightMouse   bool
 
+	ManiaKeys uint32 // Held osu!mania columns, bit per column
+
 	IsReplayFrame bool // TODO: temporary hacky solution for spinners
 	IsPlayer      bool
 	IsAutoplay    bool
@@ -128,10 +130,23 @@ func NewCursor() *Cursor {
 	return cursor
 }
 
+// NewHeadlessCursor creates a cursor without renderer and graphics resources, usable only as ruleset input
+func NewHeadlessCursor() *Cursor {
+	cursor := &Cursor{Position: vector.NewVec2f(100, 100)}
+	cursor.scale = animation.NewGlider(1.0)
+
+	return cursor
+}
+
 func (cursor *Cursor) SetPos(pt vector.Vector2f) {
 	cursor.RawPosition = pt
 	tmp := pt
 
+	if cursor.renderer == nil {
+		cursor.Position = tmp
+		return
+	}
+
 	if cursor.InvertDisplay {
 		tmp.Y = 384 - tmp.Y
 	}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/framework/util"
+	"os"
+	"sort"
+	"strings"
+)
+
+func listBeatmaps(beatmaps []*beatmap.BeatMap) {
+	sort.SliceStable(beatmaps, func(i, j int) bool {
+		a, b := beatmaps[i], beatmaps[j]
+
+		if !strings.EqualFold(a.Artist, b.Artist) {
+			return strings.ToLower(a.Artist) < strings.ToLower(b.Artist)
+		}
+
+		if !strings.EqualFold(a.Name, b.Name) {
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+
+		return a.Stars < b.Stars
+	})
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Artist", "Title", "Difficulty", "Creator", "Stars", "AR", "OD", "CS", "HP", "BPM", "Length", "MD5"})
+
+	for _, b := range beatmaps {
+		stars := "?"
+		if b.Stars >= 0 {
+			stars = fmt.Sprintf("%.2f", b.Stars)
+		}
+
+		bpm := fmt.Sprintf("%.0f", b.MaxBPM)
+		if b.MinBPM < b.MaxBPM {
+			bpm = fmt.Sprintf("%.0f-%.0f", b.MinBPM, b.MaxBPM)
+		}
+
+		table.Append([]string{
+			fmt.Sprintf("%d", b.ID),
+			b.Artist,
+			b.Name,
+			b.Difficulty,
+			b.Creator,
+			stars,
+			fmt.Sprintf("%.1f", b.Diff.GetAR()),
+			fmt.Sprintf("%.1f", b.Diff.GetOD()),
+			fmt.Sprintf("%.1f", b.Diff.GetCS()),
+			fmt.Sprintf("%.1f", b.Diff.GetHP()),
+			bpm,
+			util.FormatSeconds(b.Length / 1000),
+			b.MD5,
+		})
+	}
+
+	table.Render()
+
+	fmt.Printf("Found %d beatmaps\n", len(beatmaps))
+}
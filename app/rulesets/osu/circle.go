@@ -82,12 +82,12 @@ func (circle *Circle) UpdateClickFor(player *difficultyPlayer, time int64) bool
 						if hit == Miss {
 							combo = Reset
 						} else {
-							if len(circle.players) == 1 {
+							if circle.ruleSet.showFeedback(circle.players) {
 								circle.hitCircle.PlaySound()
 							}
 						}
 
-						if len(circle.players) == 1 {
+						if circle.ruleSet.showFeedback(circle.players) {
 							circle.hitCircle.Arm(hit != Miss, float64(time))
 						}
 
@@ -99,7 +99,7 @@ func (circle *Circle) UpdateClickFor(player *difficultyPlayer, time int64) bool
 					player.leftCondE = false
 					player.rightCondE = false
 
-					if action == Shake && len(circle.players) == 1 {
+					if action == Shake && circle.ruleSet.showFeedback(circle.players) {
 						circle.hitCircle.Shake(float64(time))
 					}
 				}
@@ -119,7 +119,7 @@ func (circle *Circle) UpdatePostFor(player *difficultyPlayer, time int64, _ bool
 		position := circle.hitCircle.GetStackedPositionAtMod(float64(time), player.diff.Mods)
 		circle.ruleSet.SendResult(time, player.cursor, circle, position.X, position.Y, Miss, Reset)
 
-		if len(circle.players) == 1 {
+		if circle.ruleSet.showFeedback(circle.players) {
 			circle.hitCircle.Arm(false, float64(time))
 		}
 
@@ -23,11 +23,17 @@ const (
 	HpSpinnerSpin  = 1.7
 	HpSpinnerBonus = 2.0
 
+	HpManiaMax = 2.4
+	HpMania300 = 2.0
+	HpMania200 = 0.8
+
 	MaxHp = 200.0
 )
 
 type FailListener func()
 
+type PassingListener func(time int64, passing bool)
+
 type drain struct {
 	start, end int64
 }
@@ -52,7 +58,11 @@ type HealthProcessor struct {
 
 	playing bool
 
-	failListeners []FailListener
+	passing     bool
+	wasDraining bool
+
+	failListeners    []FailListener
+	passingListeners []PassingListener
 }
 
 func NewHealthProcessor(beatMap *beatmap.BeatMap, diff *difficulty.Difficulty, lowerSpinnerDrain bool) *HealthProcessor {
@@ -81,10 +91,15 @@ func (hp *HealthProcessor) CalculateRate() { //nolint:gocyclo
 	hp.HpMultiplierNormal = 1.0
 	hp.HpMultiplierComboEnd = 1.0
 
-	fail := true
+	// osu!mania has no passive drain
+	fail := hp.beatMap.Mode != beatmap.ModeMania
 
 	breakCount := len(hp.beatMap.Pauses)
 
+	if !fail {
+		hp.PassiveDrain = 0
+	}
+
 	for fail {
 		hp.ResetHp()
 
@@ -210,9 +225,15 @@ func (hp *HealthProcessor) CalculateRate() { //nolint:gocyclo
 func (hp *HealthProcessor) ResetHp() {
 	hp.Health = MaxHp
 	hp.HealthUncapped = MaxHp
+	hp.passing = true
 }
 
 func (hp *HealthProcessor) AddResult(result HitResult) {
+	if hp.beatMap.Mode == beatmap.ModeMania {
+		hp.addManiaResult(result)
+		return
+	}
+
 	normal := result & (^Additions)
 	addition := result & Additions
 
@@ -251,6 +272,27 @@ func (hp *HealthProcessor) AddResult(result HitResult) {
 	hp.Increase(hpAdd, true)
 }
 
+func (hp *HealthProcessor) addManiaResult(result HitResult) {
+	hpAdd := 0.0
+
+	switch result {
+	case Hit300g:
+		hpAdd = HpManiaMax
+	case Hit300:
+		hpAdd = HpMania300
+	case Hit100k:
+		hpAdd = HpMania200
+	case Hit100:
+		hpAdd = -difficulty.DifficultyRate(hp.diff.HPMod, 1, 4, 8)
+	case Hit50:
+		hpAdd = -difficulty.DifficultyRate(hp.diff.HPMod, 2, 8, 16)
+	case Miss:
+		hpAdd = -difficulty.DifficultyRate(hp.diff.HPMod, 8, 24, 48)
+	}
+
+	hp.Increase(hpAdd, true)
+}
+
 func (hp *HealthProcessor) Increase(amount float64, fromHitObject bool) {
 	hp.HealthUncapped = math.Max(0.0, hp.HealthUncapped+amount)
 	hp.Health = mutils.ClampF(hp.Health+amount, 0.0, MaxHp)
@@ -301,9 +343,29 @@ func (hp *HealthProcessor) Update(time int64) {
 		hp.ReducePassive(time - hp.lastTime)
 	}
 
+	// Like in stable, pass/fail state is evaluated only when drain period ends (break or end of the map)
+	if hp.playing && hp.wasDraining && !drainTime {
+		if passing := hp.Health >= MaxHp/2; passing != hp.passing {
+			hp.passing = passing
+
+			for _, f := range hp.passingListeners {
+				f(time, passing)
+			}
+		}
+	}
+
+	hp.wasDraining = drainTime
 	hp.lastTime = time
 }
 
+func (hp *HealthProcessor) IsPassing() bool {
+	return hp.passing
+}
+
 func (hp *HealthProcessor) AddFailListener(listener FailListener) {
 	hp.failListeners = append(hp.failListeners, listener)
 }
+
+func (hp *HealthProcessor) AddPassingListener(listener PassingListener) {
+	hp.passingListeners = append(hp.passingListeners, listener)
+}
@@ -0,0 +1,291 @@
+package osu
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"math"
+)
+
+// osu!mania hit windows at OD 0, every point of OD shrinks them by 3ms except MAX
+const (
+	maniaWindowMax  = 16.0
+	maniaWindow300  = 64.0
+	maniaWindow200  = 97.0
+	maniaWindow100  = 127.0
+	maniaWindow50   = 151.0
+	maniaWindowMiss = 188.0
+
+	maniaTailLenience = 1.5 // Windows of hold's release are wider
+)
+
+type maniaWindows struct {
+	max, hit300, hit200, hit100, hit50, miss float64
+}
+
+// newManiaWindows calculates hit windows in map's time. Windows don't change with speed in real time,
+// HardRock and Easy scale them instead of OD.
+func newManiaWindows(diff *difficulty.Difficulty) maniaWindows {
+	od := diff.GetOD()
+
+	scale := diff.Speed
+
+	if diff.CheckModActive(difficulty.HardRock) {
+		scale /= 1.4
+	} else if diff.CheckModActive(difficulty.Easy) {
+		scale *= 1.4
+	}
+
+	return maniaWindows{
+		max:    math.Floor(maniaWindowMax*scale) + 0.5,
+		hit300: math.Floor((maniaWindow300-3*od)*scale) + 0.5,
+		hit200: math.Floor((maniaWindow200-3*od)*scale) + 0.5,
+		hit100: math.Floor((maniaWindow100-3*od)*scale) + 0.5,
+		hit50:  math.Floor((maniaWindow50-3*od)*scale) + 0.5,
+		miss:   math.Floor((maniaWindowMiss-3*od)*scale) + 0.5,
+	}
+}
+
+// judge returns the result for absolute offset from note's time. MAX is Hit300 with GekiAddition, 200 is Hit100 with KatuAddition.
+func (windows maniaWindows) judge(offset float64) HitResult {
+	switch {
+	case offset <= windows.max:
+		return Hit300g
+	case offset <= windows.hit300:
+		return Hit300
+	case offset <= windows.hit200:
+		return Hit100k
+	case offset <= windows.hit100:
+		return Hit100
+	case offset <= windows.hit50:
+		return Hit50
+	}
+
+	return Miss
+}
+
+type maniaNoteState struct {
+	headResult HitResult // Ignore till the note (or hold's head) is judged
+	holding    bool
+	isHit      bool // Note is fully judged
+}
+
+type ManiaNote struct {
+	ruleSet           *OsuRuleSet
+	note              *objects.ManiaNote
+	players           []*difficultyPlayer
+	state             map[*difficultyPlayer]*maniaNoteState
+	fadeStartRelative float64
+}
+
+func (note *ManiaNote) GetNumber() int64 {
+	return note.note.GetID()
+}
+
+func (note *ManiaNote) Init(ruleSet *OsuRuleSet, object objects.IHitObject, players []*difficultyPlayer) {
+	note.ruleSet = ruleSet
+	note.note = object.(*objects.ManiaNote)
+	note.players = players
+
+	note.state = make(map[*difficultyPlayer]*maniaNoteState)
+
+	for _, player := range note.players {
+		note.state[player] = new(maniaNoteState)
+		note.fadeStartRelative = math.Max(note.fadeStartRelative, player.maniaWindows.miss)
+	}
+}
+
+func (note *ManiaNote) UpdateFor(_ *difficultyPlayer, _ int64, _ bool) bool {
+	return true
+}
+
+// UpdateClickFor judges presses in note's column. Earlier notes in the same column are processed first so they consume the press.
+func (note *ManiaNote) UpdateClickFor(player *difficultyPlayer, time int64) bool {
+	state := note.state[player]
+
+	column := uint32(1) << note.note.Column
+
+	if state.isHit || player.maniaPressed&column == 0 {
+		return !state.isHit
+	}
+
+	if state.headResult == Ignore {
+		offset := float64(time) - note.note.GetStartTime()
+
+		if offset < -player.maniaWindows.miss {
+			return true
+		}
+
+		player.maniaPressed &^= column
+
+		note.judgeHead(player, time, player.maniaWindows.judge(math.Abs(offset)))
+	} else if note.note.IsHold() && !state.holding && float64(time) < note.note.GetEndTime() {
+		// Hold's head was missed, but it can still be held till the end
+		player.maniaPressed &^= column
+
+		state.holding = true
+
+		if note.ruleSet.showFeedback(note.players) {
+			note.note.SetHolding(true)
+		}
+	}
+
+	return !state.isHit
+}
+
+// UpdatePostFor judges hold's release. Releasing too early misses the tail.
+func (note *ManiaNote) UpdatePostFor(player *difficultyPlayer, time int64, _ bool) bool {
+	state := note.state[player]
+
+	if state.holding && float64(time) < note.note.GetEndTime() && player.cursor.ManiaKeys&(uint32(1)<<note.note.Column) == 0 {
+		note.judgeTail(player, time, player.maniaWindows.judge((note.note.GetEndTime()-float64(time))/maniaTailLenience))
+	}
+
+	note.updateTimeouts(player, time)
+
+	return state.isHit
+}
+
+// UpdatePost judges timeouts every millisecond, osu!mania replays have frames only when keys change
+func (note *ManiaNote) UpdatePost(time int64) bool {
+	unfinished := 0
+
+	for _, player := range note.players {
+		note.updateTimeouts(player, time)
+
+		if !note.state[player].isHit {
+			unfinished++
+		}
+	}
+
+	return unfinished == 0
+}
+
+func (note *ManiaNote) updateTimeouts(player *difficultyPlayer, time int64) {
+	state := note.state[player]
+
+	if state.isHit {
+		return
+	}
+
+	if state.headResult == Ignore && float64(time) > note.note.GetStartTime()+player.maniaWindows.hit50 {
+		note.judgeHead(player, time, Miss)
+	}
+
+	if !note.note.IsHold() || state.headResult == Ignore || state.isHit {
+		return
+	}
+
+	if state.holding && float64(time) >= note.note.GetEndTime() {
+		note.judgeTail(player, time, Hit300g)
+	} else if !state.holding && float64(time) > note.note.GetEndTime()+player.maniaWindows.hit50*maniaTailLenience {
+		note.judgeTail(player, time, Miss)
+	}
+}
+
+func (note *ManiaNote) judgeHead(player *difficultyPlayer, time int64, result HitResult) {
+	state := note.state[player]
+
+	state.headResult = result
+	state.holding = note.note.IsHold() && result != Miss
+	state.isHit = !note.note.IsHold()
+
+	if note.ruleSet.showFeedback(note.players) {
+		if result != Miss {
+			note.note.PlaySound()
+		}
+
+		note.note.Arm(result != Miss, float64(time))
+	}
+
+	note.sendResult(player, time, result)
+}
+
+// judgeTail finishes the hold, tail can't be better than 50 if the head was missed
+func (note *ManiaNote) judgeTail(player *difficultyPlayer, time int64, result HitResult) {
+	state := note.state[player]
+
+	if state.headResult == Miss && result != Miss {
+		result = Hit50
+	}
+
+	state.holding = false
+	state.isHit = true
+
+	if note.ruleSet.showFeedback(note.players) {
+		note.note.ArmEnd(result != Miss, float64(time))
+	}
+
+	note.sendResult(player, time, result)
+}
+
+func (note *ManiaNote) sendResult(player *difficultyPlayer, time int64, result HitResult) {
+	combo := Increase
+	if result == Miss {
+		combo = Reset
+	}
+
+	position := note.note.GetStartPosition()
+
+	note.ruleSet.SendResult(time, player.cursor, note, position.X, position.Y, result, combo)
+}
+
+func (note *ManiaNote) IsHit(player *difficultyPlayer) bool {
+	return note.state[player].isHit
+}
+
+func (note *ManiaNote) GetFadeTime() int64 {
+	return int64(note.note.GetStartTime() - note.fadeStartRelative)
+}
+
+// updateManiaScore updates counts, accuracy and grade. MAXes are counted as geki and 200s as katu like in osu!stable.
+func (subSet *subSet) updateManiaScore(result HitResult) {
+	switch result {
+	case Hit300g:
+		subSet.score.CountGeki++
+		subSet.rawScore += 300
+	case Hit300:
+		subSet.score.Count300++
+		subSet.rawScore += 300
+	case Hit100k:
+		subSet.score.CountKatu++
+		subSet.rawScore += 200
+	case Hit100:
+		subSet.score.Count100++
+		subSet.rawScore += 100
+	case Hit50:
+		subSet.score.Count50++
+		subSet.rawScore += 50
+	case Miss:
+		subSet.score.CountMiss++
+	default:
+		return
+	}
+
+	subSet.numObjects++
+
+	subSet.score.Accuracy = 100 * float64(subSet.rawScore) / float64(subSet.numObjects*300)
+	subSet.score.PerfectCombo = subSet.score.CountMiss == 0
+
+	hidden := subSet.player.diff.Mods&(difficulty.Hidden|difficulty.Flashlight) > 0
+
+	switch {
+	case subSet.rawScore == int64(subSet.numObjects*300):
+		subSet.score.Grade = SS
+		if hidden {
+			subSet.score.Grade = SSH
+		}
+	case subSet.score.Accuracy > 95:
+		subSet.score.Grade = S
+		if hidden {
+			subSet.score.Grade = SH
+		}
+	case subSet.score.Accuracy > 90:
+		subSet.score.Grade = A
+	case subSet.score.Accuracy > 80:
+		subSet.score.Grade = B
+	case subSet.score.Accuracy > 70:
+		subSet.score.Grade = C
+	default:
+		subSet.score.Grade = D
+	}
+}
@@ -0,0 +1,78 @@
+package performance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp211112"
+)
+
+type calculator211112 struct{}
+
+func (c *calculator211112) Version() int {
+	return pp211112.CurrentVersion
+}
+
+func (c *calculator211112) Description() string {
+	return "2021-11-12"
+}
+
+func (c *calculator211112) CalculateSingle(objects []objects.IHitObject, diff *difficulty.Difficulty) Attributes {
+	return from211112(pp211112.CalculateSingle(objects, diff, false))
+}
+
+func (c *calculator211112) CalculateStep(objects []objects.IHitObject, diff *difficulty.Difficulty) []Attributes {
+	steps := pp211112.CalculateStep(objects, diff, false)
+
+	attribs := make([]Attributes, len(steps))
+
+	for i, a := range steps {
+		attribs[i] = from211112(a)
+	}
+
+	return attribs
+}
+
+func (c *calculator211112) CalculateStrainPeaks(objects []objects.IHitObject, diff *difficulty.Difficulty) StrainPeaks {
+	return StrainPeaks(pp211112.CalculateStrainPeaks(objects, diff, false))
+}
+
+func (c *calculator211112) CalculatePP(attribs Attributes, combo, n300, n100, n50, nmiss int, diff *difficulty.Difficulty) PPv2Results {
+	pp := &pp211112.PPv2{}
+	pp.PPv2x(to211112(attribs), combo, n300, n100, n50, nmiss, diff, false)
+
+	return PPv2Results(pp.Results)
+}
+
+func from211112(a pp211112.Attributes) Attributes {
+	return Attributes{
+		Total:                     a.Total,
+		Aim:                       a.Aim,
+		AimDifficultStrainCount:   a.AimDifficultStrainCount,
+		SliderFactor:              a.SliderFactor,
+		Speed:                     a.Speed,
+		SpeedDifficultStrainCount: a.SpeedDifficultStrainCount,
+		Flashlight:                a.Flashlight,
+		ObjectCount:               a.ObjectCount,
+		Circles:                   a.Circles,
+		Sliders:                   a.Sliders,
+		Spinners:                  a.Spinners,
+		MaxCombo:                  a.MaxCombo,
+	}
+}
+
+func to211112(a Attributes) pp211112.Attributes {
+	return pp211112.Attributes{
+		Total:                     a.Total,
+		Aim:                       a.Aim,
+		AimDifficultStrainCount:   a.AimDifficultStrainCount,
+		SliderFactor:              a.SliderFactor,
+		Speed:                     a.Speed,
+		SpeedDifficultStrainCount: a.SpeedDifficultStrainCount,
+		Flashlight:                a.Flashlight,
+		ObjectCount:               a.ObjectCount,
+		Circles:                   a.Circles,
+		Sliders:                   a.Sliders,
+		Spinners:                  a.Spinners,
+		MaxCombo:                  a.MaxCombo,
+	}
+}
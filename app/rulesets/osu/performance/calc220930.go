@@ -0,0 +1,76 @@
+package performance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
+)
+
+type calculator220930 struct{}
+
+func (c *calculator220930) Version() int {
+	return pp220930.CurrentVersion
+}
+
+func (c *calculator220930) Description() string {
+	return "2022-09-30: https://osu.ppy.sh/home/news/2022-09-30-changes-to-osu-sr-and-pp"
+}
+
+func (c *calculator220930) CalculateSingle(objects []objects.IHitObject, diff *difficulty.Difficulty) Attributes {
+	return from220930(pp220930.CalculateSingle(objects, diff))
+}
+
+func (c *calculator220930) CalculateStep(objects []objects.IHitObject, diff *difficulty.Difficulty) []Attributes {
+	steps := pp220930.CalculateStep(objects, diff)
+
+	attribs := make([]Attributes, len(steps))
+
+	for i, a := range steps {
+		attribs[i] = from220930(a)
+	}
+
+	return attribs
+}
+
+func (c *calculator220930) CalculateStrainPeaks(objects []objects.IHitObject, diff *difficulty.Difficulty) StrainPeaks {
+	return StrainPeaks(pp220930.CalculateStrainPeaks(objects, diff))
+}
+
+func (c *calculator220930) CalculatePP(attribs Attributes, combo, n300, n100, n50, nmiss int, diff *difficulty.Difficulty) PPv2Results {
+	pp := &pp220930.PPv2{}
+	pp.PPv2x(to220930(attribs), combo, n300, n100, n50, nmiss, diff)
+
+	return PPv2Results(pp.Results)
+}
+
+func from220930(a pp220930.Attributes) Attributes {
+	return Attributes{
+		Total:          a.Total,
+		Aim:            a.Aim,
+		SliderFactor:   a.SliderFactor,
+		Speed:          a.Speed,
+		SpeedNoteCount: a.SpeedNoteCount,
+		Flashlight:     a.Flashlight,
+		ObjectCount:    a.ObjectCount,
+		Circles:        a.Circles,
+		Sliders:        a.Sliders,
+		Spinners:       a.Spinners,
+		MaxCombo:       a.MaxCombo,
+	}
+}
+
+func to220930(a Attributes) pp220930.Attributes {
+	return pp220930.Attributes{
+		Total:          a.Total,
+		Aim:            a.Aim,
+		Speed:          a.Speed,
+		SpeedNoteCount: a.SpeedNoteCount,
+		Flashlight:     a.Flashlight,
+		SliderFactor:   a.SliderFactor,
+		ObjectCount:    a.ObjectCount,
+		Circles:        a.Circles,
+		Sliders:        a.Sliders,
+		Spinners:       a.Spinners,
+		MaxCombo:       a.MaxCombo,
+	}
+}
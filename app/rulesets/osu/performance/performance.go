@@ -0,0 +1,142 @@
+package performance
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Latest is the name of the newest registered calculator
+const Latest = "latest"
+
+// Attributes contains difficulty attributes of all pp versions, fields not used by a version are left at 0
+type Attributes struct {
+	// Total Star rating, visible on osu!'s beatmap page
+	Total float64
+
+	// Aim stars, needed for Performance Points (aka PP) calculations
+	Aim float64
+
+	// Used only by 2021-11-12 calculator
+	AimDifficultStrainCount float64
+
+	// SliderFactor is a ratio of Aim calculated without sliders to Aim with them
+	SliderFactor float64
+
+	// Speed stars, needed for Performance Points (aka PP) calculations
+	Speed float64
+
+	// Used only by 2021-11-12 calculator
+	SpeedDifficultStrainCount float64
+
+	// Used only by 2022-09-30 calculator
+	SpeedNoteCount float64
+
+	// Flashlight stars, needed for Performance Points (aka PP) calculations
+	Flashlight float64
+
+	ObjectCount int
+	Circles     int
+	Sliders     int
+	Spinners    int
+	MaxCombo    int
+}
+
+// StrainPeaks contains peaks of Aim, Speed and Flashlight skills, as well as peaks passed through star rating formula
+type StrainPeaks struct {
+	// Aim peaks
+	Aim []float64
+
+	// Speed peaks
+	Speed []float64
+
+	// Flashlight peaks
+	Flashlight []float64
+
+	// Total contains aim, speed and flashlight peaks passed through star rating formula
+	Total []float64
+}
+
+type PPv2Results struct {
+	Aim, Speed, Acc, Flashlight, Total float64
+}
+
+// Calculator is a version of osu!standard star rating and performance points algorithms
+type Calculator interface {
+	// Version is the date of the release in yyyyMMdd format, it's also stored as beatmap's StarsVersion
+	Version() int
+
+	// Description is shown in logs when calculator is used
+	Description() string
+
+	// CalculateSingle calculates the final star rating of a map
+	CalculateSingle(objects []objects.IHitObject, diff *difficulty.Difficulty) Attributes
+
+	// CalculateStep calculates successive star ratings for every part of a beatmap
+	CalculateStep(objects []objects.IHitObject, diff *difficulty.Difficulty) []Attributes
+
+	CalculateStrainPeaks(objects []objects.IHitObject, diff *difficulty.Difficulty) StrainPeaks
+
+	// CalculatePP calculates pp from hit counts. Negative combo and n300 mean max combo and remaining objects
+	CalculatePP(attribs Attributes, combo, n300, n100, n50, nmiss int, diff *difficulty.Difficulty) PPv2Results
+}
+
+// Newest calculator first
+var calculators = []Calculator{
+	&calculator220930{},
+	&calculator211112{},
+}
+
+func init() {
+	for _, v := range GetVersions() {
+		settings.RegisterPPVersion(v)
+	}
+}
+
+// GetCalculator returns calculator by its version (e.g. 20211112) or Latest, nil if it doesn't exist
+func GetCalculator(version string) Calculator {
+	version = strings.TrimSpace(version)
+
+	if version == "" || strings.EqualFold(version, Latest) {
+		return calculators[0]
+	}
+
+	v, err := strconv.Atoi(strings.ReplaceAll(version, "-", ""))
+	if err != nil {
+		return nil
+	}
+
+	for _, c := range calculators {
+		if c.Version() == v {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// GetCurrent returns calculator selected in Gameplay.PPVersion setting
+func GetCurrent() Calculator {
+	if c := GetCalculator(settings.Gameplay.PPVersion); c != nil {
+		return c
+	}
+
+	log.Println(fmt.Sprintf("Unknown pp calc version \"%s\", using the latest one", settings.Gameplay.PPVersion))
+
+	return calculators[0]
+}
+
+// GetVersions returns versions of registered calculators, newest first
+func GetVersions() []string {
+	versions := make([]string, 0, len(calculators))
+
+	for _, c := range calculators {
+		versions = append(versions, strconv.Itoa(c.Version()))
+	}
+
+	return versions
+}
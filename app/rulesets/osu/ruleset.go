@@ -7,7 +7,7 @@ import (
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	"github.com/wieku/danser-go/app/beatmap/objects"
 	"github.com/wieku/danser-go/app/graphics"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/utils"
 	"github.com/wieku/danser-go/framework/math/mutils"
@@ -69,6 +69,10 @@ type difficultyPlayer struct {
 	leftCondE       bool
 	rightCond       bool
 	rightCondE      bool
+
+	maniaWindows maniaWindows
+	maniaKeys    uint32 // osu!mania columns held in the previous frame
+	maniaPressed uint32 // osu!mania columns pressed in this frame that didn't hit a note yet
 }
 
 type scoreProcessor interface {
@@ -92,7 +96,7 @@ type Score struct {
 	Count50      uint
 	CountMiss    uint
 	CountSB      uint
-	PP           pp220930.PPv2Results
+	PP           performance.PPv2Results
 }
 
 type subSet struct {
@@ -107,7 +111,7 @@ type subSet struct {
 
 	numObjects uint
 
-	ppv2 *pp220930.PPv2
+	ppResults performance.PPv2Results
 
 	recoveries int
 	failed     bool
@@ -115,27 +119,35 @@ type subSet struct {
 	forceFail  bool
 }
 
-type hitListener func(cursor *graphics.Cursor, time int64, number int64, position vector.Vector2d, result HitResult, comboResult ComboResult, ppResults pp220930.PPv2Results, score int64)
+type hitListener func(cursor *graphics.Cursor, time int64, number int64, position vector.Vector2d, result HitResult, comboResult ComboResult, ppResults performance.PPv2Results, score int64)
 
 type endListener func(time int64, number int64)
 
 type failListener func(cursor *graphics.Cursor)
 
+type passingListener func(cursor *graphics.Cursor, time int64, passing bool)
+
 type OsuRuleSet struct {
 	beatMap *beatmap.BeatMap
 	cursors map[*graphics.Cursor]*subSet
 
 	ended bool
 
-	oppDiffs map[difficulty.Modifier][]pp220930.Attributes
+	oppDiffs map[difficulty.Modifier][]performance.Attributes
 
 	queue        []HitObject
 	processed    []HitObject
-	hitListener  hitListener
-	endListener  endListener
-	failListener failListener
+	hitListeners  []hitListener
+	endListener   endListener
+	failListeners []failListener
+
+	passingListener passingListener
 
 	experimentalPP bool
+
+	ppCalculator performance.Calculator
+
+	headless bool
 }
 
 func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []difficulty.Modifier) *OsuRuleSet {
@@ -143,9 +155,10 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 
 	ruleset := new(OsuRuleSet)
 	ruleset.beatMap = beatMap
-	ruleset.oppDiffs = make(map[difficulty.Modifier][]pp220930.Attributes)
+	ruleset.oppDiffs = make(map[difficulty.Modifier][]performance.Attributes)
+	ruleset.ppCalculator = performance.GetCurrent()
 
-	log.Println("Using pp calc version 2022-09-30: https://osu.ppy.sh/home/news/2022-09-30-changes-to-osu-sr-and-pp")
+	log.Println("Using pp calc version", ruleset.ppCalculator.Description())
 
 	ruleset.cursors = make(map[*graphics.Cursor]*subSet)
 
@@ -165,10 +178,15 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 		player := &difficultyPlayer{cursor: cursor, diff: diff}
 		diffPlayers = append(diffPlayers, player)
 
+		if beatMap.Mode == beatmap.ModeMania {
+			player.maniaWindows = newManiaWindows(diff)
+		}
+
 		maskedMods := difficulty.GetDiffMaskedMods(mods[i])
 
-		if ruleset.oppDiffs[maskedMods] == nil {
-			ruleset.oppDiffs[maskedMods] = pp220930.CalculateStep(ruleset.beatMap.HitObjects, diff)
+		// pp calculators support only osu!standard
+		if ruleset.oppDiffs[maskedMods] == nil && beatMap.Mode != beatmap.ModeMania {
+			ruleset.oppDiffs[maskedMods] = ruleset.ppCalculator.CalculateStep(ruleset.beatMap.HitObjects, diff)
 
 			star := ruleset.oppDiffs[maskedMods][len(ruleset.oppDiffs[maskedMods])-1]
 
@@ -182,19 +200,18 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 
 			log.Println("\tTotal:", star.Total)
 
-			pp := &pp220930.PPv2{}
-			pp.PPv2x(star, -1, -1, 0, 0, 0, diff)
+			pp := ruleset.ppCalculator.CalculatePP(star, -1, -1, 0, 0, 0, diff)
 
 			log.Println("SS PP:")
-			log.Println("\tAim:  ", pp.Results.Aim)
-			log.Println("\tTap:  ", pp.Results.Speed)
+			log.Println("\tAim:  ", pp.Aim)
+			log.Println("\tTap:  ", pp.Speed)
 
 			if ruleset.experimentalPP && mods[i].Active(difficulty.Flashlight) {
 				log.Println("\tFlash:", star.Flashlight)
 			}
 
-			log.Println("\tAcc:  ", pp.Results.Acc)
-			log.Println("\tTotal:", pp.Results.Total)
+			log.Println("\tAcc:  ", pp.Acc)
+			log.Println("\tTotal:", pp.Total)
 		}
 
 		log.Println(fmt.Sprintf("Calculating HP rates for \"%s\"...", cursor.Name))
@@ -216,9 +233,19 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 			ruleset.failInternal(player)
 		})
 
+		hpCursor := cursor
+
+		hp.AddPassingListener(func(time int64, passing bool) {
+			if ruleset.passingListener != nil {
+				ruleset.passingListener(hpCursor, time, passing)
+			}
+		})
+
 		var sc scoreProcessor
 
-		if diff.CheckModActive(difficulty.ScoreV2) {
+		if beatMap.Mode == beatmap.ModeMania {
+			sc = newScoreManiaProcessor()
+		} else if diff.CheckModActive(difficulty.ScoreV2) {
 			sc = newScoreV2Processor()
 		} else {
 			sc = newScoreV1Processor()
@@ -231,7 +258,6 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 			score: &Score{
 				Accuracy: 100,
 			},
-			ppv2:           &pp220930.PPv2{},
 			hp:             hp,
 			recoveries:     recoveries,
 			scoreProcessor: sc,
@@ -256,6 +282,12 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 			rSpinner.Init(ruleset, spinner, diffPlayers)
 			ruleset.queue = append(ruleset.queue, rSpinner)
 		}
+
+		if note, ok := obj.(*objects.ManiaNote); ok {
+			rNote := new(ManiaNote)
+			rNote.Init(ruleset, note, diffPlayers)
+			ruleset.queue = append(ruleset.queue, rNote)
+		}
 	}
 
 	return ruleset
@@ -325,7 +357,7 @@ func (set *OsuRuleSet) Update(time int64) {
 			data = append(data, utils.Humanize(set.cursors[c].scoreProcessor.GetCombo()))
 			data = append(data, utils.Humanize(set.cursors[c].score.Combo))
 			data = append(data, set.cursors[c].player.diff.GetModString())
-			data = append(data, fmt.Sprintf("%.2f", set.cursors[c].ppv2.Results.Total))
+			data = append(data, fmt.Sprintf("%.2f", set.cursors[c].ppResults.Total))
 			table.Append(data)
 		}
 
@@ -366,6 +398,8 @@ func (set *OsuRuleSet) UpdateClickFor(cursor *graphics.Cursor, time int64) {
 				player.mouseDownButton |= Right
 			}
 		}
+
+		player.maniaPressed = player.cursor.ManiaKeys &^ player.maniaKeys
 	}
 
 	if len(set.processed) > 0 && !set.cursors[cursor].failed {
@@ -379,6 +413,7 @@ func (set *OsuRuleSet) UpdateClickFor(cursor *graphics.Cursor, time int64) {
 	if player.cursor.IsReplayFrame || player.cursor.IsPlayer {
 		player.buttons.Left = player.cursor.LeftButton
 		player.buttons.Right = player.cursor.RightButton
+		player.maniaKeys = player.cursor.ManiaKeys
 	}
 }
 
@@ -429,8 +464,10 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 	subSet := set.cursors[cursor]
 
 	if result == Ignore || result == PositionalMiss {
-		if result == PositionalMiss && set.hitListener != nil && !subSet.player.diff.Mods.Active(difficulty.Relax) {
-			set.hitListener(cursor, time, number, vector.NewVec2f(x, y).Copy64(), result, comboResult, subSet.ppv2.Results, subSet.scoreProcessor.GetScore())
+		if result == PositionalMiss && !subSet.player.diff.Mods.Active(difficulty.Relax) {
+			for _, listener := range set.hitListeners {
+				listener(cursor, time, number, vector.NewVec2f(x, y).Copy64(), result, comboResult, subSet.ppResults, subSet.scoreProcessor.GetScore())
+			}
 		}
 
 		return
@@ -457,6 +494,47 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 		subSet.score.CountSB++
 	}
 
+	subSet.score.Combo = mutils.Max(uint(subSet.scoreProcessor.GetCombo()), subSet.score.Combo)
+
+	if set.beatMap.Mode == beatmap.ModeMania {
+		subSet.updateManiaScore(result)
+	} else {
+		result = set.updateScore(subSet, number, result)
+	}
+
+	if subSet.sdpfFail {
+		subSet.hp.Increase(-100000, true)
+	} else {
+		subSet.hp.AddResult(result)
+	}
+
+	for _, listener := range set.hitListeners {
+		listener(cursor, time, number, vector.NewVec2f(x, y).Copy64(), result, comboResult, subSet.ppResults, subSet.scoreProcessor.GetScore())
+	}
+
+	if len(set.cursors) == 1 && !settings.RECORD && !set.headless {
+		log.Println(fmt.Sprintf(
+			"Got: %3d, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, 300: %4d, 100: %3d, 50: %2d, miss: %2d, from: %d, at: %d, pos: %.0fx%.0f, pp: %.2f",
+			result.ScoreValue(),
+			subSet.scoreProcessor.GetCombo(),
+			subSet.score.Combo,
+			subSet.scoreProcessor.GetScore(),
+			subSet.score.Accuracy,
+			subSet.score.Count300,
+			subSet.score.Count100,
+			subSet.score.Count50,
+			subSet.score.CountMiss,
+			number,
+			time,
+			x,
+			y,
+			subSet.ppResults.Total,
+		))
+	}
+}
+
+// updateScore updates counts, accuracy, grade and pp of osu!standard score. Returned result has combo end additions applied.
+func (set *OsuRuleSet) updateScore(subSet *subSet, number int64, result HitResult) HitResult {
 	bResult := result & BaseHitsM
 
 	if bResult > 0 {
@@ -476,8 +554,6 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 		subSet.numObjects++
 	}
 
-	subSet.score.Combo = mutils.Max(uint(subSet.scoreProcessor.GetCombo()), subSet.score.Combo)
-
 	if subSet.numObjects == 0 {
 		subSet.score.Accuracy = 100
 	} else {
@@ -514,9 +590,9 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 
 	subSet.score.PerfectCombo = uint(diff.MaxCombo) == subSet.score.Combo
 
-	subSet.ppv2.PPv2x(diff, int(subSet.score.Combo), int(subSet.score.Count300), int(subSet.score.Count100), int(subSet.score.Count50), int(subSet.score.CountMiss), subSet.player.diff)
+	subSet.ppResults = set.ppCalculator.CalculatePP(diff, int(subSet.score.Combo), int(subSet.score.Count300), int(subSet.score.Count100), int(subSet.score.Count50), int(subSet.score.CountMiss), subSet.player.diff)
 
-	subSet.score.PP = subSet.ppv2.Results
+	subSet.score.PP = subSet.ppResults
 
 	switch result {
 	case Hit100:
@@ -562,35 +638,7 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 		subSet.currentKatu = 0
 	}
 
-	if subSet.sdpfFail {
-		subSet.hp.Increase(-100000, true)
-	} else {
-		subSet.hp.AddResult(result)
-	}
-
-	if set.hitListener != nil {
-		set.hitListener(cursor, time, number, vector.NewVec2f(x, y).Copy64(), result, comboResult, subSet.ppv2.Results, subSet.scoreProcessor.GetScore())
-	}
-
-	if len(set.cursors) == 1 && !settings.RECORD {
-		log.Println(fmt.Sprintf(
-			"Got: %3d, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, 300: %4d, 100: %3d, 50: %2d, miss: %2d, from: %d, at: %d, pos: %.0fx%.0f, pp: %.2f",
-			result.ScoreValue(),
-			subSet.scoreProcessor.GetCombo(),
-			subSet.score.Combo,
-			subSet.scoreProcessor.GetScore(),
-			subSet.score.Accuracy,
-			subSet.score.Count300,
-			subSet.score.Count100,
-			subSet.score.Count50,
-			subSet.score.CountMiss,
-			number,
-			time,
-			x,
-			y,
-			subSet.ppv2.Results.Total,
-		))
-	}
+	return result
 }
 
 func (set *OsuRuleSet) CanBeHit(time int64, object HitObject, player *difficultyPlayer) ClickAction {
@@ -672,8 +720,10 @@ func (set *OsuRuleSet) failInternal(player *difficultyPlayer) {
 	}
 
 	// actual fail
-	if set.failListener != nil && !subSet.failed {
-		set.failListener(player.cursor)
+	if !subSet.failed {
+		for _, listener := range set.failListeners {
+			listener(player.cursor)
+		}
 	}
 
 	subSet.failed = true
@@ -689,16 +739,30 @@ func (set *OsuRuleSet) PlayerStopped(cursor *graphics.Cursor, time int64) {
 	}
 }
 
-func (set *OsuRuleSet) SetListener(listener hitListener) {
-	set.hitListener = listener
+// SetHeadless disables hit object feedback (samples, animations) and per-hit logging, used when there's nothing to render
+func (set *OsuRuleSet) SetHeadless(headless bool) {
+	set.headless = headless
+}
+
+func (set *OsuRuleSet) showFeedback(players []*difficultyPlayer) bool {
+	return len(players) == 1 && !set.headless
+}
+
+func (set *OsuRuleSet) AddListener(listener hitListener) {
+	set.hitListeners = append(set.hitListeners, listener)
 }
 
 func (set *OsuRuleSet) SetEndListener(listener endListener) {
 	set.endListener = listener
 }
 
-func (set *OsuRuleSet) SetFailListener(listener failListener) {
-	set.failListener = listener
+func (set *OsuRuleSet) AddFailListener(listener failListener) {
+	set.failListeners = append(set.failListeners, listener)
+}
+
+// SetPassingListener sets a listener called when player's storyboard pass/fail state changes
+func (set *OsuRuleSet) SetPassingListener(listener passingListener) {
+	set.passingListener = listener
 }
 
 func (set *OsuRuleSet) GetScore(cursor *graphics.Cursor) Score {
@@ -722,3 +786,11 @@ func (set *OsuRuleSet) GetProcessed() []HitObject {
 func (set *OsuRuleSet) GetBeatMap() *beatmap.BeatMap {
 	return set.beatMap
 }
+
+func (set *OsuRuleSet) GetPPCalculator() performance.Calculator {
+	return set.ppCalculator
+}
+
+func (set *OsuRuleSet) IsEnded() bool {
+	return set.ended
+}
@@ -0,0 +1,103 @@
+package osu
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+const maniaMaxScore = 1000000.0
+
+// scoreManiaProcessor follows osu!stable's osu!mania ScoreV1: half of the score comes from judgements,
+// the other half from bonus which grows with MAXes and 300s and drops with worse judgements.
+type scoreManiaProcessor struct {
+	score int64
+	combo int64
+
+	noteValue  float64
+	baseScore  float64
+	bonusScore float64
+	bonus      float64
+}
+
+func newScoreManiaProcessor() *scoreManiaProcessor {
+	return &scoreManiaProcessor{}
+}
+
+func (s *scoreManiaProcessor) Init(beatMap *beatmap.BeatMap, player *difficultyPlayer) {
+	judgements := 0
+
+	for _, o := range beatMap.HitObjects {
+		judgements++
+
+		// Head and tail are judged separately
+		if o.GetType() == objects.LONGNOTE {
+			judgements++
+		}
+	}
+
+	modMultiplier := 1.0
+
+	if player.diff.CheckModActive(difficulty.Easy) {
+		modMultiplier *= 0.5
+	}
+
+	if player.diff.CheckModActive(difficulty.NoFail) {
+		modMultiplier *= 0.5
+	}
+
+	if player.diff.CheckModActive(difficulty.HalfTime) {
+		modMultiplier *= 0.5
+	}
+
+	s.noteValue = maniaMaxScore * modMultiplier * 0.5 / float64(mutils.Max(judgements, 1))
+	s.bonus = 100
+}
+
+func (s *scoreManiaProcessor) AddResult(result HitResult, comboResult ComboResult) {
+	var hitValue, hitBonusValue, hitBonus, hitPunishment float64
+
+	switch result {
+	case Hit300g:
+		hitValue, hitBonusValue, hitBonus = 320, 32, 2
+	case Hit300:
+		hitValue, hitBonusValue, hitBonus = 300, 32, 1
+	case Hit100k:
+		hitValue, hitBonusValue, hitPunishment = 200, 16, 8
+	case Hit100:
+		hitValue, hitBonusValue, hitPunishment = 100, 8, 24
+	case Hit50:
+		hitValue, hitBonusValue, hitPunishment = 50, 4, 44
+	case Miss:
+		hitPunishment = math.Inf(1)
+	default:
+		return
+	}
+
+	s.bonus = mutils.ClampF(s.bonus+hitBonus-hitPunishment, 0, 100)
+
+	s.baseScore += s.noteValue * hitValue / 320
+	s.bonusScore += s.noteValue * hitBonusValue * math.Sqrt(s.bonus) / 320
+
+	s.score = int64(math.Round(s.baseScore + s.bonusScore))
+
+	if comboResult == Reset || result == Miss {
+		s.combo = 0
+	} else if comboResult == Increase {
+		s.combo++
+	}
+}
+
+func (s *scoreManiaProcessor) ModifyResult(result HitResult, _ HitObject) HitResult {
+	return result
+}
+
+func (s *scoreManiaProcessor) GetScore() int64 {
+	return s.score
+}
+
+func (s *scoreManiaProcessor) GetCombo() int64 {
+	return s.combo
+}
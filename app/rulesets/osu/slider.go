@@ -146,7 +146,7 @@ func (slider *Slider) UpdateClickFor(player *difficultyPlayer, time int64) bool
 				}
 
 				if hit != Ignore {
-					if len(slider.players) == 1 {
+					if slider.ruleSet.showFeedback(slider.players) {
 						slider.hitSlider.HitEdge(0, float64(time), hit != SliderMiss)
 					}
 
@@ -233,7 +233,7 @@ func (slider *Slider) UpdateFor(player *difficultyPlayer, time int64, processSli
 			state.sliding = true
 			state.slideStart = time
 
-			if len(slider.players) == 1 {
+			if slider.ruleSet.showFeedback(slider.players) {
 				slider.hitSlider.InitSlide(float64(time))
 			}
 		}
@@ -278,7 +278,7 @@ func (slider *Slider) UpdateFor(player *difficultyPlayer, time int64, processSli
 		}
 
 		if !allowable && state.sliding && state.scored+state.missed < len(state.points) {
-			if len(slider.players) == 1 {
+			if slider.ruleSet.showFeedback(slider.players) {
 				slider.hitSlider.KillSlide(float64(time))
 			}
 
@@ -293,7 +293,7 @@ func (slider *Slider) UpdatePostFor(player *difficultyPlayer, time int64, proces
 	state := slider.state[player]
 
 	if time > int64(slider.hitSlider.GetStartTime())+player.diff.Hit50 && !state.isStartHit {
-		if len(slider.players) == 1 {
+		if slider.ruleSet.showFeedback(slider.players) {
 			slider.hitSlider.ArmStart(false, float64(time))
 		}
 
@@ -323,7 +323,7 @@ func (slider *Slider) UpdatePostFor(player *difficultyPlayer, time int64, proces
 
 		rate := float64(state.scored) / float64(len(state.points)+1)
 
-		if rate > 0 && len(slider.players) == 1 {
+		if rate > 0 && slider.ruleSet.showFeedback(slider.players) {
 			slider.hitSlider.HitEdge(len(slider.hitSlider.TickReverse), float64(time), true)
 		}
 
@@ -0,0 +1,627 @@
+package app
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wieku/danser-go/app/database"
+	"github.com/wieku/danser-go/app/events"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/rplpa"
+	"golang.org/x/exp/slices"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobCancelled = "cancelled"
+)
+
+const maxReplaySize = 32 << 20
+
+// overridableSettings are settings which jobs can change by Set. Settings pointing to files on server's disk
+// (directories, skins, scripts, fonts, images) and ffmpeg's options and filters are left out.
+var overridableSettings = []string{
+	"Graphics",
+	"Audio",
+	"Gameplay",
+	"Skin",
+	"Cursor",
+	"Objects",
+	"Playfield",
+	"CursorDance",
+	"Knockout",
+	"Recording.FrameWidth",
+	"Recording.FrameHeight",
+	"Recording.FPS",
+	"Recording.EncodingFPSCap",
+	"Recording.OutputType",
+	"Recording.Alpha",
+	"Recording.Encoder",
+	"Recording.PixelFormat",
+	"Recording.AudioCodec",
+	"Recording.Container",
+	"Recording.SegmentLength",
+	"Recording.Markers",
+	"Recording.ShowFFmpegLogs",
+	"Recording.MotionBlur",
+}
+
+// fileSettings are settings inside overridableSettings which point to files on server's disk.
+// They can't be set directly or as a part of their section or array.
+var fileSettings = []string{
+	"Gameplay.HUDFont",
+	"Gameplay.Underlay.Path",
+	"Skin.CurrentSkin",
+	"Skin.FallbackSkin",
+	"CursorDance.Movers.Script",
+}
+
+var settingsIndexMatcher = regexp.MustCompile(`\[\d+]`)
+
+// serverJobRequest is a job submitted to POST /api/jobs. Replay is an ID returned by POST /api/replays.
+type serverJobRequest struct {
+	Replay string
+	MD5    string
+	ID     *int64
+	Query  string
+
+	Settings string
+	Set      []string
+	Mods     string
+	Start    *float64
+	End      *float64
+	Skin     string
+}
+
+type serverJob struct {
+	ID      int64
+	Status  string
+	Request serverJobRequest
+
+	Stage    string  `json:",omitempty"`
+	Progress float64 // Percent
+	ETA      float64 `json:",omitempty"` // Seconds
+
+	Created  int64 // Unix time in milliseconds
+	Started  int64 `json:",omitempty"`
+	Finished int64 `json:",omitempty"`
+
+	Output string `json:",omitempty"`
+	Error  string `json:",omitempty"`
+
+	cancel context.CancelFunc
+}
+
+type renderServer struct {
+	mutex sync.Mutex
+
+	jobs   map[int64]*serverJob
+	lastID int64
+
+	queue     []*serverJob // Jobs waiting for the worker, cancelled ones are removed
+	queueSize int
+	queueCond *sync.Cond // Signalled when a job is queued
+
+	dExec           string
+	settingsVersion string
+	overrides       []string
+
+	replayDir string
+}
+
+func getReplayUploadDir() string {
+	return filepath.Join(env.DataDir(), "server", "replays")
+}
+
+// runServer starts a local render server. Jobs are recorded one after another, each of them in a separate danser process.
+// Database and ffmpeg's encoders are checked only once, on startup.
+func runServer(address string, queueSize int, noDbCheck bool, settingsVersion string, overrides []string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		panic(fmt.Sprintf("flag -server: %s", err))
+	}
+
+	if host == "" { // Don't expose the server to the network unless it's requested explicitly
+		host = "localhost"
+	}
+
+	if err = database.Init(); err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %s", err))
+	}
+
+	defer database.Close()
+
+	database.LoadBeatmaps(noDbCheck, nil)
+
+	ffmpeg.PreCheck()
+
+	server := &renderServer{
+		jobs:            make(map[int64]*serverJob),
+		queue:           make([]*serverJob, 0, queueSize),
+		queueSize:       queueSize,
+		settingsVersion: settingsVersion,
+		overrides:       overrides,
+		replayDir:       getReplayUploadDir(),
+	}
+
+	server.queueCond = sync.NewCond(&server.mutex)
+
+	server.dExec, err = os.Executable()
+	if err != nil {
+		server.dExec = os.Args[0]
+	}
+
+	if err = os.MkdirAll(server.replayDir, 0755); err != nil {
+		panic(err)
+	}
+
+	server.loadHistory()
+
+	go server.worker()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/replays", server.handleReplays)
+	mux.HandleFunc("/api/jobs", server.handleJobs)
+	mux.HandleFunc("/api/jobs/", server.handleJob)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		panic(fmt.Sprintf("flag -server: %s", err))
+	}
+
+	log.Println("Render server listening on", "http://"+listener.Addr().String())
+
+	if err = http.Serve(listener, mux); err != nil {
+		panic(err)
+	}
+}
+
+// loadHistory loads jobs from the database. Jobs which didn't finish before the server was stopped are marked as failed.
+func (server *renderServer) loadHistory() {
+	history, err := database.LoadRenderJobs()
+	if err != nil {
+		log.Println("Failed to load job history:", err)
+		return
+	}
+
+	for _, r := range history {
+		job := &serverJob{
+			ID:       r.ID,
+			Status:   r.Status,
+			Created:  r.Created,
+			Started:  r.Started,
+			Finished: r.Finished,
+			Output:   r.Output,
+			Error:    r.Error,
+		}
+
+		_ = json.Unmarshal([]byte(r.Request), &job.Request)
+
+		if job.Status == jobQueued || job.Status == jobRunning {
+			job.Status = jobFailed
+			job.Error = "server was stopped"
+			job.Finished = time.Now().UnixMilli()
+
+			server.save(job)
+		}
+
+		server.jobs[job.ID] = job
+		server.lastID = job.ID
+	}
+
+	log.Println(fmt.Sprintf("Loaded %d jobs from history", len(history)))
+}
+
+// save persists the job, has to be called with locked mutex
+func (server *renderServer) save(job *serverJob) {
+	request, _ := json.Marshal(job.Request)
+
+	err := database.SaveRenderJob(database.RenderJob{
+		ID:       job.ID,
+		Status:   job.Status,
+		Request:  string(request),
+		Created:  job.Created,
+		Started:  job.Started,
+		Finished: job.Finished,
+		Output:   job.Output,
+		Error:    job.Error,
+	})
+
+	if err != nil {
+		log.Println(fmt.Sprintf("Failed to save job %d: %s", job.ID, err))
+	}
+}
+
+func (server *renderServer) worker() {
+	for {
+		server.mutex.Lock()
+
+		for len(server.queue) == 0 {
+			server.queueCond.Wait()
+		}
+
+		job := server.queue[0]
+		server.queue = server.queue[1:]
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		job.cancel = cancel
+		job.Status = jobRunning
+		job.Started = time.Now().UnixMilli()
+
+		server.save(job)
+
+		bJob := job.Request.toBatchJob(server.replayDir, job.ID)
+		args := bJob.getArguments(server.settingsVersion, server.overrides)
+
+		server.mutex.Unlock()
+
+		log.Println(fmt.Sprintf("Starting job %d", job.ID))
+
+		var output string
+
+		err := bJob.checkScripts(server.overrides)
+		if err == nil {
+			output, err = runDanserProcess(ctx, server.dExec, args, func(event events.Event) {
+				server.mutex.Lock()
+				defer server.mutex.Unlock()
+
+				switch event.Type {
+				case events.TypeStage:
+					job.Stage = event.Stage
+				case events.TypeProgress:
+					job.Progress = event.Progress
+					job.ETA = event.ETA
+				}
+			})
+		}
+
+		cancel()
+
+		server.mutex.Lock()
+
+		job.cancel = nil
+		job.Finished = time.Now().UnixMilli()
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			job.Status = jobCancelled
+		case err != nil:
+			job.Status = jobFailed
+			job.Error = err.Error()
+		default:
+			job.Status = jobDone
+			job.Progress = 100
+			job.Output = output
+		}
+
+		server.save(job)
+		server.removeReplay(job)
+
+		log.Println(fmt.Sprintf("Job %d %s", job.ID, job.Status))
+
+		server.mutex.Unlock()
+	}
+}
+
+// removeReplay deletes the replay uploaded for the job unless another queued or running job uses it, has to be called with locked mutex
+func (server *renderServer) removeReplay(job *serverJob) {
+	if job.Request.Replay == "" {
+		return
+	}
+
+	for _, other := range server.jobs {
+		if other != job && other.Request.Replay == job.Request.Replay && (other.Status == jobQueued || other.Status == jobRunning) {
+			return
+		}
+	}
+
+	if err := os.Remove(filepath.Join(server.replayDir, job.Request.Replay+".osr")); err != nil && !os.IsNotExist(err) {
+		log.Println(fmt.Sprintf("Failed to remove replay of job %d: %s", job.ID, err))
+	}
+}
+
+func (request *serverJobRequest) toBatchJob(replayDir string, id int64) *batchJob {
+	job := &batchJob{
+		MD5:      request.MD5,
+		ID:       request.ID,
+		Query:    request.Query,
+		Settings: request.Settings,
+		Set:      request.Set,
+		Mods:     request.Mods,
+		Start:    request.Start,
+		End:      request.End,
+		Skin:     request.Skin,
+		Out:      fmt.Sprintf("danser_job_%d", id),
+	}
+
+	if request.Replay != "" {
+		job.Replay = filepath.Join(replayDir, request.Replay+".osr")
+	}
+
+	return job
+}
+
+func (request *serverJobRequest) validate(replayDir string) error {
+	if request.Replay == "" && request.MD5 == "" && request.ID == nil && request.Query == "" {
+		return errors.New("Replay, MD5, ID or Query has to be specified")
+	}
+
+	if request.Replay != "" && (request.MD5 != "" || request.ID != nil || request.Query != "") {
+		return errors.New("Replay can't be used with MD5, ID or Query")
+	}
+
+	if request.Replay != "" {
+		if _, err := hex.DecodeString(request.Replay); err != nil {
+			return fmt.Errorf("invalid replay \"%s\"", request.Replay)
+		}
+
+		if _, err := os.Stat(filepath.Join(replayDir, request.Replay+".osr")); err != nil {
+			return fmt.Errorf("replay \"%s\" was not uploaded", request.Replay)
+		}
+	}
+
+	if request.Settings == "credentials" || request.Settings == "launcher" {
+		return fmt.Errorf("settings name \"%s\" is forbidden", request.Settings)
+	}
+
+	if strings.Contains(request.Settings, "..") || strings.Contains(request.Skin, "..") {
+		return errors.New("Settings and Skin can't point outside of their directories")
+	}
+
+	for _, o := range request.Set {
+		path, _, _ := strings.Cut(o, "=")
+
+		if !isOverridable(path) {
+			return fmt.Errorf("setting \"%s\" can't be overridden", strings.TrimSpace(path))
+		}
+	}
+
+	// Importing would read files from server's disk
+	if skin.IsArchive(request.Skin) {
+		return errors.New("Skin has to be a name of an installed skin")
+	}
+
+	return nil
+}
+
+// isOverridable checks whether the setting at JSON path (like Cursor.CursorSize or CursorDance.Movers[0].Mover) can be set by jobs
+func isOverridable(path string) bool {
+	path = strings.ToLower(settingsIndexMatcher.ReplaceAllString(strings.TrimSpace(path), ""))
+
+	matches := func(prefixes []string) bool {
+		for _, prefix := range prefixes {
+			prefix = strings.ToLower(prefix)
+
+			if path == prefix || strings.HasPrefix(path, prefix+".") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !matches(overridableSettings) || matches(fileSettings) {
+		return false
+	}
+
+	// Sections and arrays containing file settings can't be set as a whole either
+	for _, file := range fileSettings {
+		if strings.HasPrefix(strings.ToLower(file), path+".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// handleReplays handles POST /api/replays, body is the .osr file
+func (server *renderServer) handleReplays(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "only POST is allowed")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReplaySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	replay, err := rplpa.ParseReplay(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid replay: %s", err))
+		return
+	}
+
+	if replay.PlayMode != rplpa.OSU && replay.PlayMode != rplpa.MANIA {
+		writeError(w, http.StatusBadRequest, "modes other than osu!standard and osu!mania are not supported")
+		return
+	}
+
+	hash := sha1.Sum(data)
+	id := hex.EncodeToString(hash[:])
+
+	if err = os.WriteFile(filepath.Join(server.replayDir, id+".osr"), data, 0644); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, struct {
+		ID         string
+		BeatmapMD5 string
+		Player     string
+		Score      int32
+	}{id, replay.BeatmapMD5, replay.Username, replay.Score})
+}
+
+// handleJobs handles GET /api/jobs (history) and POST /api/jobs (submission)
+func (server *renderServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		server.mutex.Lock()
+
+		jobs := make([]serverJob, 0, len(server.jobs))
+		for _, job := range server.jobs {
+			jobs = append(jobs, *job)
+		}
+
+		server.mutex.Unlock()
+
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].ID > jobs[j].ID
+		})
+
+		writeJSON(w, http.StatusOK, jobs)
+	case http.MethodPost:
+		var request serverJobRequest
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Replay is checked with locked mutex, so it can't be removed by a finished job in the meantime
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		if err := request.validate(server.replayDir); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		job := &serverJob{
+			ID:      server.lastID + 1,
+			Status:  jobQueued,
+			Request: request,
+			Created: time.Now().UnixMilli(),
+		}
+
+		if len(server.queue) >= server.queueSize {
+			writeError(w, http.StatusServiceUnavailable, "job queue is full")
+			return
+		}
+
+		server.queue = append(server.queue, job)
+		server.queueCond.Signal()
+
+		server.lastID = job.ID
+		server.jobs[job.ID] = job
+
+		server.save(job)
+
+		writeJSON(w, http.StatusCreated, job)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "only GET and POST are allowed")
+	}
+}
+
+// handleJob handles GET /api/jobs/{id}, DELETE /api/jobs/{id} (cancellation) and GET /api/jobs/{id}/video
+func (server *renderServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/"), "/")
+
+	id, err := strconv.ParseInt(path[0], 10, 64)
+	if err != nil || len(path) > 2 || (len(path) == 2 && path[1] != "video") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	server.mutex.Lock()
+
+	job, ok := server.jobs[id]
+	if !ok {
+		server.mutex.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Sprintf("job %d doesn't exist", id))
+		return
+	}
+
+	if len(path) == 2 {
+		status, output := job.Status, job.Output
+
+		server.mutex.Unlock()
+
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "only GET is allowed")
+			return
+		}
+
+		if status != jobDone {
+			writeError(w, http.StatusConflict, fmt.Sprintf("job %d is %s", id, status))
+			return
+		}
+
+		if stat, err := os.Stat(output); err == nil && stat.IsDir() {
+			writeError(w, http.StatusConflict, fmt.Sprintf("job %d was recorded as an image sequence to %s", id, output))
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filepath.Base(output)))
+
+		http.ServeFile(w, r, output)
+
+		return
+	}
+
+	defer server.mutex.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, job)
+	case http.MethodDelete:
+		switch job.Status {
+		case jobQueued:
+			if i := slices.Index(server.queue, job); i >= 0 {
+				server.queue = slices.Delete(server.queue, i, i+1)
+			}
+
+			job.Status = jobCancelled
+			job.Finished = time.Now().UnixMilli()
+
+			server.save(job)
+			server.removeReplay(job)
+		case jobRunning:
+			if job.cancel != nil {
+				job.cancel()
+			}
+		default:
+			writeError(w, http.StatusConflict, fmt.Sprintf("job %d is %s", id, job.Status))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "only GET and DELETE are allowed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct{ Error string }{message})
+}
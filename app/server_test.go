@@ -0,0 +1,35 @@
+package app
+
+import "testing"
+
+func TestIsOverridable(t *testing.T) {
+	cases := []struct {
+		path     string
+		expected bool
+	}{
+		{"Cursor.CursorSize", true},
+		{"cursor.cursorsize", true},
+		{"CursorDance.Movers[0].Mover", true},
+		{"Recording.FPS", true},
+		{"Recording.MotionBlur.Enabled", true},
+		{"Recording.OutputDir", false},
+		{"Recording", false},
+		{"General.OsuSongsDir", false},
+		{"Skin", false},
+		{"Skin.CurrentSkin", false},
+		{"Skin.Cursor.UseSkinCursor", true},
+		{"Gameplay", false},
+		{"Gameplay.HUDFont", false},
+		{"Gameplay.Underlay", false},
+		{"CursorDance", false},
+		{"CursorDance.Movers", false},
+		{"CursorDance.Movers[0]", false},
+		{"CursorDance.Movers[0].Script", false},
+	}
+
+	for _, c := range cases {
+		if actual := isOverridable(c.path); actual != c.expected {
+			t.Errorf("isOverridable(%q) = %t, expected %t", c.path, actual, c.expected)
+		}
+	}
+}
@@ -1,7 +1,129 @@
 package settings
 
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
 var CursorDance = initCursorDance()
 
+var moverOptions []string
+var spinnerMoverOptions []string
+
+// moverSettingsEntry describes an array in CursorDance.MoverSettings created by RegisterMoverWithSettings
+type moverSettingsEntry struct {
+	key     string
+	typ     reflect.Type
+	factory func() reflect.Value
+}
+
+var moverSettingsEntries []moverSettingsEntry
+
+var moverSettingsType reflect.Type
+
+// MoverSettings gives typed access to the settings array of a mover registered with RegisterMoverWithSettings
+type MoverSettings[T any] struct {
+	key string
+}
+
+// Get returns settings for mover with given id, ids above the array size wrap around
+func (s *MoverSettings[T]) Get(id int) T {
+	array := moverSettingsArray(CursorDance.MoverSettings, s.key)
+
+	if !array.IsValid() { // Mover was registered after settings were loaded
+		CursorDance.updateMoverSettings()
+		array = moverSettingsArray(CursorDance.MoverSettings, s.key)
+	}
+
+	return array.Index(id % array.Len()).Interface().(T)
+}
+
+// RegisterMover makes the mover without settings selectable in Movers
+func RegisterMover(name string) {
+	moverOptions = append(moverOptions, name)
+}
+
+// RegisterMoverWithSettings makes the mover selectable in Movers and adds an array under key to CursorDance.MoverSettings,
+// factory creates its default element. Returned handle is used by the mover to read its settings.
+func RegisterMoverWithSettings[T any](name, key string, factory func() T) *MoverSettings[T] {
+	for _, entry := range moverSettingsEntries {
+		if entry.key == key {
+			panic(fmt.Sprintf("SettingsManager: mover settings \"%s\" are already registered", key))
+		}
+	}
+
+	RegisterMover(name)
+
+	moverSettingsEntries = append(moverSettingsEntries, moverSettingsEntry{
+		key: key,
+		typ: reflect.TypeOf((*T)(nil)).Elem(),
+		factory: func() reflect.Value {
+			return reflect.ValueOf(factory())
+		},
+	})
+
+	moverSettingsType = nil
+
+	return &MoverSettings[T]{key: key}
+}
+
+// RegisterSpinnerMover makes the spinner mover selectable in Spinners
+func RegisterSpinnerMover(name string) {
+	spinnerMoverOptions = append(spinnerMoverOptions, name)
+}
+
+func (d *defaultsFactory) MoverOptions() []string {
+	return moverOptions
+}
+
+func (d *defaultsFactory) SpinnerMoverOptions() []string {
+	return spinnerMoverOptions
+}
+
+// NewArrayElement creates a new element of settings array using its "new" tag
+func NewArrayElement(newTag string) reflect.Value {
+	if strings.HasPrefix(newTag, moverSettingsTag) {
+		key := strings.TrimPrefix(newTag, moverSettingsTag)
+
+		for _, entry := range moverSettingsEntries {
+			if entry.key == key {
+				return entry.factory()
+			}
+		}
+	}
+
+	return reflect.ValueOf(DefaultsFactory).MethodByName(newTag).Call(nil)[0]
+}
+
+const moverSettingsTag = "MoverSettings:"
+
+// getMoverSettingsType builds the struct with an array for every mover registered with RegisterMoverWithSettings
+func getMoverSettingsType() reflect.Type {
+	if moverSettingsType != nil {
+		return moverSettingsType
+	}
+
+	fields := make([]reflect.StructField, 0, len(moverSettingsEntries))
+
+	for _, entry := range moverSettingsEntries {
+		fields = append(fields, reflect.StructField{
+			Name: entry.key,
+			Type: reflect.SliceOf(entry.typ),
+			Tag:  reflect.StructTag(fmt.Sprintf(`new:"%s%s"`, moverSettingsTag, entry.key)),
+		})
+	}
+
+	moverSettingsType = reflect.StructOf(fields)
+
+	return moverSettingsType
+}
+
+func moverSettingsArray(moverSettings any, key string) reflect.Value {
+	return reflect.ValueOf(moverSettings).Elem().FieldByName(key)
+}
+
 func initCursorDance() *cursorDance {
 	return &cursorDance{
 		Movers: []*mover{
@@ -14,37 +136,13 @@ func initCursorDance() *cursorDance {
 		Battle:             false,
 		DoSpinnersTogether: true,
 		TAGSliderDance:     false,
-		MoverSettings: &moverSettings{
-			Bezier: []*bezier{
-				DefaultsFactory.InitBezier(),
-			},
-			Flower: []*flower{
-				DefaultsFactory.InitFlower(),
-			},
-			HalfCircle: []*circular{
-				DefaultsFactory.InitCircular(),
-			},
-			Spline: []*spline{
-				DefaultsFactory.InitSpline(),
-			},
-			Momentum: []*momentum{
-				DefaultsFactory.InitMomentum(),
-			},
-			ExGon: []*exgon{
-				DefaultsFactory.InitExGon(),
-			},
-			Linear: []*linear{
-				DefaultsFactory.InitLinear(),
-			},
-			Pippi: []*pippi{
-				DefaultsFactory.InitPippi(),
-			},
-		},
+		MoverSettings:      newMoverSettings(),
 	}
 }
 
 type mover struct {
-	Mover             string `combo:"spline,bezier,circular,linear,axis,aggressive,flower,momentum,exgon,pippi"`
+	Mover             string `combo:"true" comboSrc:"MoverOptions"`
+	Script            string `file:"Select mover script" filter:"Mover script (*.txt)|txt" showif:"Mover=scripted" tooltip:"Text file with expressions defining cursor's x and y between objects" liveedit:"false"`
 	SliderDance       bool
 	RandomSliderDance bool
 }
@@ -58,7 +156,7 @@ func (d *defaultsFactory) InitMover() *mover {
 }
 
 type spinner struct {
-	Mover         string  `combo:"heart,triangle,square,cube,circle"`
+	Mover         string  `combo:"true" comboSrc:"SpinnerMoverOptions"`
 	centerOffset  string  `vector:"true" left:"CenterOffsetX" right:"CenterOffsetY"`
 	CenterOffsetX float64 `min:"-1000" max:"1000"`
 	CenterOffsetY float64 `min:"-1000" max:"1000"`
@@ -79,16 +177,67 @@ type cursorDance struct {
 	Battle             bool       `liveedit:"false"`
 	DoSpinnersTogether bool       `liveedit:"false"`
 	TAGSliderDance     bool       `label:"TAG slider dance" liveedit:"false"`
-	MoverSettings      *moverSettings
-}
-
-type moverSettings struct {
-	Bezier     []*bezier   `new:"InitBezier"`
-	Flower     []*flower   `new:"InitFlower"`
-	HalfCircle []*circular `new:"InitCircular"`
-	Spline     []*spline   `new:"InitSpline"`
-	Momentum   []*momentum `new:"InitMomentum"`
-	ExGon      []*exgon    `new:"InitExGon"`
-	Linear     []*linear   `new:"InitLinear"`
-	Pippi      []*pippi    `new:"InitPippi"`
+	MoverSettings      any        // Created by newMoverSettings from registered movers
+}
+
+// GetScripts returns script paths of scripted entries in Movers, in order
+func (cursorDance *cursorDance) GetScripts() []string {
+	scripts := make([]string, 0)
+
+	for _, m := range cursorDance.Movers {
+		if strings.ToLower(m.Mover) == "scripted" {
+			scripts = append(scripts, m.Script)
+		}
+	}
+
+	return scripts
+}
+
+// newMoverSettings returns a pointer to the struct created by getMoverSettingsType, filled with default settings
+func newMoverSettings() any {
+	moverSettings := reflect.New(getMoverSettingsType()).Interface()
+	fillMissingMoverSettings(moverSettings)
+
+	return moverSettings
+}
+
+// fillMissingMoverSettings adds default settings to arrays of registered movers that are empty, so movers can safely index them
+func fillMissingMoverSettings(moverSettings any) {
+	value := reflect.ValueOf(moverSettings).Elem()
+
+	for _, entry := range moverSettingsEntries {
+		if field := value.FieldByName(entry.key); field.Len() == 0 {
+			field.Set(reflect.Append(field, entry.factory()))
+		}
+	}
+}
+
+// updateMoverSettings makes sure MoverSettings has arrays of all registered movers, it's needed if movers were
+// registered after settings were created
+func (cursorDance *cursorDance) updateMoverSettings() {
+	if cursorDance.MoverSettings == nil || reflect.TypeOf(cursorDance.MoverSettings).Elem() != getMoverSettingsType() {
+		moverSettings := reflect.New(getMoverSettingsType()).Interface()
+
+		if cursorDance.MoverSettings != nil {
+			if data, err := json.Marshal(cursorDance.MoverSettings); err == nil {
+				_ = json.Unmarshal(data, moverSettings)
+			}
+		}
+
+		cursorDance.MoverSettings = moverSettings
+	}
+
+	fillMissingMoverSettings(cursorDance.MoverSettings)
+}
+
+// setMoverSettings replaces the array under key with a single element, it's used to migrate old settings
+func (cursorDance *cursorDance) setMoverSettings(key string, value any) {
+	cursorDance.updateMoverSettings()
+
+	array := moverSettingsArray(cursorDance.MoverSettings, key)
+	if !array.IsValid() {
+		return
+	}
+
+	array.Set(reflect.Append(array.Slice(0, 0), reflect.ValueOf(value)))
 }
@@ -0,0 +1,283 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/danser-go/framework/files"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var indexMatcher = regexp.MustCompile(`\[\d+]$`)
+
+type configHeader struct {
+	Extends string
+}
+
+// getBasePath returns the path of settings file given in Extends, names are resolved the same way as -settings
+func getBasePath(name string) string {
+	return filepath.Join(env.ConfigDir(), strings.TrimSuffix(name, ".json")+".json")
+}
+
+// readConfigData reads settings file and fixes backslashes so JSON can parse them
+func readConfigData(file *os.File) (data, fixed []byte, err error) {
+	data, err = io.ReadAll(files.NewUnicodeReader(file))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, fixBackslashes(data), nil
+}
+
+func fixBackslashes(data []byte) []byte {
+	// I hope it won't backfire, replacing \ or \\\\\\\ with \\ so JSON can parse it as \
+
+	str := regexp.MustCompile(`\\+`).ReplaceAllString(string(data), `\`)
+	str = strings.ReplaceAll(str, `\`, `\\`)
+
+	return []byte(str)
+}
+
+// loadBaseChain loads settings files referenced by Extends, the top-most base is first
+func loadBaseChain(name string, visited []string) (chain [][]byte, paths []string, err error) {
+	path := getBasePath(name)
+
+	abs, _ := filepath.Abs(path)
+
+	for _, v := range visited {
+		if v == abs {
+			return nil, nil, fmt.Errorf("SettingsManager: Circular Extends: %s -> %s", strings.Join(visited, " -> "), abs)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SettingsManager: Failed to load base settings \"%s\"! Error: %s", name, err)
+	}
+
+	_, data, err := readConfigData(file)
+
+	file.Close()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("SettingsManager: Failed to load base settings \"%s\"! Error: %s", name, err)
+	}
+
+	var header configHeader
+
+	if err = json.Unmarshal(data, &header); err != nil {
+		return nil, nil, fmt.Errorf("SettingsManager: Failed to parse %s! Please re-check the file for mistakes. Error: %s", path, err)
+	}
+
+	if header.Extends != "" {
+		chain, paths, err = loadBaseChain(header.Extends, append(visited, abs))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return append(chain, data), append(paths, path), nil
+}
+
+// parseConfig merges settings files on top of the defaults, later files override earlier ones
+func parseConfig(name string, chain ...[]byte) (*Config, error) {
+	config := NewConfigFile()
+
+	config.General.OsuReplaysDir = "" // Clear Replay path, so we can migrate it from Songs if JSON misses it
+
+	for _, data := range chain {
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("SettingsManager: Failed to parse %s! Please re-check the file for mistakes. Error: %s", name, err)
+		}
+	}
+
+	config.migrateCursorDance()
+	config.migrateHitCounterColors()
+	config.migrateBlendWeights()
+
+	config.CursorDance.updateMoverSettings()
+
+	if config.General.OsuReplaysDir == "" { // Set the replay directory if it hasn't been loaded
+		config.General.OsuReplaysDir = filepath.Join(filepath.Dir(config.General.OsuSongsDir), "Replays")
+	}
+
+	return config, nil
+}
+
+// marshalOverrides creates JSON with only the values that differ from the base settings
+func (config *Config) marshalOverrides() ([]byte, error) {
+	base, err := parseConfig(config.Extends, config.baseData...)
+	if err != nil {
+		return nil, err
+	}
+
+	base.Extends = ""
+
+	full, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	baseData, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, _, err := diffJSON(full, baseData)
+	if err != nil {
+		return nil, err
+	}
+
+	config.overrides = nil
+	config.baseValues = nil
+
+	_ = json.Unmarshal(diff, &config.overrides)
+	_ = json.Unmarshal(baseData, &config.baseValues)
+
+	buf := new(bytes.Buffer)
+
+	if err = json.Indent(buf, diff, "", "\t"); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// diffJSON returns members of value's objects which are missing or different in base, keeping value's key order.
+// Arrays are compared as a whole.
+func diffJSON(value, base json.RawMessage) (json.RawMessage, bool, error) {
+	value = bytes.TrimSpace(value)
+	base = bytes.TrimSpace(base)
+
+	if len(value) == 0 || len(base) == 0 || value[0] != '{' || base[0] != '{' {
+		return value, !bytes.Equal(value, base), nil
+	}
+
+	keys, values, err := decodeObject(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, baseValues, err := decodeObject(base)
+	if err != nil {
+		return nil, false, err
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+
+	changed := false
+
+	for _, key := range keys {
+		diff := values[key]
+
+		if baseValue, ok := baseValues[key]; ok {
+			var keyChanged bool
+
+			diff, keyChanged, err = diffJSON(diff, baseValue)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if !keyChanged {
+				continue
+			}
+		}
+
+		if changed {
+			buf.WriteByte(',')
+		}
+
+		keyData, _ := json.Marshal(key)
+
+		buf.Write(keyData)
+		buf.WriteByte(':')
+		buf.Write(diff)
+
+		changed = true
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), changed, nil
+}
+
+func decodeObject(data json.RawMessage) (keys []string, values map[string]json.RawMessage, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	if _, err = decoder.Token(); err != nil { // opening brace
+		return
+	}
+
+	values = make(map[string]json.RawMessage)
+
+	for decoder.More() {
+		var token json.Token
+
+		if token, err = decoder.Token(); err != nil {
+			return
+		}
+
+		key := token.(string)
+
+		var value json.RawMessage
+
+		if err = decoder.Decode(&value); err != nil {
+			return
+		}
+
+		keys = append(keys, key)
+		values[key] = value
+	}
+
+	return
+}
+
+// IsInherited checks whether the value at JSON path (like General.OsuSongsDir or CursorDance.Movers[1].Mover) comes from
+// the settings given in Extends. It reflects the state from the last load or save.
+func (config *Config) IsInherited(path string) bool {
+	if config.Extends == "" || config.baseValues == nil {
+		return false
+	}
+
+	overrides := config.overrides
+	base := config.baseValues
+
+	for _, part := range strings.Split(path, ".") {
+		indexed := indexMatcher.MatchString(part)
+		key := indexMatcher.ReplaceAllString(part, "")
+
+		baseValue, ok := base[key]
+		if !ok { // Not a part of settings file
+			return false
+		}
+
+		value, ok := overrides[key]
+		if !ok {
+			return true
+		}
+
+		if indexed { // Arrays are always overridden as a whole
+			return false
+		}
+
+		base, _ = baseValue.(map[string]any)
+		overrides, _ = value.(map[string]any)
+
+		if base == nil || overrides == nil {
+			return false
+		}
+	}
+
+	return false
+}
+
+// GetBasePaths returns paths of settings files this one inherits from
+func (config *Config) GetBasePaths() []string {
+	return config.basePaths
+}
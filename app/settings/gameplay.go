@@ -2,6 +2,22 @@ package settings
 
 var Gameplay = initGameplay()
 
+var ppVersionOptions = []string{"latest|Latest"}
+
+// RegisterPPVersion makes pp calculator version (in yyyyMMdd format) selectable in PPVersion
+func RegisterPPVersion(version string) {
+	label := version
+	if len(version) == 8 {
+		label = version[:4] + "-" + version[4:6] + "-" + version[6:]
+	}
+
+	ppVersionOptions = append(ppVersionOptions, version+"|"+label)
+}
+
+func (d *defaultsFactory) PPVersionOptions() []string {
+	return ppVersionOptions
+}
+
 func initGameplay() *gameplay {
 	return &gameplay{
 		HitErrorMeter: &hitError{
@@ -168,6 +184,18 @@ func initGameplay() *gameplay {
 				InnerOpacity:  0.5,
 			},
 		},
+		IntegrityReport: &integrityReport{
+			hudElementPosition: &hudElementPosition{
+				hudElement: &hudElement{
+					Show:    false,
+					Scale:   1.0,
+					Opacity: 1.0,
+				},
+				XPosition: 5,
+				YPosition: 330,
+			},
+			Align: "TopLeft",
+		},
 		KeyOverlay: &hudElementOffset{
 			hudElement: &hudElement{
 				Show:    true,
@@ -234,10 +262,12 @@ func initGameplay() *gameplay {
 		ResultsUseLocalTimeZone: false,
 		ShowWarningArrows:       true,
 		ShowHitLighting:         false,
+		ShowComboBursts:         true,
 		FlashlightDim:           1,
 		PlayUsername:            "Guest",
 		IgnoreFailsInReplays:    false,
 		UseLazerPP:              false,
+		PPVersion:               "latest",
 	}
 }
 
@@ -250,6 +280,7 @@ type gameplay struct {
 	PPCounter               *ppCounter
 	HitCounter              *hitCounter
 	StrainGraph             *strainGraph
+	IntegrityReport         *integrityReport
 	KeyOverlay              *hudElementOffset
 	ScoreBoard              *scoreBoard
 	Mods                    *mods
@@ -261,10 +292,12 @@ type gameplay struct {
 	ResultsUseLocalTimeZone bool    `label:"Show PC's time zone instead of UTC"`
 	ShowWarningArrows       bool
 	ShowHitLighting         bool
+	ShowComboBursts         bool
 	FlashlightDim           float64
 	PlayUsername            string `liveedit:"false"`
 	IgnoreFailsInReplays    bool
-	UseLazerPP              bool `liveedit:"false" skip:"true"`
+	UseLazerPP              bool   `liveedit:"false" skip:"true"`
+	PPVersion               string `label:"PP calculator version" combo:"true" comboSrc:"PPVersionOptions" tooltip:"Older versions can be used to reproduce historical star ratings and pp values" liveedit:"false"`
 }
 
 type boundaries struct {
@@ -401,6 +434,11 @@ type strainGraph struct {
 	Outline *outline
 }
 
+type integrityReport struct {
+	*hudElementPosition
+	Align string `combo:"TopLeft,Top,TopRight,Left,Centre,Right,BottomLeft,Bottom,BottomRight"`
+}
+
 type outline struct {
 	Show          bool
 	Width         float64 `min:"1" max:"5"`
@@ -59,6 +59,8 @@ func LoadSettings(version string) bool {
 		currentConfig.Save("", false) // this is done to save additions from the current format
 	}
 
+	runOverrides = nil // Overrides of a previous batch job don't apply to these settings
+
 	LoadCredentials()
 
 	currentConfig.attachToGlobals()
@@ -70,6 +72,37 @@ func LoadSettings(version string) bool {
 	return newFile
 }
 
+// ReadSettings loads settings with given overrides without making them current, missing file gives default settings
+func ReadSettings(version string, overrides []string) (*Config, error) {
+	fileName := "default"
+	if version != "" {
+		fileName = version
+	}
+
+	config := NewConfigFile()
+
+	file, err := os.Open(filepath.Join(env.ConfigDir(), fileName+".json"))
+	if err == nil {
+		config, err = LoadConfig(file)
+
+		file.Close()
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range overrides {
+		if err = config.applyOverride(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
 func setupWatcher(file string) {
 	var err error
 
@@ -87,11 +120,11 @@ func setupWatcher(file string) {
 				}
 
 				if event.Op&fsnotify.Write == fsnotify.Write {
-					log.Println("SettingsManager: Detected", file, "modification, reloading...")
+					log.Println("SettingsManager: Detected", event.Name, "modification, reloading...")
 
 					time.Sleep(time.Millisecond * 200)
 
-					sFile, _ := os.Open(event.Name)
+					sFile, _ := os.Open(file) // Base settings may have been modified so the main file is always reloaded
 
 					currentConfig, err = LoadConfig(sFile)
 					if err != nil {
@@ -101,6 +134,7 @@ func setupWatcher(file string) {
 					sFile.Close()
 
 					currentConfig.Save("", false)
+					currentConfig.applyOverrides()
 					currentConfig.attachToGlobals()
 				}
 			case err, ok := <-watcher.Errors:
@@ -113,11 +147,13 @@ func setupWatcher(file string) {
 		}
 	})
 
-	abs, _ := filepath.Abs(file)
+	for _, path := range append([]string{file}, currentConfig.GetBasePaths()...) {
+		abs, _ := filepath.Abs(path)
 
-	err = watcher.Add(abs)
-	if err != nil {
-		log.Fatal(err)
+		err = watcher.Add(abs)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 }
 
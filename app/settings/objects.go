@@ -95,6 +95,11 @@ func initObjects() *objects {
 				},
 			},
 		},
+		Mania: &mania{
+			ScrollTime:   600,
+			ColumnAlpha:  0.8,
+			HighlightKey: true,
+		},
 	}
 }
 
@@ -107,6 +112,13 @@ type objects struct {
 	StackEnabled        bool `label:"Enable stack leniency" liveedit:"false"` //true, stack leniency
 	Sliders             *sliders
 	Colors              *objectColors
+	Mania               *mania `label:"osu!mania"`
+}
+
+type mania struct {
+	ScrollTime   float64 `min:"100" max:"5000" format:"%.0fms" tooltip:"How long a note is visible before reaching the judgement line"`
+	ColumnAlpha  float64 `label:"Stage opacity" scale:"100" format:"%.0f%%"`
+	HighlightKey bool    `label:"Highlight pressed columns" tooltip:"Works only with a single player"`
 }
 
 type sliders struct {
@@ -0,0 +1,415 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var pathPartMatcher = regexp.MustCompile(`^([^\[\]]+)((?:\[\d+])*)$`)
+
+// Overrides given by -set, they are applied again when settings file is reloaded
+var runOverrides []string
+
+// SetOverrides applies values in form of "Section.Field=value" to the current settings for this run only.
+// Paths are the same as JSON paths shown in the launcher's settings editor, array elements are accessed with [index].
+func SetOverrides(values []string) error {
+	for _, v := range values {
+		if err := currentConfig.applyOverride(v); err != nil {
+			return err
+		}
+	}
+
+	runOverrides = append(runOverrides, values...)
+
+	return nil
+}
+
+func (config *Config) applyOverrides() {
+	for _, v := range runOverrides {
+		if err := config.applyOverride(v); err != nil { // Values were validated before so it can only fail if file changed
+			log.Println("SettingsManager: Failed to apply override:", err)
+		}
+	}
+}
+
+// appliedOverride keeps JSON values from before and after the override, so overrides can be left out when settings are saved
+type appliedOverride struct {
+	path     string
+	original []byte
+	value    []byte
+}
+
+func (config *Config) applyOverride(override string) error {
+	path, value, ok := strings.Cut(override, "=")
+	if !ok {
+		return fmt.Errorf("\"%s\" has to be in form of Section.Field=value", override)
+	}
+
+	path = strings.TrimSpace(path)
+
+	field, desc, err := config.findValue(path)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	original, err := json.Marshal(field.Interface())
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	if err = setValue(field, desc, value); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	newValue, _ := json.Marshal(field.Interface())
+
+	config.applied = append(config.applied, appliedOverride{
+		path:     path,
+		original: original,
+		value:    newValue,
+	})
+
+	return nil
+}
+
+// revertOverrides temporarily restores values from before overrides were applied
+func (config *Config) revertOverrides() {
+	for i := len(config.applied) - 1; i >= 0; i-- {
+		config.setJSON(config.applied[i].path, config.applied[i].original)
+	}
+}
+
+func (config *Config) reapplyOverrides() {
+	for _, o := range config.applied {
+		config.setJSON(o.path, o.value)
+	}
+}
+
+func (config *Config) setJSON(path string, data []byte) {
+	field, _, err := config.findValue(path)
+	if err != nil {
+		panic(err)
+	}
+
+	if field.Kind() == reflect.Interface && !field.IsNil() { // Structs created at runtime, like CursorDance.MoverSettings
+		if err = json.Unmarshal(data, field.Interface()); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	value := reflect.New(field.Type())
+
+	if err = json.Unmarshal(data, value.Interface()); err != nil {
+		panic(err)
+	}
+
+	field.Set(value.Elem())
+}
+
+// findValue finds the field by its JSON path, e.g. Cursor.CursorSize or CursorDance.Movers[0].Mover
+func (config *Config) findValue(path string) (reflect.Value, reflect.StructField, error) {
+	current := reflect.ValueOf(config).Elem()
+
+	var desc reflect.StructField
+
+	for _, part := range strings.Split(path, ".") {
+		matches := pathPartMatcher.FindStringSubmatch(part)
+		if matches == nil {
+			return reflect.Value{}, desc, fmt.Errorf("invalid path element \"%s\"", part)
+		}
+
+		for current.Kind() == reflect.Ptr || current.Kind() == reflect.Interface {
+			if current.IsNil() {
+				return reflect.Value{}, desc, fmt.Errorf("\"%s\" is not set", part)
+			}
+
+			current = current.Elem()
+		}
+
+		if current.Kind() != reflect.Struct {
+			return reflect.Value{}, desc, fmt.Errorf("\"%s\" is not a section", part)
+		}
+
+		var ok bool
+
+		current, desc, ok = findField(current, matches[1])
+		if !ok {
+			return reflect.Value{}, desc, fmt.Errorf("unknown setting \"%s\"", matches[1])
+		}
+
+		if matches[2] == "" {
+			continue
+		}
+
+		for _, idx := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(matches[2], "["), "]"), "][") {
+			if current.Kind() != reflect.Slice {
+				return reflect.Value{}, desc, fmt.Errorf("\"%s\" is not an array", matches[1])
+			}
+
+			i, _ := strconv.Atoi(idx)
+
+			if i >= current.Len() {
+				return reflect.Value{}, desc, fmt.Errorf("index %d is out of bounds of \"%s\" (length %d)", i, matches[1], current.Len())
+			}
+
+			current = current.Index(i)
+		}
+	}
+
+	return current, desc, nil
+}
+
+// findField finds exported field by its JSON name, fields of embedded structs are treated as if they were in the parent struct
+func findField(u reflect.Value, name string) (reflect.Value, reflect.StructField, bool) {
+	typ := u.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		v := u.Field(i)
+
+		if f.Anonymous {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					continue
+				}
+
+				v = v.Elem()
+			}
+
+			if v.Kind() == reflect.Struct {
+				if found, desc, ok := findField(v, name); ok {
+					return found, desc, true
+				}
+			}
+
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		jsonName := f.Name
+
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+
+			if sp := strings.Split(tag, ",")[0]; sp != "" {
+				jsonName = sp
+			}
+		}
+
+		if strings.EqualFold(jsonName, name) {
+			return v, f, true
+		}
+	}
+
+	return reflect.Value{}, reflect.StructField{}, false
+}
+
+// setValue parses and validates the value using min, max, combo and comboSrc tags
+func setValue(field reflect.Value, desc reflect.StructField, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		options, ok := getStringOptions(desc)
+		if !ok {
+			field.SetString(value)
+			return nil
+		}
+
+		for _, o := range options {
+			if strings.EqualFold(o, value) {
+				field.SetString(o)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("invalid value \"%s\", available values: %s", value, strings.Join(options, ", "))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value \"%s\", expected true or false", value)
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value \"%s\", expected an integer", value)
+		}
+
+		if err = validateInt(desc, i); err != nil {
+			return err
+		}
+
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(value), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value \"%s\", expected a non-negative integer", value)
+		}
+
+		if err = validateInt(desc, int64(u)); err != nil {
+			return err
+		}
+
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value \"%s\", expected a number", value)
+		}
+
+		if err = validateRange(desc, f); err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	default: // Sections, arrays and colors are given as JSON, e.g. Cursor.Colors.BaseColor={"Hue":100,"Saturation":1,"Value":1}
+		target := reflect.New(field.Type())
+		target.Elem().Set(field)
+
+		if err := json.Unmarshal([]byte(value), target.Interface()); err != nil {
+			return fmt.Errorf("invalid JSON value \"%s\": %s", value, err)
+		}
+
+		if err := validateValue(target.Elem(), desc, ""); err != nil {
+			return err
+		}
+
+		field.Set(target.Elem())
+	}
+
+	return nil
+}
+
+// validateValue checks numbers unmarshalled from JSON with the same min, max and combo tags as setValue, walking sections and arrays.
+// Strings are not checked, options of some of them (like movers) are known only after settings are loaded.
+func validateValue(value reflect.Value, desc reflect.StructField, path string) error {
+	var err error
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		return validateValue(value.Elem(), desc, path)
+	case reflect.Struct:
+		typ := value.Type()
+
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				continue
+			}
+
+			fieldPath := path // Fields of embedded structs are treated as if they were in the parent struct
+			if !f.Anonymous {
+				fieldPath = strings.TrimPrefix(path+"."+f.Name, ".")
+			}
+
+			if err = validateValue(value.Field(i), f, fieldPath); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err = validateValue(value.Index(i), desc, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		err = validateInt(desc, value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		err = validateInt(desc, int64(value.Uint()))
+	case reflect.Float32, reflect.Float64:
+		err = validateRange(desc, value.Float())
+	}
+
+	if err != nil && path != "" {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	return err
+}
+
+func getStringOptions(desc reflect.StructField) ([]string, bool) {
+	var options []string
+
+	if cFunc, ok := desc.Tag.Lookup("comboSrc"); ok {
+		options = reflect.ValueOf(DefaultsFactory).MethodByName(cFunc).Call(nil)[0].Interface().([]string)
+	} else if cSpec, ok := desc.Tag.Lookup("combo"); ok {
+		options = strings.Split(cSpec, ",")
+	} else {
+		return nil, false
+	}
+
+	values := make([]string, 0, len(options))
+
+	for _, o := range options {
+		values = append(values, strings.Split(o, "|")[0])
+	}
+
+	return values, true
+}
+
+func validateInt(desc reflect.StructField, value int64) error {
+	cSpec, ok := desc.Tag.Lookup("combo")
+	if !ok {
+		return validateRange(desc, float64(value))
+	}
+
+	var values []string
+
+	hasCustom := false
+
+	for _, s := range strings.Split(cSpec, ",") {
+		if s == "custom" {
+			hasCustom = true
+			continue
+		}
+
+		if c, _ := strconv.ParseInt(strings.Split(s, "|")[0], 10, 64); c == value {
+			return nil
+		}
+
+		values = append(values, s)
+	}
+
+	if hasCustom { // Any value in min-max range is allowed
+		return validateRange(desc, float64(value))
+	}
+
+	return fmt.Errorf("invalid value %d, available values: %s", value, strings.Join(values, ", "))
+}
+
+func validateRange(desc reflect.StructField, value float64) error {
+	if min, ok := desc.Tag.Lookup("min"); ok {
+		if m, err := strconv.ParseFloat(min, 64); err == nil && value < m {
+			return fmt.Errorf("value %s is lower than minimum %s", strconv.FormatFloat(value, 'f', -1, 64), min)
+		}
+	}
+
+	if max, ok := desc.Tag.Lookup("max"); ok {
+		if m, err := strconv.ParseFloat(max, 64); err == nil && value > m {
+			return fmt.Errorf("value %s is higher than maximum %s", strconv.FormatFloat(value, 'f', -1, 64), max)
+		}
+	}
+
+	return nil
+}
@@ -14,6 +14,8 @@ func initRecording() *recording {
 		FrameHeight:    1080,
 		FPS:            60,
 		EncodingFPSCap: 0,
+		OutputType:     "video",
+		Alpha:          false,
 		Encoder:        "libx264",
 		X264Settings: &x264Settings{
 			RateControl:       "crf",
@@ -87,9 +89,19 @@ func initRecording() *recording {
 		CustomAudioSettings: &custom{
 			CustomOptions: "",
 		},
-		AudioFilters:   "",
-		OutputDir:      "videos",
-		Container:      "mp4",
+		AudioFilters:  "",
+		OutputDir:     "videos",
+		Container:     "mp4",
+		SegmentLength: 60,
+		Markers: &markers{
+			Chapters:         false,
+			Subtitles:        false,
+			SubtitleDuration: 3,
+			Misses:           true,
+			ComboBreaks:      true,
+			Breaks:           true,
+			Fails:            true,
+		},
 		ShowFFmpegLogs: true,
 		MotionBlur: &motionblur{
 			Enabled:              false,
@@ -107,7 +119,9 @@ type recording struct {
 	FrameHeight         int                `min:"1" max:"17280"`
 	FPS                 int                `label:"FPS (PLEASE READ TOOLTIP)" string:"true" min:"1" max:"10727" tooltip:"IMPORTANT: If you plan to have a \"high fps\" video, use Motion Blur below instead of setting FPS to absurd numbers. Setting the value too high will result in a broken video!"`
 	EncodingFPSCap      int                `string:"true" min:"0" max:"10727" label:"Max Encoding FPS (Speed)" tooltip:"Limits the speed at which danser renders the video. If FPS is set to 60 and this option to 30, then it means 2 minute map will take at least 4 minutes to render"`
-	Encoder             string             `combo:"libx264|Software x264 (AVC),libx265|Software x265 (HEVC),h264_nvenc|NVIDIA NVENC H.264 (AVC),hevc_nvenc|NVIDIA NVENC H.265 (HEVC),h264_qsv|Intel QuickSync H.264 (AVC),hevc_qsv|Intel QuickSync H.265 (HEVC)" tooltip:"Even if AMD cards have their own hardware encoder, you will still get better results with software encoders"`
+	OutputType          string             `label:"Output type" combo:"video|Video,lossless|Lossless intermediate (FFV1 in MKV),png|PNG image sequence,exr|OpenEXR image sequence" tooltip:"Image sequences are saved in a directory named after the output, together with the audio file"`
+	Alpha               bool               `label:"Transparent background" showif:"OutputType=!video" tooltip:"Keep alpha channel, pixels where nothing was drawn are transparent.\nBackground has to be disabled by setting its dim to 100%"`
+	Encoder             string             `showif:"OutputType=video" combo:"libx264|Software x264 (AVC),libx265|Software x265 (HEVC),h264_nvenc|NVIDIA NVENC H.264 (AVC),hevc_nvenc|NVIDIA NVENC H.265 (HEVC),h264_qsv|Intel QuickSync H.264 (AVC),hevc_qsv|Intel QuickSync H.265 (HEVC)" tooltip:"Even if AMD cards have their own hardware encoder, you will still get better results with software encoders"`
 	X264Settings        *x264Settings      `json:"libx264" label:"Software x264 (AVC) Settings" showif:"Encoder=libx264"`
 	X265Settings        *x265Settings      `json:"libx265" label:"Software x265 (HEVC) Settings" showif:"Encoder=libx265"`
 	H264NvencSettings   *h264NvencSettings `json:"h264_nvenc" label:"NVIDIA NVENC H.264 (AVC) Settings" showif:"Encoder=h264_nvenc"`
@@ -124,15 +138,36 @@ type recording struct {
 	FLACSettings        *flacSettings      `json:"flac" label:"FLAC Settings" showif:"AudioCodec=flac"`
 	CustomAudioSettings *custom            `json:"customAudio" label:"Custom Audio Settings" showif:"AudioCodec=!"`
 	//AudioOptions        string             `label:"Audio Encoder Options"`
-	AudioFilters   string `label:"FFmpeg Audio Filters"`
-	OutputDir      string `path:"Select video output directory"`
-	Container      string `combo:"mp4,mkv"`
+	AudioFilters   string   `label:"FFmpeg Audio Filters"`
+	OutputDir      string   `path:"Select video output directory"`
+	Container      string   `combo:"mp4,mkv"`
+	SegmentLength  int      `string:"true" min:"0" max:"3600" label:"Segment length (seconds)" tooltip:"Video is encoded in segments of this length, so a crashed recording can be resumed by running it again with the same output name and parameters. 0 disables resuming"`
+	Markers        *markers `label:"Gameplay markers"`
 	ShowFFmpegLogs bool
 	MotionBlur     *motionblur
 
 	outDir *string
 }
 
+// IsImageSequence checks whether frames are saved as separate images instead of a video file
+func (g *recording) IsImageSequence() bool {
+	return g.OutputType == "png" || g.OutputType == "exr"
+}
+
+// HasAlpha checks whether the recording keeps alpha channel
+func (g *recording) HasAlpha() bool {
+	return g.Alpha && g.OutputType != "video"
+}
+
+// GetContainer returns container of video and audio files, lossless intermediate is always saved in MKV
+func (g *recording) GetContainer() string {
+	if g.OutputType == "lossless" {
+		return "mkv"
+	}
+
+	return g.Container
+}
+
 func (g *recording) GetEncoderOptions() EncoderOptions {
 	switch strings.ToLower(g.Encoder) {
 	case "libx264":
@@ -181,6 +216,21 @@ func (g *recording) GetOutputDir() string {
 	return *g.outDir
 }
 
+type markers struct {
+	Chapters         bool    `tooltip:"Add chapters marking gameplay events to the video.\nFor image sequences chapters are saved to chapters.txt in FFmpeg's metadata format"`
+	Subtitles        bool    `tooltip:"Add a subtitle track describing gameplay events to the video.\nFor image sequences subtitles are saved to subtitles.srt"`
+	SubtitleDuration float64 `string:"true" min:"0.5" max:"30" label:"Subtitle duration (seconds)" showif:"Subtitles=true"`
+	Misses           bool    `label:"Mark misses"`
+	ComboBreaks      bool    `label:"Mark slider breaks" tooltip:"Combo breaks that are not misses"`
+	Breaks           bool    `label:"Mark breaks"`
+	Fails            bool    `label:"Mark fails"`
+}
+
+// IsEnabled checks whether gameplay markers are saved in any form
+func (m *markers) IsEnabled() bool {
+	return m.Chapters || m.Subtitles
+}
+
 type motionblur struct {
 	Enabled              bool
 	OversampleMultiplier int           `string:"true" min:"1" max:"512" tooltip:"Multiplier for FPS. FPS=60 and Oversample=16 means original footage has 960fps before blending to 60fps"`
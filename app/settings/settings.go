@@ -4,12 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"github.com/wieku/danser-go/framework/files"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
@@ -21,6 +18,20 @@ type Config struct {
 	srcPath string
 	srcData []byte
 
+	// Settings files referenced by Extends, the top-most base is first
+	baseData  [][]byte
+	basePaths []string
+
+	// Overridden and base values as of the last load or save, used by IsInherited
+	overrides  map[string]any
+	baseValues map[string]any
+
+	// Values overridden by -set, they are not saved
+	applied []appliedOverride
+
+	// Name of the settings file this one inherits from, only values that differ from it are saved
+	Extends string `json:",omitempty"`
+
 	General     *general     `icon:"\uF0AD"`                   // wrench
 	Graphics    *graphics    `icon:"\uE163"  liveedit:"false"` // display
 	Audio       *audio       `icon:"\uF028"`                   // volume-high
@@ -55,35 +66,46 @@ type CombinedConfig struct {
 func LoadConfig(file *os.File) (*Config, error) {
 	log.Println(fmt.Sprintf(`SettingsManager: Loading "%s"`, file.Name()))
 
-	data, err := io.ReadAll(files.NewUnicodeReader(file))
+	data, fixed, err := readConfigData(file)
 	if err != nil {
 		return nil, fmt.Errorf("SettingsManager: Failed to read %s! Error: %s", file.Name(), err)
 	}
 
-	config := NewConfigFile()
+	var header configHeader
 
-	config.General.OsuReplaysDir = "" // Clear Replay path, so we can migrate it from Songs if JSON misses it
+	if err = json.Unmarshal(fixed, &header); err != nil {
+		return nil, fmt.Errorf("SettingsManager: Failed to parse %s! Please re-check the file for mistakes. Error: %s", file.Name(), err)
+	}
 
-	config.srcPath = file.Name()
-	config.srcData = data
+	var baseData [][]byte
+	var basePaths []string
 
-	str := string(data)
+	if header.Extends != "" {
+		abs, _ := filepath.Abs(file.Name())
 
-	// I hope it won't backfire, replacing \ or \\\\\\\ with \\ so JSON can parse it as \
+		baseData, basePaths, err = loadBaseChain(header.Extends, []string{abs})
+		if err != nil {
+			return nil, err
+		}
 
-	str = regexp.MustCompile(`\\+`).ReplaceAllString(str, `\`)
-	str = strings.ReplaceAll(str, `\`, `\\`)
+		log.Println(fmt.Sprintf(`SettingsManager: "%s" extends "%s"`, file.Name(), strings.Join(basePaths, `" -> "`)))
+	}
 
-	if err = json.Unmarshal([]byte(str), config); err != nil {
-		return nil, fmt.Errorf("SettingsManager: Failed to parse %s! Please re-check the file for mistakes. Error: %s", file.Name(), err)
+	config, err := parseConfig(file.Name(), append(baseData, fixed)...)
+	if err != nil {
+		return nil, err
 	}
 
-	config.migrateCursorDance()
-	config.migrateHitCounterColors()
-	config.migrateBlendWeights()
+	config.srcPath = file.Name()
+	config.srcData = data
 
-	if config.General.OsuReplaysDir == "" { // Set the replay directory if it hasn't been loaded
-		config.General.OsuReplaysDir = filepath.Join(filepath.Dir(config.General.OsuSongsDir), "Replays")
+	config.baseData = baseData
+	config.basePaths = basePaths
+
+	if config.Extends != "" {
+		if _, err = config.marshalOverrides(); err != nil {
+			return nil, fmt.Errorf("SettingsManager: Failed to compare %s with base settings! Error: %s", file.Name(), err)
+		}
 	}
 
 	log.Println(fmt.Sprintf(`SettingsManager: "%s" loaded!`, file.Name()))
@@ -139,39 +161,27 @@ func (config *Config) migrateCursorDance() {
 	config.CursorDance.TAGSliderDance = config.Dance.TAGSliderDance
 
 	if config.Dance.Bezier != nil {
-		config.CursorDance.MoverSettings.Bezier = []*bezier{
-			config.Dance.Bezier,
-		}
+		config.CursorDance.setMoverSettings("Bezier", config.Dance.Bezier)
 	}
 
 	if config.Dance.Flower != nil {
-		config.CursorDance.MoverSettings.Flower = []*flower{
-			config.Dance.Flower,
-		}
+		config.CursorDance.setMoverSettings("Flower", config.Dance.Flower)
 	}
 
 	if config.Dance.HalfCircle != nil {
-		config.CursorDance.MoverSettings.HalfCircle = []*circular{
-			config.Dance.HalfCircle,
-		}
+		config.CursorDance.setMoverSettings("HalfCircle", config.Dance.HalfCircle)
 	}
 
 	if config.Dance.Spline != nil {
-		config.CursorDance.MoverSettings.Spline = []*spline{
-			config.Dance.Spline,
-		}
+		config.CursorDance.setMoverSettings("Spline", config.Dance.Spline)
 	}
 
 	if config.Dance.Momentum != nil {
-		config.CursorDance.MoverSettings.Momentum = []*momentum{
-			config.Dance.Momentum,
-		}
+		config.CursorDance.setMoverSettings("Momentum", config.Dance.Momentum)
 	}
 
 	if config.Dance.ExGon != nil {
-		config.CursorDance.MoverSettings.ExGon = []*exgon{
-			config.Dance.ExGon,
-		}
+		config.CursorDance.setMoverSettings("ExGon", config.Dance.ExGon)
 	}
 
 	config.Dance = nil
@@ -257,7 +267,20 @@ func (config *Config) Save(path string, forceSave bool) {
 		path = config.srcPath
 	}
 
-	data, err := json.MarshalIndent(config, "", "\t")
+	if len(config.applied) > 0 {
+		config.revertOverrides()
+		defer config.reapplyOverrides()
+	}
+
+	var data []byte
+	var err error
+
+	if config.Extends != "" {
+		data, err = config.marshalOverrides()
+	} else {
+		data, err = json.MarshalIndent(config, "", "\t")
+	}
+
 	if err != nil {
 		panic(err)
 	}
@@ -46,9 +46,23 @@ func (d *defaultsFactory) SkinOptions() []string {
 
 		fs, err := ioutil.ReadDir(skinPath)
 		if err == nil {
+			names := make(map[string]bool)
+
 			for _, f := range fs {
-				if f.IsDir() {
-					skinCache = append(skinCache, filepath.Base(f.Name()))
+				name := filepath.Base(f.Name())
+
+				// .osk files are unpacked when danser starts, so they can be selected already
+				if !f.IsDir() {
+					if !strings.EqualFold(filepath.Ext(name), ".osk") {
+						continue
+					}
+
+					name = strings.TrimSuffix(name, filepath.Ext(name))
+				}
+
+				if !names[name] {
+					names[name] = true
+					skinCache = append(skinCache, name)
 				}
 			}
 
@@ -0,0 +1,200 @@
+package skin
+
+import (
+	"github.com/wieku/danser-go/app/settings"
+	"strings"
+)
+
+// Element kinds reported by Check
+const (
+	KindTexture   = "texture"
+	KindAnimation = "animation"
+	KindFont      = "font"
+	KindSample    = "sample"
+)
+
+var checkedTextures = []string{
+	"hitcircle", "hitcircleoverlay", "sliderstartcircle", "sliderstartcircleoverlay", "sliderendcircle", "sliderendcircleoverlay",
+	"approachcircle", "reversearrow", "sliderscorepoint", "sliderb-nd", "sliderb-spec", "lighting",
+	"particle50", "particle100", "particle300",
+	"cursor", "cursormiddle", "cursortrail", "cursor-ripple", "cursor-smoke",
+	"spinner-background", "spinner-circle", "spinner-metre", "spinner-approachcircle", "spinner-glow", "spinner-bottom", "spinner-top",
+	"spinner-middle", "spinner-middle2", "spinner-clear", "spinner-spin", "spinner-rpm",
+	"scorebar-bg", "scorebar-marker", "scorebar-ki", "scorebar-kidanger", "scorebar-kidanger2",
+	"section-pass", "section-fail", "play-warningarrow", "arrow-warning", "inputoverlay-background", "inputoverlay-key",
+	"menu-button-background", "ranking-panel", "ranking-graph", "ranking-accuracy", "ranking-maxcombo", "ranking-title", "ranking-perfect",
+	"selection-mod-base",
+}
+
+var checkedGrades = []string{"d", "c", "b", "a", "s", "sh", "x", "xh"}
+
+var checkedMods = []string{
+	"autoplay", "doubletime", "easy", "flashlight", "halftime", "hardrock", "hidden", "nightcore", "nofail", "perfect",
+	"relax", "relax2", "scorev2", "spunout", "suddendeath",
+}
+
+type checkedAnimation struct {
+	name    string
+	useDash bool
+}
+
+var checkedAnimations = []checkedAnimation{
+	{"hit0", true}, {"hit50", true}, {"hit100", true}, {"hit100k", true}, {"hit300", true}, {"hit300k", true}, {"hit300g", true},
+	{"followpoint", true}, {"sliderb", false}, {"sliderfollowcircle", true}, {"play-skip", true}, {"scorebar-colour", true},
+	{"comboburst", true},
+}
+
+var checkedSamples = []string{
+	"combobreak", "comboburst", "failsound", "sectionpass", "sectionfail", "spinnerspin", "spinnerbonus",
+	"nightcore-hat", "nightcore-clap", "nightcore-kick", "nightcore-finish",
+}
+
+var digitGlyphs = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+var scoreGlyphs = append(append([]string{}, digitGlyphs...), "comma", "dot", "percent", "x")
+
+var comboGlyphs = append(append([]string{}, digitGlyphs...), "x")
+
+type checkedFont struct {
+	prefix string
+	glyphs []string
+}
+
+// CheckElement tells where a skin element will be loaded from
+type CheckElement struct {
+	Name   string
+	Kind   string
+	Source string // skin, fallback, default or missing (element is not drawn/played or is replaced by another one)
+}
+
+// CheckReport lists elements that won't be loaded from the checked skin
+type CheckReport struct {
+	Skin         string
+	FallbackSkin string
+	Checked      int
+	FromSkin     int
+	Elements     []CheckElement
+	UnparsedKeys []string
+}
+
+func (source Source) String() string {
+	switch source {
+	case SKIN:
+		return "skin"
+	case FALLBACK:
+		return "fallback"
+	case LOCAL:
+		return "default"
+	case BEATMAP:
+		return "beatmap"
+	}
+
+	return "missing"
+}
+
+// Check reports which textures, animations, fonts and samples of given skin fall back to Skin.FallbackSkin or danser's default skin.
+// It only looks for files, so it can be used without graphics and audio being initialized.
+func Check(name string) (*CheckReport, error) {
+	report := &CheckReport{
+		Skin:         name,
+		FallbackSkin: defaultName,
+	}
+
+	res, err := NewResolver(name, settings.Skin.FallbackSkin)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.HasFallback() {
+		report.FallbackSkin = settings.Skin.FallbackSkin
+	}
+
+	skinInfo := newDefaultInfo()
+
+	if path, err := res.getFile("skin.ini", SKIN); err == nil {
+		if skinInfo, err = LoadInfo(path, false); err != nil {
+			return nil, err
+		}
+	}
+
+	report.UnparsedKeys = skinInfo.UnparsedKeys
+
+	add := func(name, kind string, source Source) {
+		report.Checked++
+
+		if source == SKIN {
+			report.FromSkin++
+			return
+		}
+
+		report.Elements = append(report.Elements, CheckElement{
+			Name:   name,
+			Kind:   kind,
+			Source: source.String(),
+		})
+	}
+
+	textures := append([]string{}, checkedTextures...)
+
+	for _, grade := range checkedGrades {
+		textures = append(textures, "ranking-"+grade, "ranking-"+grade+"-small")
+	}
+
+	for _, mod := range checkedMods {
+		textures = append(textures, "selection-mod-"+mod)
+	}
+
+	for _, texture := range textures {
+		add(texture, KindTexture, res.TextureSource(texture))
+	}
+
+	for _, animation := range checkedAnimations {
+		add(animation.name, KindAnimation, res.FramesSource(animation.name, animation.useDash))
+	}
+
+	fonts := []checkedFont{
+		{skinInfo.HitCirclePrefix, digitGlyphs},
+		{skinInfo.ScorePrefix, scoreGlyphs},
+		{skinInfo.ComboPrefix, comboGlyphs},
+		{"scoreentry", scoreGlyphs},
+	}
+
+	checkedFonts := make(map[string]bool)
+
+	for _, font := range fonts {
+		if checkedFonts[strings.ToLower(font.prefix)] {
+			continue
+		}
+
+		checkedFonts[strings.ToLower(font.prefix)] = true
+
+		add(font.prefix, KindFont, fontSource(res, font.prefix, font.glyphs))
+	}
+
+	samples := append([]string{}, checkedSamples...)
+
+	for _, set := range []string{"normal", "soft", "drum"} {
+		for _, hitSound := range []string{"hitnormal", "hitwhistle", "hitfinish", "hitclap", "slidertick", "sliderslide", "sliderwhistle"} {
+			samples = append(samples, set+"-"+hitSound)
+		}
+	}
+
+	for _, sample := range samples {
+		add(sample, KindSample, res.SampleSource(sample))
+	}
+
+	return report, nil
+}
+
+// fontSource returns the least specific source of font's glyphs
+func fontSource(res *Resolver, prefix string, glyphs []string) Source {
+	source := SKIN
+
+	for _, glyph := range glyphs {
+		if glyphSource := res.TextureSource(prefix + "-" + glyph); sourcePriority(glyphSource) < sourcePriority(source) {
+			source = glyphSource
+		}
+	}
+
+	return source
+}
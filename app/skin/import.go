@@ -0,0 +1,109 @@
+package skin
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/utils"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const archiveExt = ".osk"
+
+// IsArchive returns true if path points to .osk file
+func IsArchive(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), archiveExt)
+}
+
+// UnpackSkins extracts .osk files dropped into skins directory. Archives are removed after extraction like .osz files in Songs directory.
+func UnpackSkins() {
+	skinsDir := settings.General.GetSkinsDir()
+
+	entries, err := os.ReadDir(skinsDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !IsArchive(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(skinsDir, entry.Name())
+
+		if _, err = ImportSkin(path); err != nil {
+			log.Println(fmt.Sprintf("SkinManager: Failed to unpack %s: %s", path, err))
+			continue
+		}
+
+		_ = os.Remove(path)
+	}
+}
+
+// ImportSkin extracts .osk file into skins directory and returns the name of imported skin.
+// Name is taken from archive's file name, files of existing skin with the same name are overwritten.
+// If all files are inside a single folder, folder's contents are extracted.
+func ImportSkin(path string) (string, error) {
+	if !IsArchive(path) {
+		return "", errors.New("not an .osk file")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSpace(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if name == "" || strings.EqualFold(name, defaultName) {
+		return "", fmt.Errorf("invalid skin name \"%s\"", name)
+	}
+
+	destination := filepath.Join(settings.General.GetSkinsDir(), name)
+
+	log.Println("SkinManager: Unpacking", path, "->", destination)
+
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return "", err
+	}
+
+	rootDir, err := getRootDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = utils.UnzipDir(path, destination, rootDir); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// getRootDir returns the folder containing all files of the archive, like "Skin Name/", or empty string if there's none
+func getRootDir(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer r.Close()
+
+	rootDir := ""
+
+	for _, f := range r.File {
+		i := strings.Index(f.Name, "/")
+		if i < 0 { // File at the root of the archive
+			return "", nil
+		}
+
+		if rootDir == "" {
+			rootDir = f.Name[:i+1]
+		} else if f.Name[:i+1] != rootDir {
+			return "", nil
+		}
+	}
+
+	return rootDir, nil
+}
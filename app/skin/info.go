@@ -7,6 +7,7 @@ import (
 	"github.com/wieku/danser-go/framework/math/color"
 	"io"
 	"os"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -33,7 +34,8 @@ type SkinInfo struct {
 
 	LayeredHitSounds bool
 
-	//skipping combo bursts
+	ComboBurstRandom       bool
+	CustomComboBurstSounds []int
 
 	CursorCentre bool
 	CursorExpand bool
@@ -55,6 +57,10 @@ type SkinInfo struct {
 	SongSelectActiveText   color.Color
 	InputOverlayText       color.Color
 
+	MenuGlow          color.Color // Parsed for completeness, danser doesn't have osu!'s menu
+	SpinnerBackground color.Color
+	StarBreakAdditive color.Color // Parsed for completeness, danser doesn't draw break stars
+
 	//hit circle font settings
 	HitCirclePrefix             string
 	HitCircleOverlap            float64
@@ -67,6 +73,11 @@ type SkinInfo struct {
 	//combo font settings
 	ComboPrefix  string
 	ComboOverlap float64
+
+	Mania []*ManiaInfo
+
+	// Keys found in skin.ini that danser doesn't use, in "[Section] Key" form
+	UnparsedKeys []string
 }
 
 func newDefaultInfo() *SkinInfo {
@@ -95,6 +106,9 @@ func newDefaultInfo() *SkinInfo {
 		SongSelectInactiveText:      color.NewL(1),
 		SongSelectActiveText:        color.NewL(0),
 		InputOverlayText:            color.NewL(0),
+		MenuGlow:                    color.NewIRGB(0, 78, 155),
+		SpinnerBackground:           color.NewIRGB(100, 100, 100),
+		StarBreakAdditive:           color.NewIRGB(255, 182, 193),
 		HitCirclePrefix:             "default",
 		HitCircleOverlap:            -2,
 		HitCircleOverlayAboveNumber: false,
@@ -174,6 +188,28 @@ func ParseColor(text, errType string) color.Color {
 	return clr
 }
 
+// parsePath makes texture paths like "Fonts\\score" or "./fonts/score" usable as skin texture names
+func parsePath(text string) string {
+	text = strings.Trim(strings.TrimSpace(text), "\"")
+	text = strings.ReplaceAll(text, "\\", "/")
+
+	if text == "" {
+		return text
+	}
+
+	return path.Clean(text)
+}
+
+func parseInts(text, errType string) (values []int) {
+	for _, v := range strings.Split(text, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, int(ParseFloat(v, errType)))
+		}
+	}
+
+	return
+}
+
 func LoadInfo(path string, local bool) (*SkinInfo, error) {
 	var file io.ReadCloser
 	var err error
@@ -198,15 +234,49 @@ func LoadInfo(path string, local bool) (*SkinInfo, error) {
 
 	colorsI := make([]colorI, 0)
 
+	section := ""
+	unparsed := make(map[string]bool)
+
+	var mania *ManiaInfo
+
+	addUnparsed := func(section, key string) {
+		key = strings.TrimSpace(section + " " + key)
+
+		if !unparsed[key] {
+			unparsed[key] = true
+			info.UnparsedKeys = append(info.UnparsedKeys, key)
+		}
+	}
+
 	for scanner.Scan() {
 		line := scanner.Text()
 
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = trimmed
+
+			mania = nil
+			if strings.EqualFold(section, "[Mania]") {
+				mania = newDefaultManiaInfo(0)
+				info.Mania = append(info.Mania, mania)
+			}
+
+			continue
+		}
+
 		tokenized := tokenize(line, ":")
 
 		if tokenized == nil {
 			continue
 		}
 
+		if mania != nil {
+			if !mania.parse(tokenized[0], tokenized[1]) {
+				addUnparsed(section, tokenized[0])
+			}
+
+			continue
+		}
+
 		switch tokenized[0] {
 		case "Name":
 			info.Name = tokenized[1]
@@ -230,6 +300,10 @@ func LoadInfo(path string, local bool) (*SkinInfo, error) {
 			info.SpinnerFrequencyModulate = ParseBool(tokenized[1], tokenized[0])
 		case "LayeredHitSounds":
 			info.LayeredHitSounds = ParseBool(tokenized[1], tokenized[0])
+		case "ComboBurstRandom":
+			info.ComboBurstRandom = ParseBool(tokenized[1], tokenized[0])
+		case "CustomComboBurstSounds":
+			info.CustomComboBurstSounds = parseInts(tokenized[1], tokenized[0])
 		case "CursorCentre":
 			info.CursorCentre = ParseBool(tokenized[1], tokenized[0])
 		case "CursorExpand":
@@ -262,20 +336,36 @@ func LoadInfo(path string, local bool) (*SkinInfo, error) {
 			info.SongSelectActiveText = ParseColor(tokenized[1], tokenized[0])
 		case "InputOverlayText":
 			info.InputOverlayText = ParseColor(tokenized[1], tokenized[0])
+		case "MenuGlow":
+			info.MenuGlow = ParseColor(tokenized[1], tokenized[0])
+		case "SpinnerBackground":
+			info.SpinnerBackground = ParseColor(tokenized[1], tokenized[0])
+		case "StarBreakAdditive":
+			info.StarBreakAdditive = ParseColor(tokenized[1], tokenized[0])
 		case "HitCirclePrefix":
-			info.HitCirclePrefix = tokenized[1]
+			info.HitCirclePrefix = parsePath(tokenized[1])
 		case "HitCircleOverlap":
 			info.HitCircleOverlap = ParseFloat(tokenized[1], tokenized[0])
 		case "HitCircleOverlayAboveNumber", "HitCircleOverlayAboveNumer":
 			info.HitCircleOverlayAboveNumber = ParseBool(tokenized[1], tokenized[0])
 		case "ScorePrefix":
-			info.ScorePrefix = tokenized[1]
+			info.ScorePrefix = parsePath(tokenized[1])
 		case "ScoreOverlap":
 			info.ScoreOverlap = ParseFloat(tokenized[1], tokenized[0])
 		case "ComboPrefix":
-			info.ComboPrefix = tokenized[1]
+			info.ComboPrefix = parsePath(tokenized[1])
 		case "ComboOverlap":
 			info.ComboOverlap = ParseFloat(tokenized[1], tokenized[0])
+		default:
+			addUnparsed(section, tokenized[0])
+		}
+	}
+
+	// Sections without valid Keys can't be matched to any beatmap
+	for i := 0; i < len(info.Mania); i++ {
+		if info.Mania[i].Keys == 0 {
+			info.Mania = append(info.Mania[:i], info.Mania[i+1:]...)
+			i--
 		}
 	}
 
@@ -0,0 +1,275 @@
+package skin
+
+import (
+	"github.com/wieku/danser-go/framework/math/color"
+	"strconv"
+	"strings"
+)
+
+// ManiaInfo holds settings of a single [Mania] section of skin.ini, there's one section per key count
+type ManiaInfo struct {
+	Keys int
+
+	ColumnStart     float64
+	ColumnRight     float64
+	ColumnSpacing   []float64
+	ColumnWidth     []float64
+	ColumnLineWidth []float64
+	BarlineHeight   float64
+
+	LightingNWidth []float64
+	LightingLWidth []float64
+
+	WidthForNoteHeightScale float64
+
+	HitPosition   float64
+	LightPosition float64
+	ScorePosition float64
+	ComboPosition float64
+
+	JudgementLine       bool
+	LightFramePerSecond float64
+
+	SpecialStyle    int
+	ComboBurstStyle int
+
+	SplitStages     bool
+	StageSeparation float64
+	SeparateScore   bool
+	KeysUnderNotes  bool
+
+	UpsideDown             bool
+	KeyFlipWhenUpsideDown  bool
+	NoteFlipWhenUpsideDown bool
+
+	NoteBodyStyle int
+
+	ColumnColors      []color.Color
+	ColumnLightColors []color.Color
+
+	ColourColumnLine    color.Color
+	ColourBarline       color.Color
+	ColourJudgementLine color.Color
+	ColourKeyWarning    color.Color
+	ColourHold          color.Color
+	ColourBreak         color.Color
+
+	// Per-column overrides, like KeyFlipWhenUpsideDown3 or NoteBodyStyle0, by their key in skin.ini
+	ColumnOverrides map[string]int
+
+	// Texture names of keys, notes, stage and judgements, like KeyImage0D or StageHint, by their key in skin.ini
+	Images map[string]string
+}
+
+func newDefaultManiaInfo(keys int) *ManiaInfo {
+	mania := &ManiaInfo{
+		ColumnStart:            136,
+		ColumnRight:            19,
+		BarlineHeight:          1.2,
+		HitPosition:            402,
+		LightPosition:          413,
+		JudgementLine:          true,
+		LightFramePerSecond:    60,
+		SplitStages:            true,
+		StageSeparation:        40,
+		SeparateScore:          true,
+		KeysUnderNotes:         false,
+		KeyFlipWhenUpsideDown:  true,
+		NoteFlipWhenUpsideDown: true,
+		ColourColumnLine:       color.NewL(1),
+		ColourBarline:          color.NewL(1),
+		ColourJudgementLine:    color.NewL(1),
+		ColourKeyWarning:       color.NewL(0),
+		ColourHold:             color.NewIRGB(255, 191, 51),
+		ColourBreak:            color.NewIRGB(255, 0, 0),
+		ColumnOverrides:        make(map[string]int),
+		Images:                 make(map[string]string),
+	}
+
+	mania.setKeys(keys)
+
+	return mania
+}
+
+// setKeys resizes per-column settings, values that were already set are kept
+func (mania *ManiaInfo) setKeys(keys int) {
+	mania.Keys = keys
+
+	mania.ColumnSpacing = resizeFloats(mania.ColumnSpacing, keys-1, 0)
+	mania.ColumnWidth = resizeFloats(mania.ColumnWidth, keys, 30)
+	mania.ColumnLineWidth = resizeFloats(mania.ColumnLineWidth, keys+1, 2)
+
+	for len(mania.ColumnColors) < keys {
+		mania.ColumnColors = append(mania.ColumnColors, color.NewL(0))
+	}
+
+	for len(mania.ColumnLightColors) < keys {
+		mania.ColumnLightColors = append(mania.ColumnLightColors, color.NewIRGB(55, 255, 255))
+	}
+}
+
+// parse applies a single skin.ini key of the section, returns false if the key is unknown
+func (mania *ManiaInfo) parse(key, value string) bool {
+	switch key {
+	case "Keys":
+		keys, err := strconv.Atoi(value)
+		if err != nil || keys < 1 || keys > 18 {
+			panic("Error while parsing Keys: " + value)
+		}
+
+		mania.setKeys(keys)
+	case "ColumnStart":
+		mania.ColumnStart = ParseFloat(value, key)
+	case "ColumnRight":
+		mania.ColumnRight = ParseFloat(value, key)
+	case "ColumnSpacing":
+		mania.ColumnSpacing = parseFloats(mania.ColumnSpacing, value, key)
+	case "ColumnWidth":
+		mania.ColumnWidth = parseFloats(mania.ColumnWidth, value, key)
+	case "ColumnLineWidth":
+		mania.ColumnLineWidth = parseFloats(mania.ColumnLineWidth, value, key)
+	case "BarlineHeight":
+		mania.BarlineHeight = ParseFloat(value, key)
+	case "LightingNWidth":
+		mania.LightingNWidth = parseFloats(mania.LightingNWidth, value, key)
+	case "LightingLWidth":
+		mania.LightingLWidth = parseFloats(mania.LightingLWidth, value, key)
+	case "WidthForNoteHeightScale":
+		mania.WidthForNoteHeightScale = ParseFloat(value, key)
+	case "HitPosition":
+		mania.HitPosition = ParseFloat(value, key)
+	case "LightPosition":
+		mania.LightPosition = ParseFloat(value, key)
+	case "ScorePosition":
+		mania.ScorePosition = ParseFloat(value, key)
+	case "ComboPosition":
+		mania.ComboPosition = ParseFloat(value, key)
+	case "JudgementLine":
+		mania.JudgementLine = ParseBool(value, key)
+	case "LightFramePerSecond":
+		mania.LightFramePerSecond = ParseFloat(value, key)
+	case "SpecialStyle":
+		mania.SpecialStyle = int(ParseFloat(value, key))
+	case "ComboBurstStyle":
+		mania.ComboBurstStyle = int(ParseFloat(value, key))
+	case "SplitStages":
+		mania.SplitStages = ParseBool(value, key)
+	case "StageSeparation":
+		mania.StageSeparation = ParseFloat(value, key)
+	case "SeparateScore":
+		mania.SeparateScore = ParseBool(value, key)
+	case "KeysUnderNotes":
+		mania.KeysUnderNotes = ParseBool(value, key)
+	case "UpsideDown":
+		mania.UpsideDown = ParseBool(value, key)
+	case "KeyFlipWhenUpsideDown":
+		mania.KeyFlipWhenUpsideDown = ParseBool(value, key)
+	case "NoteFlipWhenUpsideDown":
+		mania.NoteFlipWhenUpsideDown = ParseBool(value, key)
+	case "NoteBodyStyle":
+		mania.NoteBodyStyle = int(ParseFloat(value, key))
+	case "ColourColumnLine":
+		mania.ColourColumnLine = ParseColor(value, key)
+	case "ColourBarline":
+		mania.ColourBarline = ParseColor(value, key)
+	case "ColourJudgementLine":
+		mania.ColourJudgementLine = ParseColor(value, key)
+	case "ColourKeyWarning":
+		mania.ColourKeyWarning = ParseColor(value, key)
+	case "ColourHold":
+		mania.ColourHold = ParseColor(value, key)
+	case "ColourBreak":
+		mania.ColourBreak = ParseColor(value, key)
+	case "StageLeft", "StageRight", "StageBottom", "StageHint", "StageLight", "LightingN", "LightingL", "WarningArrow",
+		"Hit0", "Hit50", "Hit100", "Hit200", "Hit300", "Hit300g":
+		mania.Images[key] = parsePath(value)
+	default:
+		return mania.parseColumnKey(key, value)
+	}
+
+	return true
+}
+
+// parseColumnKey handles keys that end with column index, like Colour1, KeyImage0D or NoteBodyStyle3
+func (mania *ManiaInfo) parseColumnKey(key, value string) bool {
+	for _, prefix := range []string{"ColourLight", "Colour"} {
+		if column, ok := columnIndex(key, prefix, ""); ok {
+			if column < 1 || column > len(mania.ColumnColors) {
+				return false
+			}
+
+			if prefix == "ColourLight" {
+				mania.ColumnLightColors[column-1] = ParseColor(value, key)
+			} else {
+				mania.ColumnColors[column-1] = ParseColor(value, key)
+			}
+
+			return true
+		}
+	}
+
+	for _, prefix := range []string{"KeyImage", "NoteImage"} {
+		for _, suffix := range []string{"", "D", "H", "L", "T"} {
+			if _, ok := columnIndex(key, prefix, suffix); ok {
+				mania.Images[key] = parsePath(value)
+				return true
+			}
+		}
+	}
+
+	for _, prefix := range []string{"KeyFlipWhenUpsideDown", "NoteFlipWhenUpsideDown", "NoteBodyStyle"} {
+		for _, suffix := range []string{"", "D", "H", "L", "T"} {
+			if _, ok := columnIndex(key, prefix, suffix); ok {
+				mania.ColumnOverrides[key] = int(ParseFloat(value, key))
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// GetManiaInfo returns [Mania] section for given key count, or default settings if skin doesn't have it
+func (info *SkinInfo) GetManiaInfo(keys int) *ManiaInfo {
+	for _, mania := range info.Mania {
+		if mania.Keys == keys {
+			return mania
+		}
+	}
+
+	return newDefaultManiaInfo(keys)
+}
+
+func columnIndex(key, prefix, suffix string) (int, bool) {
+	if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) || len(key) <= len(prefix)+len(suffix) {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(key[len(prefix) : len(key)-len(suffix)])
+	if err != nil || index < 0 {
+		return 0, false
+	}
+
+	return index, true
+}
+
+func resizeFloats(values []float64, size int, value float64) []float64 {
+	for len(values) < size {
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// parseFloats overwrites leading values with comma separated list, remaining ones keep their defaults
+func parseFloats(values []float64, text, errType string) []float64 {
+	divided := strings.Split(text, ",")
+
+	values = resizeFloats(values, len(divided), 0)
+
+	for i, v := range divided {
+		values[i] = ParseFloat(strings.TrimSpace(v), errType)
+	}
+
+	return values
+}
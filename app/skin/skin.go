@@ -12,7 +12,6 @@ import (
 	"github.com/wieku/danser-go/framework/math/color"
 	"log"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,6 +30,141 @@ const (
 
 const defaultName = "default"
 
+var sampleExtensions = []string{".wav", ".ogg", ".mp3"}
+
+// Resolver locates files of a skin and its fallback skin. Textures and samples are searched in the skin,
+// then in the fallback skin and then in danser's default skin. Its Source methods only look for files,
+// so they tell where an element will be loaded from without graphics and audio being initialized.
+type Resolver struct {
+	skin     *files.FileMap // nil if default skin is used
+	fallback *files.FileMap // nil if fallback skin is not used
+}
+
+// NewResolver returns a Resolver of skin and fallback skin from skins directory, fallback is ignored if it doesn't exist
+func NewResolver(name, fallbackName string) (*Resolver, error) {
+	res := new(Resolver)
+
+	if name == defaultName {
+		return res, nil
+	}
+
+	var err error
+
+	res.skin, err = files.NewFileMap(filepath.Join(settings.General.GetSkinsDir(), name))
+	if err != nil {
+		return nil, fmt.Errorf("skin \"%s\" does not exist", name)
+	}
+
+	if fallbackName != name && fallbackName != defaultName {
+		res.fallback, _ = files.NewFileMap(filepath.Join(settings.General.GetSkinsDir(), fallbackName))
+	}
+
+	return res, nil
+}
+
+// HasFallback returns true if fallback skin is used
+func (res *Resolver) HasFallback() bool {
+	return res.fallback != nil
+}
+
+// sources returns searched sources allowed by mask, in search order
+func (res *Resolver) sources(mask Source) []Source {
+	sources := make([]Source, 0, 3)
+
+	if mask&SKIN > 0 && res.skin != nil {
+		sources = append(sources, SKIN)
+	}
+
+	if mask&FALLBACK > 0 && res.fallback != nil {
+		sources = append(sources, FALLBACK)
+	}
+
+	if mask&LOCAL > 0 {
+		sources = append(sources, LOCAL)
+	}
+
+	return sources
+}
+
+// getFile returns the path of skin's or fallback skin's file
+func (res *Resolver) getFile(name string, source Source) (string, error) {
+	fileMap := res.skin
+	if source == FALLBACK {
+		fileMap = res.fallback
+	}
+
+	if source == LOCAL || fileMap == nil {
+		return "", fmt.Errorf("file \"%s\" is not in a skin directory", name)
+	}
+
+	return fileMap.GetFile(name)
+}
+
+func (res *Resolver) exists(name string, source Source) bool {
+	if source == LOCAL {
+		file, err := assets.Open(filepath.Join("assets", "default-skin", name))
+		if err != nil {
+			return false
+		}
+
+		_ = file.Close()
+
+		return true
+	}
+
+	_, err := res.getFile(name, source)
+
+	return err == nil
+}
+
+// TextureSource returns the source GetTexture loads the texture from, UNKNOWN if it's missing
+func (res *Resolver) TextureSource(name string) Source {
+	for _, source := range res.sources(ALL) {
+		if res.exists(getX2Name(name+".png"), source) || res.exists(name+".png", source) {
+			return source
+		}
+	}
+
+	return UNKNOWN
+}
+
+// FramesSource returns the source GetFrames loads the animation from, UNKNOWN if it's missing
+func (res *Resolver) FramesSource(name string, useDash bool) Source {
+	dash := ""
+	if useDash {
+		dash = "-"
+	}
+
+	frame := res.TextureSource(name + dash + "0")
+	single := res.TextureSource(name)
+
+	// The first frame is used if it's at least as specific as the single texture, like in GetMostSpecific
+	if frame != UNKNOWN && sourcePriority(frame) >= sourcePriority(single) {
+		return frame
+	}
+
+	return single
+}
+
+// SampleSource returns the source GetSample loads the sample from, UNKNOWN if it's missing
+func (res *Resolver) SampleSource(name string) Source {
+	for _, source := range res.sources(ALL) {
+		for _, ext := range sampleExtensions {
+			if res.exists(name+ext, source) {
+				return source
+			}
+		}
+	}
+
+	return UNKNOWN
+}
+
+func getX2Name(name string) string {
+	ext := filepath.Ext(name)
+
+	return strings.TrimSuffix(name, ext) + "@2x" + ext
+}
+
 var fontLock = &sync.Mutex{}
 var soundLock = &sync.Mutex{}
 var textureLock = &sync.Mutex{}
@@ -49,8 +183,7 @@ var fontCache = make(map[string]*font.Font)
 
 var sampleCache = make(map[string]*bass.Sample)
 
-var skinPathCache *files.FileMap
-var fallbackPathCache *files.FileMap
+var resolver = new(Resolver)
 
 var CurrentSkin = defaultName
 var FallbackSkin = defaultName
@@ -61,6 +194,9 @@ func loadDefault() {
 	CurrentSkin = defaultName
 	FallbackSkin = defaultName
 
+	resolver.skin = nil
+	resolver.fallback = nil
+
 	var err error
 	info, err = LoadInfo(filepath.Join("assets", "default-skin", "skin.ini"), true)
 
@@ -83,7 +219,7 @@ func checkInit() {
 		log.Println("SkinManager: Loading fallback skin:", FallbackSkin)
 
 		var err error
-		fallbackPathCache, err = files.NewFileMap(filepath.Join(settings.General.GetSkinsDir(), FallbackSkin))
+		resolver.fallback, err = files.NewFileMap(filepath.Join(settings.General.GetSkinsDir(), FallbackSkin))
 
 		if err != nil {
 			log.Println("SkinManager:", FallbackSkin, "does not exist, falling back to default...")
@@ -94,6 +230,38 @@ func checkInit() {
 	}
 }
 
+// Unload forgets the loaded skin with its textures, fonts and samples, so the next use loads the skin set in current settings
+func Unload() {
+	fontLock.Lock()
+	textureLock.Lock()
+	soundLock.Lock()
+
+	defer fontLock.Unlock()
+	defer textureLock.Unlock()
+	defer soundLock.Unlock()
+
+	if atlas != nil {
+		atlas.Dispose()
+		atlas = nil
+	}
+
+	animationCache = make(map[string][]*texture.TextureRegion)
+	skinCache = make(map[string]*texture.TextureRegion)
+	fallbackCache = make(map[string]*texture.TextureRegion)
+	defaultCache = make(map[string]*texture.TextureRegion)
+	sourceCache = make(map[*texture.TextureRegion]Source)
+	fontCache = make(map[string]*font.Font)
+	sampleCache = make(map[string]*bass.Sample)
+
+	resolver = new(Resolver)
+
+	CurrentSkin = defaultName
+	FallbackSkin = defaultName
+
+	info = nil
+	beatmapColors = nil
+}
+
 func tryLoadSkin(name, fallbackName string) {
 	CurrentSkin = name
 	FallbackSkin = fallbackName
@@ -106,13 +274,13 @@ func tryLoadSkin(name, fallbackName string) {
 	log.Println("SkinManager: Loading skin:", name)
 
 	var err error
-	skinPathCache, err = files.NewFileMap(filepath.Join(settings.General.GetSkinsDir(), name))
+	resolver.skin, err = files.NewFileMap(filepath.Join(settings.General.GetSkinsDir(), name))
 
 	if err != nil {
 		log.Println(fmt.Sprintf("SkinManager: %s does not exist, falling back to %s...", name, fallbackName))
 		tryLoadSkin(fallbackName, defaultName)
 	} else {
-		path, err := skinPathCache.GetFile("skin.ini")
+		path, err := resolver.skin.GetFile("skin.ini")
 		if err != nil {
 			info = newDefaultInfo()
 		} else if info, err = LoadInfo(path, false); err != nil {
@@ -185,64 +353,36 @@ func GetTextureSource(name string, source Source) *texture.TextureRegion {
 	textureLock.Lock()
 	defer textureLock.Unlock()
 
-	source = source & (^BEATMAP)
-
-	if CurrentSkin == defaultName {
-		source = source & (^(SKIN | FALLBACK))
-	}
-
-	if CurrentSkin == FallbackSkin || FallbackSkin == defaultName {
-		source = source & (^FALLBACK)
-	}
-
-	if source&SKIN > 0 {
-		if rg, exists := skinCache[name]; exists {
-			if rg != nil {
-				return rg
-			}
-		} else {
-			rg := loadTexture(name+".png", SKIN)
-			skinCache[name] = rg
-
-			if rg != nil {
-				sourceCache[rg] = SKIN
-				return rg
-			}
-		}
-	}
+	for _, src := range resolver.sources(source) {
+		cache := getTextureCache(src)
 
-	if source&FALLBACK > 0 {
-		if rg, exists := fallbackCache[name]; exists {
-			if rg != nil {
-				return rg
-			}
-		} else {
-			rg := loadTexture(name+".png", FALLBACK)
-			fallbackCache[name] = rg
+		rg, exists := cache[name]
+		if !exists {
+			rg = loadTexture(name+".png", src)
+			cache[name] = rg
 
 			if rg != nil {
-				sourceCache[rg] = FALLBACK
-				return rg
+				sourceCache[rg] = src
 			}
 		}
-	}
 
-	if source&LOCAL > 0 {
-		if rg, exists := defaultCache[name]; exists {
+		if rg != nil {
 			return rg
 		}
+	}
 
-		rg := loadTexture(name+".png", LOCAL)
-		defaultCache[name] = rg
-
-		if rg != nil {
-			sourceCache[rg] = LOCAL
-		}
+	return nil
+}
 
-		return rg
+func getTextureCache(source Source) map[string]*texture.TextureRegion {
+	switch source {
+	case SKIN:
+		return skinCache
+	case FALLBACK:
+		return fallbackCache
 	}
 
-	return nil
+	return defaultCache
 }
 
 func GetFrames(name string, useDash bool) []*texture.TextureRegion {
@@ -285,19 +425,28 @@ func GetMostSpecific(rg1, rg2 *texture.TextureRegion) *texture.TextureRegion {
 		return rg1
 	}
 
-	rg1S := sourceCache[rg1]
-	rg2S := sourceCache[rg2]
-
-	if rg1S == BEATMAP ||
-		rg1S == SKIN && rg2S != BEATMAP ||
-		rg1S == FALLBACK && rg2S != SKIN && rg2S != BEATMAP ||
-		rg1S == LOCAL && rg2S != FALLBACK && rg2S != SKIN && rg2S != BEATMAP {
+	if sourcePriority(sourceCache[rg1]) >= sourcePriority(sourceCache[rg2]) {
 		return rg1
 	}
 
 	return rg2
 }
 
+func sourcePriority(source Source) int {
+	switch source {
+	case BEATMAP:
+		return 4
+	case SKIN:
+		return 3
+	case FALLBACK:
+		return 2
+	case LOCAL:
+		return 1
+	}
+
+	return 0
+}
+
 func GetSource(name string) Source {
 	tx := GetTexture(name)
 	if tx == nil {
@@ -332,15 +481,7 @@ func getPixmap(name string, source Source) (*texture.Pixmap, error) {
 		return assets.GetPixmap(filepath.Join("assets", "default-skin", name))
 	}
 
-	var path string
-	var err error
-
-	if source == SKIN {
-		path, err = skinPathCache.GetFile(name)
-	} else if source == FALLBACK {
-		path, err = fallbackPathCache.GetFile(name)
-	}
-
+	path, err := resolver.getFile(name, source)
 	if err != nil {
 		return nil, err
 	}
@@ -349,13 +490,9 @@ func getPixmap(name string, source Source) (*texture.Pixmap, error) {
 }
 
 func loadTexture(name string, source Source) *texture.TextureRegion {
-	ext := filepath.Ext(name)
-
-	x2Name := strings.TrimSuffix(name, ext) + "@2x" + ext
-
 	var region *texture.TextureRegion
 
-	image, err := getPixmap(x2Name, source)
+	image, err := getPixmap(getX2Name(name), source)
 	if err != nil {
 		image, err = getPixmap(name, source)
 		if err == nil {
@@ -422,18 +559,12 @@ func GetSample(name string) *bass.Sample {
 
 	var sample *bass.Sample
 
-	if CurrentSkin != defaultName {
-		sample = tryLoad(name, SKIN)
-
-		if sample == nil && FallbackSkin != CurrentSkin && FallbackSkin != defaultName {
-			sample = tryLoad(name, FALLBACK)
+	for _, source := range resolver.sources(ALL) {
+		if sample = tryLoad(name, source); sample != nil {
+			break
 		}
 	}
 
-	if sample == nil {
-		sample = tryLoad(name, LOCAL)
-	}
-
 	sampleCache[name] = sample
 
 	return sample
@@ -449,15 +580,7 @@ func getSample(name string, source Source) *bass.Sample {
 		return bass.NewSampleData(data)
 	}
 
-	var path string
-	var err error
-
-	if source == SKIN {
-		path, err = skinPathCache.GetFile(name)
-	} else if source == FALLBACK {
-		path, err = fallbackPathCache.GetFile(name)
-	}
-
+	path, err := resolver.getFile(name, source)
 	if err != nil {
 		return nil
 	}
@@ -466,43 +589,21 @@ func getSample(name string, source Source) *bass.Sample {
 }
 
 func tryLoad(basePath string, source Source) *bass.Sample {
-	if sam := getSample(basePath+".wav", source); sam != nil {
-		return sam
-	}
-
-	if sam := getSample(basePath+".ogg", source); sam != nil {
-		return sam
-	}
-
-	if sam := getSample(basePath+".mp3", source); sam != nil {
-		return sam
+	for _, ext := range sampleExtensions {
+		if sam := getSample(basePath+ext, source); sam != nil {
+			return sam
+		}
 	}
 
 	return nil
 }
 
-var beatmapColorsI []colorI
 var beatmapColors []color.Color
 
-func AddBeatmapColor(data []string) {
-	index, _ := strconv.ParseInt(strings.TrimPrefix(data[0], "Combo"), 10, 64)
-	beatmapColorsI = append(beatmapColorsI, colorI{
-		index: int(index),
-		color: ParseColor(data[1], data[0]),
-	})
-}
-
-func FinishBeatmapColors() {
-	if len(beatmapColorsI) > 0 {
-		sort.SliceStable(beatmapColorsI, func(i, j int) bool {
-			return beatmapColorsI[i].index <= beatmapColorsI[j].index
-		})
-
-		beatmapColors = make([]color.Color, 0)
-
-		for _, c := range beatmapColorsI {
-			beatmapColors = append(beatmapColors, c.color)
-		}
+// SetBeatmapColors sets combo colors of currently played beatmap
+func SetBeatmapColors(colors []color.Color) {
+	if len(colors) > 0 {
+		beatmapColors = colors
 	}
 }
 
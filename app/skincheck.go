@@ -0,0 +1,62 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/build"
+	"github.com/wieku/danser-go/framework/assets"
+	"os"
+	"strings"
+)
+
+// checkSkin prints elements of the skin that are loaded from the fallback or default skin. An .osk file is imported first.
+func checkSkin(name, format string) {
+	assets.Init(build.Stream == "Dev")
+
+	skin.UnpackSkins()
+
+	if skin.IsArchive(name) {
+		imported, err := skin.ImportSkin(name)
+		if err != nil {
+			panic(fmt.Sprintf("flag -skin-check: failed to import skin: %s", err))
+		}
+
+		name = imported
+	}
+
+	report, err := skin.Check(name)
+	if err != nil {
+		panic(fmt.Sprintf("flag -skin-check: %s", err))
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+
+		if err = encoder.Encode(report); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	fmt.Println("Skin:", report.Skin, "Fallback skin:", report.FallbackSkin)
+	fmt.Printf("%d of %d elements are provided by the skin\n", report.FromSkin, report.Checked)
+
+	if len(report.Elements) > 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Element", "Kind", "Loaded from"})
+
+		for _, e := range report.Elements {
+			table.Append([]string{e.Name, e.Kind, e.Source})
+		}
+
+		table.Render()
+	}
+
+	if len(report.UnparsedKeys) > 0 {
+		fmt.Println("Unused skin.ini keys:", strings.Join(report.UnparsedKeys, ", "))
+	}
+}
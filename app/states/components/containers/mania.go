@@ -0,0 +1,198 @@
+package containers
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+)
+
+const (
+	maniaStageTop      = -40.0 // Notes appear slightly above the playfield
+	maniaNoteHeight    = 0.35  // Relative to column width
+	maniaMissedAlpha   = 0.4
+	maniaKeyLightAlpha = 0.35
+)
+
+var (
+	maniaColorWhite  = color2.NewRGB(0.9, 0.9, 0.9)
+	maniaColorBlue   = color2.NewRGB(0.35, 0.6, 1)
+	maniaColorCentre = color2.NewRGB(1, 0.85, 0.3)
+)
+
+// ManiaContainer draws osu!mania stage with scrolling notes instead of osu!standard objects
+type ManiaContainer struct {
+	beatMap *beatmap.BeatMap
+	cursors []*graphics.Cursor
+
+	keys        int
+	columnWidth float64
+
+	skinInfo *skin.ManiaInfo
+
+	objectQueue []*objects.ManiaNote
+	notes       []*objects.ManiaNote
+}
+
+func NewManiaContainer(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor) *ManiaContainer {
+	log.Println("Creating osu!mania container...")
+
+	container := &ManiaContainer{
+		beatMap: beatMap,
+		cursors: cursors,
+		keys:    beatMap.GetManiaKeys(),
+	}
+
+	container.columnWidth = objects.GetManiaColumnWidth(container.keys)
+	container.skinInfo = skin.GetInfo().GetManiaInfo(container.keys)
+
+	for _, o := range beatMap.HitObjects {
+		if note, ok := o.(*objects.ManiaNote); ok {
+			container.objectQueue = append(container.objectQueue, note)
+		}
+	}
+
+	log.Println("Container created.")
+
+	return container
+}
+
+func (container *ManiaContainer) Update(float64) {}
+
+// noteY returns Y of the note at given time, notes reach the judgement line at their time
+func (container *ManiaContainer) noteY(noteTime, time float64) float64 {
+	return objects.ManiaHitPosition - (noteTime-time)/settings.Objects.Mania.ScrollTime*(objects.ManiaHitPosition-maniaStageTop)
+}
+
+func (container *ManiaContainer) processQueue(time float64) {
+	for len(container.objectQueue) > 0 && container.objectQueue[0].GetStartTime()-settings.Objects.Mania.ScrollTime <= time {
+		container.notes = append(container.notes, container.objectQueue[0])
+		container.objectQueue = container.objectQueue[1:]
+	}
+
+	// Time needed for the note to leave the screen below the judgement line
+	outTime := settings.Objects.Mania.ScrollTime * (384 - maniaStageTop) / (objects.ManiaHitPosition - maniaStageTop)
+
+	for i := 0; i < len(container.notes); i++ {
+		note := container.notes[i]
+		state := note.GetState()
+
+		if (state.Finished && state.FinishTime <= time) || note.GetEndTime()+outTime < time {
+			container.notes = append(container.notes[:i], container.notes[i+1:]...)
+			i--
+		}
+	}
+}
+
+func (container *ManiaContainer) columnColor(column int) color2.Color {
+	if container.keys%2 == 1 && column == container.keys/2 {
+		return maniaColorCentre
+	}
+
+	// Colors are mirrored around the centre
+	if mutils.Min(column, container.keys-1-column)%2 == 0 {
+		return maniaColorWhite
+	}
+
+	return maniaColorBlue
+}
+
+func (container *ManiaContainer) Draw(batch *batch.QuadBatch, _ mgl32.Mat4, cameras []mgl32.Mat4, time float64, _, alpha float32) {
+	container.processQueue(time)
+
+	if !settings.Playfield.DrawObjects {
+		return
+	}
+
+	pixel := graphics.Pixel.GetRegion()
+
+	left := objects.GetManiaColumnX(0, container.keys) - container.columnWidth/2
+	width := container.columnWidth * float64(container.keys)
+	height := objects.ManiaHitPosition - maniaStageTop
+	noteHeight := container.columnWidth * maniaNoteHeight
+
+	batch.Begin()
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, float64(alpha))
+	batch.SetCamera(cameras[0])
+
+	var keys uint32
+	if settings.Objects.Mania.HighlightKey && len(container.cursors) == 1 {
+		keys = container.cursors[0].ManiaKeys
+	}
+
+	// Column backgrounds, key lights and column lines use skin's [Mania] colors
+	for i := 0; i < container.keys; i++ {
+		x := objects.GetManiaColumnX(i, container.keys)
+
+		batch.DrawStObject(vector.NewVec2d(x, maniaStageTop), vector.TopCentre, vector.NewVec2d(container.columnWidth, height+noteHeight), false, false, 0, container.skinInfo.ColumnColors[i].Mul(color2.NewLA(1, float32(settings.Objects.Mania.ColumnAlpha))), false, pixel)
+
+		if keys&(1<<i) > 0 {
+			batch.DrawStObject(vector.NewVec2d(x, objects.ManiaHitPosition), vector.BottomCentre, vector.NewVec2d(container.columnWidth, height/3), false, false, 0, container.skinInfo.ColumnLightColors[i].Mul(color2.NewLA(1, maniaKeyLightAlpha)), true, pixel)
+		}
+
+		if i > 0 {
+			batch.DrawStObject(vector.NewVec2d(x-container.columnWidth/2, maniaStageTop), vector.TopCentre, vector.NewVec2d(1, height+noteHeight), false, false, 0, container.skinInfo.ColourColumnLine.Mul(color2.NewLA(1, 0.15)), false, pixel)
+		}
+	}
+
+	if container.skinInfo.JudgementLine {
+		batch.DrawStObject(vector.NewVec2d(left, objects.ManiaHitPosition), vector.CentreLeft, vector.NewVec2d(width, 2), false, false, 0, container.skinInfo.ColourJudgementLine, false, pixel)
+	}
+
+	for _, note := range container.notes {
+		container.drawNote(batch, note, time, noteHeight)
+	}
+
+	batch.SetColor(1, 1, 1, 1)
+	batch.End()
+}
+
+func (container *ManiaContainer) drawNote(batch *batch.QuadBatch, note *objects.ManiaNote, time, noteHeight float64) {
+	pixel := graphics.Pixel.GetRegion()
+	state := note.GetState()
+
+	x := objects.GetManiaColumnX(note.Column, container.keys)
+	y := container.noteY(note.GetStartTime(), time)
+
+	color := container.columnColor(note.Column)
+	if state.Missed {
+		color.A = maniaMissedAlpha
+	}
+
+	if note.IsHold() {
+		// Held hold's head stays on the judgement line
+		if state.Holding {
+			y = math.Min(y, objects.ManiaHitPosition)
+		}
+
+		endY := math.Max(container.noteY(note.GetEndTime(), time), maniaStageTop)
+
+		if y > endY {
+			body := color
+			body.A *= 0.6
+
+			batch.DrawStObject(vector.NewVec2d(x, y), vector.BottomCentre, vector.NewVec2d(container.columnWidth*0.8, y-endY), false, false, 0, body, false, pixel)
+		}
+
+		batch.DrawStObject(vector.NewVec2d(x, endY), vector.BottomCentre, vector.NewVec2d(container.columnWidth-2, noteHeight/2), false, false, 0, color, false, pixel)
+	}
+
+	if y < maniaStageTop {
+		return
+	}
+
+	batch.DrawStObject(vector.NewVec2d(x, y), vector.BottomCentre, vector.NewVec2d(container.columnWidth-2, noteHeight), false, false, 0, color, false, pixel)
+}
+
+func (container *ManiaContainer) GetNumProcessed() int {
+	return len(container.notes)
+}
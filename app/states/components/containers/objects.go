@@ -18,6 +18,13 @@ import (
 	"sort"
 )
 
+// ObjectContainer updates and draws beatmap's objects
+type ObjectContainer interface {
+	Update(time float64)
+	Draw(batch *batch.QuadBatch, baseCamera mgl32.Mat4, cameras []mgl32.Mat4, time float64, scale, alpha float32)
+	GetNumProcessed() int
+}
+
 type renderableProxy struct {
 	renderable   objects.Renderable
 	isSliderBody bool
@@ -0,0 +1,143 @@
+package play
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/sprite"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"github.com/wieku/danser-go/framework/math/animation/easing"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math/rand"
+)
+
+const (
+	burstSlideTime = 700.0
+	burstFadeTime  = 800.0
+)
+
+// ComboBurst shows skin's comboburst images from the side of the screen when combo reaches a milestone
+type ComboBurst struct {
+	bursts []*texture.TextureRegion
+	sound  *bass.Sample
+
+	sprites *sprite.Manager
+
+	nextBurst int
+	rightSide bool
+
+	generator *rand.Rand
+
+	time float64
+
+	audioDisabled bool
+
+	ScaledWidth  float64
+	ScaledHeight float64
+}
+
+// NewComboBurst creates combo bursts, seed makes random bursts of ComboBurstRandom repeatable
+func NewComboBurst(seed int64) *ComboBurst {
+	burst := &ComboBurst{
+		bursts:    skin.GetFrames("comboburst", true),
+		sound:     audio.LoadSample("comboburst"),
+		sprites:   sprite.NewManager(),
+		generator: rand.New(rand.NewSource(seed)),
+	}
+
+	burst.ScaledHeight = 768
+	burst.ScaledWidth = settings.Graphics.GetAspectRatio() * burst.ScaledHeight
+
+	return burst
+}
+
+// isMilestone returns true for combos that show a burst: 30, 60 and every 50 from 100
+func isMilestone(combo int) bool {
+	return combo == 30 || combo == 60 || (combo >= 100 && combo%50 == 0)
+}
+
+// isSoundMilestone uses skin's CustomComboBurstSounds if present, otherwise sound is played with every burst
+func isSoundMilestone(combo int) bool {
+	custom := skin.GetInfo().CustomComboBurstSounds
+	if len(custom) == 0 {
+		return isMilestone(combo)
+	}
+
+	for _, c := range custom {
+		if c == combo {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpdateCombo has to be called every time the combo changes
+func (burst *ComboBurst) UpdateCombo(combo int) {
+	if !settings.Gameplay.ShowComboBursts {
+		return
+	}
+
+	if isSoundMilestone(combo) && burst.sound != nil && !burst.audioDisabled {
+		burst.sound.Play()
+	}
+
+	if !isMilestone(combo) || len(burst.bursts) == 0 {
+		return
+	}
+
+	index := burst.nextBurst
+	if skin.GetInfo().ComboBurstRandom {
+		index = burst.generator.Intn(len(burst.bursts))
+	}
+
+	burst.nextBurst = (burst.nextBurst + 1) % len(burst.bursts)
+
+	tex := burst.bursts[index]
+
+	origin := vector.BottomLeft
+	from, to := -float64(tex.Width)*0.5, 0.0
+
+	if burst.rightSide {
+		origin = vector.BottomRight
+		from, to = burst.ScaledWidth-from, burst.ScaledWidth
+	}
+
+	burstSprite := sprite.NewSpriteSingle(tex, 0, vector.NewVec2d(from, burst.ScaledHeight), origin)
+	burstSprite.SetHFlip(burst.rightSide)
+	burstSprite.SetAlpha(0)
+
+	burstSprite.AddTransform(animation.NewSingleTransform(animation.MoveX, easing.OutQuad, burst.time, burst.time+burstSlideTime, from, to))
+	burstSprite.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, burst.time, burst.time+burstSlideTime/4, 0, 1))
+	burstSprite.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, burst.time+burstSlideTime, burst.time+burstSlideTime+burstFadeTime, 1, 0))
+	burstSprite.AdjustTimesToTransformations()
+
+	burst.sprites.Add(burstSprite)
+
+	burst.rightSide = !burst.rightSide
+}
+
+func (burst *ComboBurst) DisableAudioSubmission(b bool) {
+	burst.audioDisabled = b
+}
+
+func (burst *ComboBurst) Update(time float64) {
+	burst.time = time
+	burst.sprites.Update(time)
+}
+
+func (burst *ComboBurst) Draw(batch *batch.QuadBatch, alpha float64) {
+	if !settings.Gameplay.ShowComboBursts {
+		return
+	}
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha)
+
+	burst.sprites.Draw(burst.time, batch)
+
+	batch.SetColor(1, 1, 1, 1)
+}
@@ -0,0 +1,87 @@
+package play
+
+import (
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+)
+
+const integrityFontSize = 20.0
+
+type IntegrityDisplay struct {
+	font   *font.Font
+	report *dance.IntegrityReport
+
+	title string
+	lines []string
+}
+
+func NewIntegrityDisplay(report *dance.IntegrityReport) *IntegrityDisplay {
+	return &IntegrityDisplay{
+		font:   font.GetFont("HUDFont"),
+		report: report,
+	}
+}
+
+func (display *IntegrityDisplay) Update(_ float64) {
+	if !settings.Gameplay.IntegrityReport.Show {
+		return
+	}
+
+	display.title = "Replay integrity: OK"
+	if display.report.IsSuspicious() {
+		display.title = "Replay integrity: SUSPICIOUS"
+	}
+
+	display.lines = display.report.Summary()
+}
+
+func (display *IntegrityDisplay) Draw(batch *batch.QuadBatch, alpha float64) {
+	batch.ResetTransform()
+
+	iAlpha := settings.Gameplay.IntegrityReport.Opacity * alpha
+
+	if iAlpha < 0.001 || !settings.Gameplay.IntegrityReport.Show || display.title == "" {
+		return
+	}
+
+	scale := settings.Gameplay.IntegrityReport.Scale
+	size := integrityFontSize * scale
+
+	width := display.font.GetWidth(size, display.title)
+	for _, line := range display.lines {
+		width = math.Max(width, display.font.GetWidth(size, line))
+	}
+
+	height := size * float64(len(display.lines)+1)
+
+	position := vector.NewVec2d(settings.Gameplay.IntegrityReport.XPosition, settings.Gameplay.IntegrityReport.YPosition)
+	origin := vector.ParseOrigin(settings.Gameplay.IntegrityReport.Align)
+
+	position = position.Add(origin.AddS(1, 1).Mult(vector.NewVec2d(-width/2, -height/2)))
+
+	titleColor := color2.NewLA(1, float32(iAlpha))
+	if display.report.IsSuspicious() {
+		titleColor = color2.NewRGBA(1, 0.3, 0.3, float32(iAlpha))
+	}
+
+	display.drawLine(batch, display.title, position, size, scale, titleColor)
+
+	for i, line := range display.lines {
+		display.drawLine(batch, line, position.AddS(0, size*float64(i+1)), size, scale, color2.NewLA(1, float32(iAlpha)))
+	}
+
+	batch.ResetTransform()
+}
+
+func (display *IntegrityDisplay) drawLine(batch *batch.QuadBatch, text string, position vector.Vector2d, size, scale float64, color color2.Color) {
+	batch.SetColor(0, 0, 0, float64(color.A)*0.8)
+	display.font.DrawOriginV(batch, position.AddS(scale, scale), vector.TopLeft, size, false, text)
+
+	batch.SetColorM(color)
+	display.font.DrawOriginV(batch, position, vector.TopLeft, size, false, text)
+}
@@ -2,9 +2,10 @@ package play
 
 import (
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	"github.com/wieku/danser-go/app/rulesets/osu"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/graphics/batch"
 	"github.com/wieku/danser-go/framework/graphics/buffer"
@@ -21,7 +22,7 @@ import (
 
 type StrainGraph struct {
 	shapeRenderer *shape.Renderer
-	strains       pp220930.StrainPeaks
+	strains       performance.StrainPeaks
 	baseLine      float64
 	maxStrain     float32
 	time          float64
@@ -45,12 +46,16 @@ type StrainGraph struct {
 func NewStrainGraph(ruleset *osu.OsuRuleSet) *StrainGraph {
 	graph := &StrainGraph{
 		shapeRenderer: shape.NewRenderer(),
-		strains:       pp220930.CalculateStrainPeaks(ruleset.GetBeatMap().HitObjects, ruleset.GetBeatMap().Diff),
 		startTime:     ruleset.GetBeatMap().HitObjects[mutils.Min(1, len(ruleset.GetBeatMap().HitObjects)-1)].GetStartTime(),
 		endTime:       ruleset.GetBeatMap().HitObjects[len(ruleset.GetBeatMap().HitObjects)-1].GetStartTime(),
 		screenWidth:   768 * settings.Graphics.GetAspectRatio(),
 	}
 
+	// pp calculators support only osu!standard, graph is hidden without strains
+	if ruleset.GetBeatMap().Mode != beatmap.ModeMania {
+		graph.strains = ruleset.GetPPCalculator().CalculateStrainPeaks(ruleset.GetBeatMap().HitObjects, ruleset.GetBeatMap().Diff)
+	}
+
 	// Those magic numbers are derived from sr formula with all difficulty values being 0 (e.g. at breaks)
 	graph.baseLine = 0.1401973407499798
 	if ruleset.GetBeatMap().Diff.CheckModActive(difficulty.Flashlight) {
@@ -196,7 +201,7 @@ func (graph *StrainGraph) Draw(batch *batch.QuadBatch, alpha float64) {
 
 	sgAlpha := conf.Opacity * alpha
 
-	if sgAlpha < 0.001 || !conf.Show {
+	if sgAlpha < 0.001 || !conf.Show || len(graph.strains.Total) == 0 {
 		return
 	}
 
@@ -8,11 +8,12 @@ import (
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	"github.com/wieku/danser-go/app/beatmap/objects"
 	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/dance"
 	"github.com/wieku/danser-go/app/discord"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/input"
 	"github.com/wieku/danser-go/app/rulesets/osu"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/skin"
 	"github.com/wieku/danser-go/app/states/components/common"
@@ -31,6 +32,7 @@ import (
 	color2 "github.com/wieku/danser-go/framework/math/color"
 	"github.com/wieku/danser-go/framework/math/mutils"
 	"github.com/wieku/danser-go/framework/math/vector"
+	"hash/fnv"
 	"log"
 	"math"
 	"os"
@@ -107,6 +109,7 @@ type ScoreOverlay struct {
 	oldGrade osu.Grade
 
 	comboCounter *play.ComboCounter
+	comboBurst   *play.ComboBurst
 
 	hpBar *play.HpBar
 
@@ -127,6 +130,9 @@ type ScoreOverlay struct {
 	ppDisplay   *play.PPDisplay
 	strainGraph *play.StrainGraph
 
+	integrityReport  *dance.IntegrityReport
+	integrityDisplay *play.IntegrityDisplay
+
 	underlay *sprite.Sprite
 	failed   bool
 }
@@ -218,7 +224,7 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	overlay.scoreFont = skin.GetFont("score")
 	overlay.circularMetre = skin.GetTextureSource("circularmetre", skin.LOCAL)
 
-	ruleset.SetListener(overlay.hitReceived)
+	ruleset.AddListener(overlay.hitReceived)
 
 	overlay.camera = camera2.NewCamera()
 	overlay.camera.SetViewportF(0, int(overlay.ScaledHeight), int(overlay.ScaledWidth), 0)
@@ -270,6 +276,11 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	}
 
 	overlay.comboCounter = play.NewComboCounter()
+	// Random combo bursts are the same every time the beatmap is played
+	burstSeed := fnv.New64a()
+	_, _ = burstSeed.Write([]byte(overlay.ruleset.GetBeatMap().MD5))
+
+	overlay.comboBurst = play.NewComboBurst(int64(burstSeed.Sum64()))
 
 	overlay.hpBar = play.NewHpBar()
 
@@ -324,7 +335,7 @@ func (overlay *ScoreOverlay) initUnderlay() {
 	overlay.underlay.SetScale(uScale)
 }
 
-func (overlay *ScoreOverlay) hitReceived(c *graphics.Cursor, time int64, number int64, position vector.Vector2d, result osu.HitResult, comboResult osu.ComboResult, ppResults pp220930.PPv2Results, _ int64) {
+func (overlay *ScoreOverlay) hitReceived(c *graphics.Cursor, time int64, number int64, position vector.Vector2d, result osu.HitResult, comboResult osu.ComboResult, ppResults performance.PPv2Results, _ int64) {
 	object := overlay.ruleset.GetBeatMap().HitObjects[number]
 
 	if result&(osu.BaseHitsM) > 0 {
@@ -341,6 +352,10 @@ func (overlay *ScoreOverlay) hitReceived(c *graphics.Cursor, time int64, number
 
 		overlay.hitErrorMeter.Add(float64(time), timeDiff, result == osu.PositionalMiss)
 
+		if overlay.integrityReport != nil && result != osu.PositionalMiss {
+			overlay.integrityReport.AddHitError(timeDiff)
+		}
+
 		var startPos *vector.Vector2f
 		if number > 0 {
 			pos := overlay.ruleset.GetBeatMap().HitObjects[number-1].GetStackedEndPositionMod(overlay.ruleset.GetBeatMap().Diff.Mods)
@@ -358,6 +373,7 @@ func (overlay *ScoreOverlay) hitReceived(c *graphics.Cursor, time int64, number
 
 	if comboResult == osu.Increase {
 		overlay.comboCounter.Increase()
+		overlay.comboBurst.UpdateCombo(overlay.comboCounter.GetCombo())
 	} else if comboResult == osu.Reset {
 		overlay.comboCounter.Reset()
 	}
@@ -392,6 +408,12 @@ func (overlay *ScoreOverlay) hitReceived(c *graphics.Cursor, time int64, number
 	}
 }
 
+// SetIntegrityReport attaches the report of played replay, it's filled with hit errors and the final score as the replay goes
+func (overlay *ScoreOverlay) SetIntegrityReport(report *dance.IntegrityReport) {
+	overlay.integrityReport = report
+	overlay.integrityDisplay = play.NewIntegrityDisplay(report)
+}
+
 func (overlay *ScoreOverlay) Update(time float64) {
 	if overlay.audioTime == 0 {
 		overlay.audioTime = time
@@ -489,6 +511,7 @@ func (overlay *ScoreOverlay) updateNormal(time float64) {
 	overlay.mods.Update(time)
 
 	overlay.comboCounter.Update(time)
+	overlay.comboBurst.Update(time)
 
 	overlay.hpBar.SetHp(overlay.ruleset.GetHP(overlay.cursor))
 	overlay.hpBar.Update(time)
@@ -500,6 +523,14 @@ func (overlay *ScoreOverlay) updateNormal(time float64) {
 	overlay.ppDisplay.Update(time)
 	overlay.hitCounts.Update(time)
 
+	if overlay.integrityReport != nil {
+		if !overlay.integrityReport.ScoreChecked && overlay.ruleset.IsEnded() {
+			overlay.integrityReport.SetScore(overlay.ruleset.GetScore(overlay.cursor))
+		}
+
+		overlay.integrityDisplay.Update(time)
+	}
+
 	var currentStates [4]bool
 	if !overlay.failed {
 		currentStates = [4]bool{overlay.cursor.LeftKey, overlay.cursor.RightKey, overlay.cursor.LeftMouse && !overlay.cursor.LeftKey, overlay.cursor.RightMouse && !overlay.cursor.RightKey}
@@ -634,6 +665,8 @@ func (overlay *ScoreOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, a
 
 	overlay.passContainer.Draw(overlay.audioTime, batch)
 
+	overlay.comboBurst.Draw(batch, alpha)
+
 	overlay.drawScore(batch, alpha)
 	overlay.comboCounter.Draw(batch, alpha)
 	overlay.hpBar.Draw(batch, alpha)
@@ -663,6 +696,10 @@ func (overlay *ScoreOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, a
 	overlay.strainGraph.Draw(batch, alpha)
 	overlay.hitCounts.Draw(batch, alpha)
 
+	if overlay.integrityDisplay != nil {
+		overlay.integrityDisplay.Draw(batch, alpha)
+	}
+
 	if overlay.panel != nil {
 		settings.Playfield.Bloom.Enabled = false
 		overlay.panel.Draw(batch, overlay.resultsFade.GetValue())
@@ -1003,6 +1040,7 @@ func (overlay *ScoreOverlay) DisableAudioSubmission(b bool) {
 	overlay.audioDisabled = b
 
 	overlay.comboCounter.DisableAudioSubmission(b)
+	overlay.comboBurst.DisableAudioSubmission(b)
 }
 
 func (overlay *ScoreOverlay) SetBeatmapEnd(end float64) {
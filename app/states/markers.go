@@ -0,0 +1,99 @@
+package states
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/vector"
+)
+
+// recordingMarkers collects misses, slider breaks, fails and breaks, and passes them to the encoder as chapters and subtitles
+type recordingMarkers struct {
+	ruleset *osu.OsuRuleSet
+	combos  map[*graphics.Cursor]uint
+
+	pauses     []*beatmap.Pause
+	pauseIndex int
+	inPause    bool
+}
+
+func (player *Player) setupMarkers() {
+	markers := &recordingMarkers{
+		combos: make(map[*graphics.Cursor]uint),
+	}
+
+	if rC, ok := player.controller.(*dance.ReplayController); ok {
+		markers.ruleset = rC.GetRuleset()
+	} else if rP, ok := player.controller.(*dance.PlayerController); ok {
+		markers.ruleset = rP.GetRuleset()
+	}
+
+	if markers.ruleset != nil {
+		markers.ruleset.AddListener(markers.hitReceived)
+		markers.ruleset.AddFailListener(markers.failReceived)
+	}
+
+	if settings.Recording.Markers.Breaks {
+		for _, p := range player.bMap.Pauses {
+			// Same breaks as the ones that dim the background
+			if p.Length() < 1000*settings.SPEED || p.GetStartTime() < player.startPoint || p.GetStartTime() > player.MapEnd {
+				continue
+			}
+
+			markers.pauses = append(markers.pauses, p)
+		}
+	}
+
+	player.markers = markers
+}
+
+func (markers *recordingMarkers) hitReceived(cursor *graphics.Cursor, _ int64, _ int64, _ vector.Vector2d, result osu.HitResult, comboResult osu.ComboResult, _ performance.PPv2Results, _ int64) {
+	combo := markers.combos[cursor]
+
+	switch comboResult {
+	case osu.Increase:
+		markers.combos[cursor]++
+	case osu.Reset:
+		markers.combos[cursor] = 0
+	}
+
+	if result&osu.Miss > 0 {
+		if settings.Recording.Markers.Misses {
+			ffmpeg.AddMarker(fmt.Sprintf("Miss #%d by %s at combo %d", markers.ruleset.GetScore(cursor).CountMiss, cursor.Name, combo), true)
+		}
+	} else if comboResult == osu.Reset && combo > 0 && settings.Recording.Markers.ComboBreaks {
+		ffmpeg.AddMarker(fmt.Sprintf("Slider break #%d by %s at combo %d", markers.ruleset.GetScore(cursor).CountSB, cursor.Name, combo), true)
+	}
+}
+
+func (markers *recordingMarkers) failReceived(cursor *graphics.Cursor) {
+	if settings.Recording.Markers.Fails {
+		ffmpeg.AddMarker(fmt.Sprintf("%s failed", cursor.Name), true)
+	}
+}
+
+func (markers *recordingMarkers) Update(time float64) {
+	if markers.pauseIndex >= len(markers.pauses) {
+		return
+	}
+
+	pause := markers.pauses[markers.pauseIndex]
+
+	if !markers.inPause && time >= pause.GetStartTime() {
+		ffmpeg.AddMarker(fmt.Sprintf("Break #%d", markers.pauseIndex+1), true)
+
+		markers.inPause = true
+	}
+
+	if markers.inPause && time >= pause.GetEndTime() {
+		ffmpeg.AddMarker(fmt.Sprintf("End of break #%d", markers.pauseIndex+1), false)
+
+		markers.inPause = false
+		markers.pauseIndex++
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"github.com/wieku/danser-go/app/audio"
 	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
 	camera2 "github.com/wieku/danser-go/app/bmath/camera"
 	"github.com/wieku/danser-go/app/dance"
 	"github.com/wieku/danser-go/app/discord"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/input"
 	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/states/components/common"
 	"github.com/wieku/danser-go/app/states/components/containers"
@@ -32,6 +34,7 @@ import (
 	"github.com/wieku/danser-go/framework/math/vector"
 	"github.com/wieku/danser-go/framework/qpc"
 	"github.com/wieku/danser-go/framework/statistic"
+	"github.com/wieku/rplpa"
 	"log"
 	"math"
 	"math/rand"
@@ -86,6 +89,11 @@ type Player struct {
 
 	coin *common.DanserCoin
 
+	// Storyboard's HitSound triggers checked by time, used when there's no ruleset
+	hitSoundTriggers bool
+	hitSoundObject   int
+	hitSoundTime     float64
+
 	hudGlider *animation.Glider
 
 	volumeGlider    *animation.Glider
@@ -100,7 +108,7 @@ type Player struct {
 	updateLimiter *frame.Limiter
 
 	objectsAlpha    *animation.Glider
-	objectContainer *containers.HitObjectContainer
+	objectContainer containers.ObjectContainer
 
 	MapEnd      float64
 	RunningTime float64
@@ -123,6 +131,10 @@ type Player struct {
 	failing bool
 	failAt  float64
 	failed  bool
+
+	replaySaved bool
+
+	markers *recordingMarkers
 }
 
 func NewPlayer(beatMap *beatmap.BeatMap) *Player {
@@ -250,7 +262,13 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		player.controller.InitCursors()
 
 		if settings.PLAYERS == 1 {
-			player.overlay = overlays.NewScoreOverlay(player.controller.(*dance.ReplayController).GetRuleset(), player.controller.GetCursors()[0])
+			scoreOverlay := overlays.NewScoreOverlay(controller.(*dance.ReplayController).GetRuleset(), player.controller.GetCursors()[0])
+
+			if report := controller.(*dance.ReplayController).GetIntegrityReport(0); report != nil {
+				scoreOverlay.SetIntegrityReport(report)
+			}
+
+			player.overlay = scoreOverlay
 		} else {
 			player.overlay = overlays.NewKnockoutOverlay(controller.(*dance.ReplayController))
 		}
@@ -262,7 +280,11 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 	player.lastTime = -1
 
-	player.objectContainer = containers.NewHitObjectContainer(beatMap)
+	if beatMap.Mode == beatmap.ModeMania {
+		player.objectContainer = containers.NewManiaContainer(beatMap, player.controller.GetCursors())
+	} else {
+		player.objectContainer = containers.NewHitObjectContainer(beatMap)
+	}
 
 	player.Scl = 1
 	player.fadeOut = 1.0
@@ -293,6 +315,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	player.failRotation = animation.NewGlider(0)
 
 	player.trySetupFail()
+	player.setupStoryboardTriggers()
 
 	preempt := math.Min(1800, beatMap.Diff.Preempt)
 
@@ -483,6 +506,10 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		player.nightcore.SetMap(player.bMap, player.musicPlayer)
 	}
 
+	if settings.RECORD && settings.Recording.Markers.IsEnabled() {
+		player.setupMarkers()
+	}
+
 	if settings.RECORD {
 		return player
 	}
@@ -559,7 +586,7 @@ func (player *Player) trySetupFail() {
 		}
 
 		if ruleset != nil {
-			ruleset.SetFailListener(func(cursor *graphics.Cursor) {
+			ruleset.AddFailListener(func(cursor *graphics.Cursor) {
 				if !settings.RECORD {
 					audio.PlayFailSound()
 				}
@@ -596,6 +623,121 @@ func (player *Player) trySetupFail() {
 	}
 }
 
+func (player *Player) setupStoryboardTriggers() {
+	storyboard := player.background.GetStoryboard()
+	if storyboard == nil {
+		return
+	}
+
+	var ruleset *osu.OsuRuleSet
+
+	if rC, ok := player.controller.(*dance.ReplayController); ok {
+		ruleset = rC.GetRuleset()
+	} else if rP, ok := player.controller.(*dance.PlayerController); ok {
+		ruleset = rP.GetRuleset()
+	}
+
+	if ruleset == nil {
+		// There are no judgements, so edges of objects are checked in updateHitSoundTriggers
+		player.hitSoundTriggers = true
+		player.hitSoundTime = math.Inf(-1)
+
+		return
+	}
+
+	mainCursor := player.controller.GetCursors()[0]
+
+	sliderEdges := make(map[int64]int)
+
+	ruleset.AddListener(func(cursor *graphics.Cursor, time int64, number int64, _ vector.Vector2d, result osu.HitResult, _ osu.ComboResult, _ performance.PPv2Results, _ int64) {
+		if cursor != mainCursor {
+			return
+		}
+
+		obj, ok := player.bMap.HitObjects[number].(objects.IHasHitSound)
+		if !ok {
+			return
+		}
+
+		var edge int
+
+		switch {
+		case result&osu.SliderStart > 0:
+			edge = 0
+		case result&osu.SliderRepeat > 0:
+			sliderEdges[number]++
+			edge = sliderEdges[number]
+		case result&osu.BaseHits > 0:
+			edge = obj.GetEdgeCount() - 1
+		default:
+			return
+		}
+
+		sampleSet, additionSet, hitsound, index := obj.GetHitSound(mutils.Min(edge, obj.GetEdgeCount()-1))
+
+		storyboard.FireHitSound(float64(time), sampleSet, additionSet, hitsound, index)
+	})
+
+	ruleset.SetPassingListener(func(cursor *graphics.Cursor, time int64, passing bool) {
+		if cursor == mainCursor {
+			storyboard.SetPassing(float64(time), passing)
+		}
+	})
+}
+
+// updateHitSoundTriggers fires storyboard's HitSound triggers at edges of objects passed since the last update
+func (player *Player) updateHitSoundTriggers(time float64) {
+	storyboard := player.background.GetStoryboard()
+
+	for i := player.hitSoundObject; i < len(player.bMap.HitObjects); i++ {
+		o := player.bMap.HitObjects[i]
+		if o.GetStartTime() > time {
+			break
+		}
+
+		if obj, ok := o.(objects.IHasHitSound); ok {
+			for edge := 0; edge < obj.GetEdgeCount(); edge++ {
+				edgeTime := obj.GetEdgeTime(edge)
+
+				if edgeTime > player.hitSoundTime && edgeTime <= time && edgeTime >= player.startPoint {
+					sampleSet, additionSet, hitsound, index := obj.GetHitSound(edge)
+
+					storyboard.FireHitSound(edgeTime, sampleSet, additionSet, hitsound, index)
+				}
+			}
+		}
+
+		if i == player.hitSoundObject && o.GetEndTime() <= time {
+			player.hitSoundObject++
+		}
+	}
+
+	player.hitSoundTime = time
+}
+
+func (player *Player) saveReplay() {
+	player.replaySaved = true
+
+	exporter, ok := player.controller.(dance.ReplayExporter)
+	if !ok {
+		return
+	}
+
+	exporter.ExportReplay(player.failed, func(replay *rplpa.Replay) {
+		if replay == nil {
+			return
+		}
+
+		path, err := dance.SaveReplay(player.bMap, replay)
+		if err != nil {
+			log.Println("Failed to save replay:", err)
+			return
+		}
+
+		log.Println("Replay saved to:", path)
+	})
+}
+
 func (player *Player) Update(delta float64) bool {
 	speed := 1.0
 
@@ -626,6 +768,10 @@ func (player *Player) Update(delta float64) bool {
 	return false
 }
 
+func (player *Player) GetBeatMap() *beatmap.BeatMap {
+	return player.bMap
+}
+
 func (player *Player) GetTime() float64 {
 	return player.progressMsF
 }
@@ -686,6 +832,10 @@ func (player *Player) updateMain(delta float64) {
 			player.bMap.Update(player.progressMsF)
 		}
 
+		if player.hitSoundTriggers {
+			player.updateHitSoundTriggers(player.progressMsF)
+		}
+
 		player.objectContainer.Update(player.progressMsF)
 	}
 
@@ -714,6 +864,14 @@ func (player *Player) updateMain(delta float64) {
 		player.overlay.Update(player.progressMsF)
 	}
 
+	if player.markers != nil {
+		player.markers.Update(player.progressMsF)
+	}
+
+	if settings.SAVEREPLAY && !player.replaySaved && (player.failed || player.progressMsF >= player.mapEndL) {
+		player.saveReplay()
+	}
+
 	player.updateMusic(delta)
 
 	player.coin.Update(player.progressMsF)
@@ -853,7 +1011,8 @@ func (player *Player) Draw(float64) {
 		player.drawOverlayPart(player.overlay.DrawHUD, cursorColors, player.uiCamera.GetProjectionView(), 1)
 	}
 
-	if settings.Playfield.DrawCursors {
+	// osu!mania has no cursor to show
+	if settings.Playfield.DrawCursors && player.bMap.Mode != beatmap.ModeMania {
 		for _, g := range player.controller.GetCursors() {
 			g.UpdateRenderer()
 		}
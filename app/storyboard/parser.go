@@ -22,12 +22,15 @@ func cutWhites(text string) (string, int) {
 	return text, 0
 }
 
-func parseCommands(commands []string) []*animation.Transformation {
+func parseCommands(commands []string) ([]*animation.Transformation, []*TriggerProcessor) {
 	transforms := make([]*animation.Transformation, 0)
+	triggers := make([]*TriggerProcessor, 0)
 
 	var currentLoop *LoopProcessor = nil
+	var currentTrigger *TriggerProcessor = nil
 
 	loopDepth := -1
+	triggerDepth := -1
 
 	for _, subCommand := range commands {
 		command := strings.Split(subCommand, ",")
@@ -35,10 +38,6 @@ func parseCommands(commands []string) []*animation.Transformation {
 		var removed int
 		command[0], removed = cutWhites(command[0])
 
-		if command[0] == "T" {
-			continue
-		}
-
 		if removed == 1 {
 			if currentLoop != nil {
 				transforms = append(transforms, currentLoop.Unwind()...)
@@ -47,7 +46,10 @@ func parseCommands(commands []string) []*animation.Transformation {
 				loopDepth = -1
 			}
 
-			if command[0] != "L" {
+			currentTrigger = nil
+			triggerDepth = -1
+
+			if command[0] != "L" && command[0] != "T" {
 				if parsed := parseCommand(command); parsed != nil {
 					transforms = append(transforms, parsed...)
 				}
@@ -57,8 +59,15 @@ func parseCommands(commands []string) []*animation.Transformation {
 		if command[0] == "L" {
 			currentLoop = NewLoopProcessor(command)
 			loopDepth = removed + 1
+		} else if command[0] == "T" {
+			if currentTrigger = NewTriggerProcessor(command); currentTrigger != nil {
+				triggers = append(triggers, currentTrigger)
+				triggerDepth = removed + 1
+			}
 		} else if removed == loopDepth && currentLoop != nil {
 			currentLoop.Add(command)
+		} else if removed == triggerDepth && currentTrigger != nil {
+			currentTrigger.Add(command)
 		}
 	}
 
@@ -66,7 +75,7 @@ func parseCommands(commands []string) []*animation.Transformation {
 		transforms = append(transforms, currentLoop.Unwind()...)
 	}
 
-	return transforms
+	return transforms, triggers
 }
 
 func parseCommand(data []string) []*animation.Transformation {
@@ -18,10 +18,12 @@ import (
 	"github.com/wieku/danser-go/framework/math/vector"
 	"github.com/wieku/danser-go/framework/qpc"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Storyboard struct {
@@ -31,6 +33,7 @@ type Storyboard struct {
 	samples map[string]*bass.Sample
 
 	background  *sprite.Manager
+	fail        *sprite.Manager
 	pass        *sprite.Manager
 	foreground  *sprite.Manager
 	overlay     *sprite.Manager
@@ -47,6 +50,11 @@ type Storyboard struct {
 
 	videos     []sprite.ISprite
 	videoAlpha float64
+
+	triggered     []*triggeredSprite
+	triggerEvents []triggerEvent
+	triggerMutex  *sync.Mutex
+	passing       bool
 }
 
 func getSection(line string) string {
@@ -71,11 +79,15 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 		samples:    make(map[string]*bass.Sample),
 		zIndex:     -1,
 		background: sprite.NewManager(),
+		fail:       sprite.NewManager(),
 		pass:       sprite.NewManager(),
 		foreground: sprite.NewManager(),
 		overlay:    sprite.NewManager(),
 		atlas:      nil,
 		videos:     make([]sprite.ISprite, 0),
+
+		triggerMutex: &sync.Mutex{},
+		passing:      true,
 	}
 
 	storyboard.pathCache, _ = files2.NewFileMap(path)
@@ -274,13 +286,32 @@ func (storyboard *Storyboard) loadSprite(currentSprite string, commands []string
 	if len(textures) != 0 {
 		sbSprite := sprite.NewAnimation(textures, frameDelay, loopForever, float64(storyboard.zIndex), pos, origin)
 
-		transforms := parseCommands(commands)
+		transforms, triggers := parseCommands(commands)
 
 		sbSprite.ShowForever(false)
 		sbSprite.AddTransforms(transforms)
 		sbSprite.AdjustTimesToTransformations()
 		sbSprite.ResetValuesToTransforms()
 
+		if len(triggers) > 0 {
+			tSprite := newTriggeredSprite(sbSprite, triggers)
+
+			// Sprite has to live as long as its triggers can run
+			startTime, endTime := tSprite.getTimeRange()
+
+			if len(transforms) == 0 {
+				sbSprite.SetAlpha(0) // Sprites with only triggers are hidden until triggered
+			} else {
+				startTime = math.Min(startTime, sbSprite.GetStartTime())
+				endTime = math.Max(endTime, sbSprite.GetEndTime())
+			}
+
+			sbSprite.SetStartTime(startTime)
+			sbSprite.SetEndTime(endTime)
+
+			storyboard.triggered = append(storyboard.triggered, tSprite)
+		}
+
 		storyboard.addSpriteToLayer(spl[1], sbSprite)
 
 		storyboard.numSprites++
@@ -291,6 +322,8 @@ func (storyboard *Storyboard) addSpriteToLayer(layer string, sbSprite sprite.ISp
 	switch layer {
 	case "0", "Background":
 		storyboard.background.Add(sbSprite)
+	case "1", "Fail":
+		storyboard.fail.Add(sbSprite)
 	case "2", "Pass":
 		storyboard.pass.Add(sbSprite)
 	case "3", "Foreground":
@@ -400,8 +433,65 @@ func (storyboard *Storyboard) SetFPS(i int) {
 	storyboard.limiter.FPS = i
 }
 
+// FireHitSound activates HitSound and HitObjectHit triggers, hitsound is a bitmask of played additions
+func (storyboard *Storyboard) FireHitSound(time float64, sampleSet, additionSet, hitsound, index int) {
+	storyboard.queueTriggerEvent(triggerEvent{
+		typ:         hitSoundTrigger,
+		time:        time,
+		sampleSet:   sampleSet,
+		additionSet: additionSet,
+		hitsound:    hitsound,
+		index:       index,
+	})
+}
+
+// SetPassing switches between Pass and Fail layers, activating Passing or Failing triggers
+func (storyboard *Storyboard) SetPassing(time float64, passing bool) {
+	typ := failingTrigger
+	if passing {
+		typ = passingTrigger
+	}
+
+	storyboard.queueTriggerEvent(triggerEvent{
+		typ:  typ,
+		time: time,
+	})
+}
+
+func (storyboard *Storyboard) queueTriggerEvent(event triggerEvent) {
+	storyboard.triggerMutex.Lock()
+	storyboard.triggerEvents = append(storyboard.triggerEvents, event)
+	storyboard.triggerMutex.Unlock()
+}
+
+func (storyboard *Storyboard) processTriggers() {
+	storyboard.triggerMutex.Lock()
+	events := storyboard.triggerEvents
+	storyboard.triggerEvents = nil
+	storyboard.triggerMutex.Unlock()
+
+	for _, event := range events {
+		switch event.typ {
+		case passingTrigger, failingTrigger:
+			passing := event.typ == passingTrigger
+			if passing == storyboard.passing {
+				continue
+			}
+
+			storyboard.passing = passing
+		}
+
+		for _, tSprite := range storyboard.triggered {
+			tSprite.fire(event)
+		}
+	}
+}
+
 func (storyboard *Storyboard) Update(time float64) {
+	storyboard.processTriggers()
+
 	storyboard.background.Update(time)
+	storyboard.fail.Update(time)
 	storyboard.pass.Update(time)
 	storyboard.foreground.Update(time)
 	storyboard.overlay.Update(time)
@@ -420,7 +510,13 @@ func (storyboard *Storyboard) Update(time float64) {
 func (storyboard *Storyboard) Draw(time float64, batch *batch.QuadBatch) {
 	batch.SetTranslation(vector.NewVec2d(-64, -48))
 	storyboard.background.Draw(time, batch)
-	storyboard.pass.Draw(time, batch)
+
+	if storyboard.passing {
+		storyboard.pass.Draw(time, batch)
+	} else {
+		storyboard.fail.Draw(time, batch)
+	}
+
 	storyboard.foreground.Draw(time, batch)
 	batch.SetTranslation(vector.NewVec2d(0, 0))
 }
@@ -432,15 +528,15 @@ func (storyboard *Storyboard) DrawOverlay(time float64, batch *batch.QuadBatch)
 }
 
 func (storyboard *Storyboard) GetRenderedSprites() int {
-	return storyboard.background.GetNumRendered() + storyboard.pass.GetNumRendered() + storyboard.foreground.GetNumRendered() + storyboard.overlay.GetNumRendered()
+	return storyboard.background.GetNumRendered() + storyboard.fail.GetNumRendered() + storyboard.pass.GetNumRendered() + storyboard.foreground.GetNumRendered() + storyboard.overlay.GetNumRendered()
 }
 
 func (storyboard *Storyboard) GetProcessedSprites() int {
-	return storyboard.background.GetNumProcessed() + storyboard.pass.GetNumProcessed() + storyboard.foreground.GetNumProcessed() + storyboard.overlay.GetNumProcessed()
+	return storyboard.background.GetNumProcessed() + storyboard.fail.GetNumProcessed() + storyboard.pass.GetNumProcessed() + storyboard.foreground.GetNumProcessed() + storyboard.overlay.GetNumProcessed()
 }
 
 func (storyboard *Storyboard) GetQueueSprites() int {
-	return storyboard.background.GetNumInQueue() + storyboard.pass.GetNumInQueue() + storyboard.foreground.GetNumInQueue() + storyboard.overlay.GetNumInQueue()
+	return storyboard.background.GetNumInQueue() + storyboard.fail.GetNumInQueue() + storyboard.pass.GetNumInQueue() + storyboard.foreground.GetNumInQueue() + storyboard.overlay.GetNumInQueue()
 }
 
 func (storyboard *Storyboard) GetTotalSprites() int {
@@ -0,0 +1,244 @@
+package storyboard
+
+import (
+	"github.com/wieku/danser-go/framework/graphics/sprite"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type triggerType int
+
+const (
+	hitSoundTrigger = triggerType(iota)
+	hitObjectHitTrigger
+	passingTrigger
+	failingTrigger
+)
+
+type triggerEvent struct {
+	typ  triggerType
+	time float64
+
+	sampleSet   int
+	additionSet int
+	hitsound    int
+	index       int
+}
+
+var sampleSetNames = map[string]int{
+	"All":    0,
+	"Normal": 1,
+	"Soft":   2,
+	"Drum":   3,
+}
+
+var additionNames = map[string]int{
+	"Whistle": 2,
+	"Finish":  4,
+	"Clap":    8,
+}
+
+type TriggerProcessor struct {
+	typ        triggerType
+	start, end float64
+	group      int64
+
+	// HitSound conditions, 0 (or -1 for index) means any
+	sampleSet   int
+	additionSet int
+	addition    int
+	index       int
+
+	transforms []*animation.Transformation
+	duration   float64
+}
+
+func NewTriggerProcessor(data []string) *TriggerProcessor {
+	trigger := &TriggerProcessor{index: -1}
+
+	var err error
+
+	trigger.start, err = strconv.ParseFloat(data[2], 64)
+	if err != nil {
+		log.Println("Failed to parse: ", data)
+		panic(err)
+	}
+
+	trigger.end, err = strconv.ParseFloat(data[3], 64)
+	if err != nil {
+		log.Println("Failed to parse: ", data)
+		panic(err)
+	}
+
+	if len(data) > 4 && strings.TrimSpace(data[4]) != "" {
+		trigger.group, err = strconv.ParseInt(strings.TrimSpace(data[4]), 10, 64)
+		if err != nil {
+			log.Println("Failed to parse: ", data)
+			panic(err)
+		}
+	}
+
+	name := strings.TrimSpace(data[1])
+
+	switch {
+	case name == "Passing":
+		trigger.typ = passingTrigger
+	case name == "Failing":
+		trigger.typ = failingTrigger
+	case name == "HitObjectHit":
+		trigger.typ = hitObjectHitTrigger
+	case strings.HasPrefix(name, "HitSound"):
+		trigger.typ = hitSoundTrigger
+		trigger.parseHitSoundConditions(strings.TrimPrefix(name, "HitSound"))
+	default:
+		log.Println("Unknown storyboard trigger: ", data)
+		return nil
+	}
+
+	return trigger
+}
+
+// parseHitSoundConditions parses HitSound[SampleSet][AdditionsSampleSet][Addition][CustomSampleSet]
+func (trigger *TriggerProcessor) parseHitSoundConditions(conditions string) {
+	setsParsed := 0
+
+	for conditions != "" {
+		found := false
+
+		if setsParsed < 2 {
+			for name, set := range sampleSetNames {
+				if strings.HasPrefix(conditions, name) {
+					if setsParsed == 0 {
+						trigger.sampleSet = set
+					} else {
+						trigger.additionSet = set
+					}
+
+					setsParsed++
+					conditions = strings.TrimPrefix(conditions, name)
+					found = true
+
+					break
+				}
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		for name, addition := range additionNames {
+			if strings.HasPrefix(conditions, name) {
+				trigger.addition = addition
+				setsParsed = 2
+				conditions = strings.TrimPrefix(conditions, name)
+				found = true
+
+				break
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if index, err := strconv.Atoi(conditions); err == nil {
+			trigger.index = index
+		} else {
+			log.Println("Unknown HitSound trigger condition: ", conditions)
+		}
+
+		break
+	}
+}
+
+func (trigger *TriggerProcessor) Add(command []string) {
+	if parsed := parseCommand(command); parsed != nil {
+		trigger.transforms = append(trigger.transforms, parsed...)
+
+		for _, t := range parsed {
+			trigger.duration = math.Max(trigger.duration, t.GetTotalEndTime())
+		}
+	}
+}
+
+func (trigger *TriggerProcessor) matches(event triggerEvent) bool {
+	if event.time < trigger.start || event.time > trigger.end {
+		return false
+	}
+
+	if event.typ != trigger.typ {
+		// Every played hitsound also means that hit object has been hit
+		return event.typ == hitSoundTrigger && trigger.typ == hitObjectHitTrigger
+	}
+
+	if trigger.typ != hitSoundTrigger {
+		return true
+	}
+
+	return (trigger.sampleSet == 0 || trigger.sampleSet == event.sampleSet) &&
+		(trigger.additionSet == 0 || trigger.additionSet == event.additionSet) &&
+		(trigger.addition == 0 || event.hitsound&trigger.addition > 0) &&
+		(trigger.index < 0 || trigger.index == event.index)
+}
+
+// Unwind creates transformations of the trigger activated at the given time
+func (trigger *TriggerProcessor) Unwind(time float64) []*animation.Transformation {
+	transforms := make([]*animation.Transformation, 0, len(trigger.transforms))
+
+	for _, t := range trigger.transforms {
+		transforms = append(transforms, t.Clone(time+t.GetStartTime(), time+t.GetEndTime()))
+	}
+
+	return transforms
+}
+
+type triggeredSprite struct {
+	sprite   *sprite.Animation
+	triggers []*TriggerProcessor
+
+	// Transformations added by currently running triggers, by trigger group
+	active map[int64][]*animation.Transformation
+}
+
+func newTriggeredSprite(sprite *sprite.Animation, triggers []*TriggerProcessor) *triggeredSprite {
+	return &triggeredSprite{
+		sprite:   sprite,
+		triggers: triggers,
+		active:   make(map[int64][]*animation.Transformation),
+	}
+}
+
+// getTimeRange returns the time range in which sprite's triggers can run
+func (tSprite *triggeredSprite) getTimeRange() (startTime, endTime float64) {
+	startTime = math.MaxFloat64
+	endTime = -math.MaxFloat64
+
+	for _, t := range tSprite.triggers {
+		startTime = math.Min(startTime, t.start)
+		endTime = math.Max(endTime, t.end+t.duration)
+	}
+
+	return
+}
+
+func (tSprite *triggeredSprite) fire(event triggerEvent) {
+	for _, t := range tSprite.triggers {
+		if !t.matches(event) {
+			continue
+		}
+
+		// Trigger activation cancels previous activations in the same group
+		if active := tSprite.active[t.group]; len(active) > 0 {
+			tSprite.sprite.RemoveTransforms(active)
+		}
+
+		transforms := t.Unwind(event.time)
+
+		tSprite.sprite.AddTransforms(transforms)
+		tSprite.active[t.group] = transforms
+	}
+}
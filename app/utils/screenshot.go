@@ -2,6 +2,7 @@ package utils
 
 import (
 	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/wieku/danser-go/app/events"
 	"github.com/wieku/danser-go/framework/env"
 	"github.com/wieku/danser-go/framework/graphics/texture"
 	"log"
@@ -22,6 +23,7 @@ func MakeScreenshot(w, h int, name string, async bool) {
 		err := os.Mkdir(filepath.Join(env.DataDir(), "screenshots"), 0755)
 		if err != nil && !os.IsExist(err) {
 			log.Println("Failed to save the screenshot! Error:", err)
+			events.EmitWarning("Failed to save the screenshot: " + err.Error())
 			return
 		}
 
@@ -36,10 +38,13 @@ func MakeScreenshot(w, h int, name string, async bool) {
 		err = pixmap.WritePng(filepath.Join(env.DataDir(), "screenshots", fileName), true)
 		if err != nil {
 			log.Println("Failed to save the screenshot! Error:", err)
+			events.EmitWarning("Failed to save the screenshot: " + err.Error())
 			return
 		}
 
 		log.Println("Screenshot", fileName, "saved!")
+
+		events.EmitOutput(events.OutputScreenshot, filepath.Join(env.DataDir(), "screenshots", fileName))
 	}
 
 	if async {
@@ -58,6 +58,12 @@ func LoadTextureToAtlas(atlas *texture.TextureAtlas, path string) (*texture.Text
 // Unzip will decompress a zip archive, moving all files and folders
 // within the zip file (parameter 1) to an output directory (parameter 2).
 func Unzip(src string, dest string) ([]string, error) {
+	return UnzipDir(src, dest, "")
+}
+
+// UnzipDir works like Unzip but extracts only the contents of given folder inside the archive, like "folder/".
+// Empty dir extracts the whole archive.
+func UnzipDir(src string, dest string, dir string) ([]string, error) {
 
 	var filenames []string
 
@@ -68,9 +74,12 @@ func Unzip(src string, dest string) ([]string, error) {
 	defer r.Close()
 
 	for _, f := range r.File {
+		if !strings.HasPrefix(f.Name, dir) || f.Name == dir {
+			continue
+		}
 
 		// Store filename/path for returning and using later on
-		fpath := filepath.Join(dest, f.Name)
+		fpath := filepath.Join(dest, strings.TrimPrefix(f.Name, dir))
 
 		// Check for ZipSlip. More Info: http://bit.ly/2MsjAWE
 		if !strings.HasPrefix(fpath, filepath.Clean(dest)+string(os.PathSeparator)) {
@@ -3,6 +3,7 @@ package effects
 import (
 	"github.com/wieku/danser-go/framework/assets"
 	"github.com/wieku/danser-go/framework/graphics/attribute"
+	"github.com/wieku/danser-go/framework/graphics/blend"
 	"github.com/wieku/danser-go/framework/graphics/buffer"
 	"github.com/wieku/danser-go/framework/graphics/shader"
 	"github.com/wieku/danser-go/framework/graphics/texture"
@@ -12,6 +13,7 @@ import (
 type Blend struct {
 	width        int
 	height       int
+	alpha        bool
 	layers       int
 	head         int
 	fbos         []*buffer.Framebuffer
@@ -20,7 +22,8 @@ type Blend struct {
 	multiTexture *texture.TextureMultiLayer
 }
 
-func NewBlend(width, height, frames int, weights []float32) *Blend {
+// NewBlend creates a motion blur effect blending given number of frames. If alpha is true, alpha channel is blended as well instead of being opaque.
+func NewBlend(width, height, frames int, weights []float32, alpha bool) *Blend {
 	if frames != len(weights) {
 		panic("Wrong number of weights")
 	}
@@ -28,6 +31,7 @@ func NewBlend(width, height, frames int, weights []float32) *Blend {
 	effect := new(Blend)
 	effect.width = width
 	effect.height = height
+	effect.alpha = alpha
 	effect.layers = frames
 
 	vert, err := assets.GetString("assets/shaders/fbopass.vsh")
@@ -71,7 +75,12 @@ func NewBlend(width, height, frames int, weights []float32) *Blend {
 		effect.blendShader.SetUniformArr("weights", i, v/sum)
 	}
 
-	effect.multiTexture = texture.NewTextureMultiLayerFormat(width, height, texture.RGB, 0, frames)
+	format := texture.RGB
+	if alpha {
+		format = texture.RGBA
+	}
+
+	effect.multiTexture = texture.NewTextureMultiLayerFormat(width, height, format, 0, frames)
 
 	for i := 0; i < frames; i++ {
 		effect.fbos = append(effect.fbos, buffer.NewFrameLayer(effect.multiTexture, i))
@@ -83,7 +92,13 @@ func NewBlend(width, height, frames int, weights []float32) *Blend {
 func (effect *Blend) Begin() {
 	effect.head = (effect.head + 1) % effect.layers
 	effect.fbos[effect.head].Bind()
-	effect.fbos[effect.head].ClearColor(0, 0, 0, 1)
+
+	if effect.alpha {
+		effect.fbos[effect.head].ClearColor(0, 0, 0, 0)
+	} else {
+		effect.fbos[effect.head].ClearColor(0, 0, 0, 1)
+	}
+
 	viewport.Push(effect.width, effect.height)
 }
 
@@ -99,11 +114,17 @@ func (effect *Blend) Blend() {
 
 	viewport.Push(effect.width, effect.height)
 
+	// Result has to replace the target, otherwise translucent pixels would be mixed with the previous frame
+	blend.Push()
+	blend.Disable()
+
 	effect.blendShader.Bind()
 	effect.vao.Bind()
 	effect.vao.Draw()
 	effect.vao.Unbind()
 	effect.blendShader.Unbind()
 
+	blend.Pop()
+
 	viewport.Pop()
 }
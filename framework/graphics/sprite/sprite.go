@@ -172,6 +172,16 @@ func (sprite *Sprite) ClearTransformationsOfType(transformationType animation.Tr
 	}
 }
 
+func (sprite *Sprite) RemoveTransforms(transformations []*animation.Transformation) {
+	for i := 0; i < len(sprite.transforms); i++ {
+		if slices.Contains(transformations, sprite.transforms[i]) {
+			copy(sprite.transforms[i:], sprite.transforms[i+1:])
+			sprite.transforms = sprite.transforms[:len(sprite.transforms)-1]
+			i--
+		}
+	}
+}
+
 func (sprite *Sprite) AdjustTimesToTransformations() {
 	if len(sprite.transforms) == 0 {
 		return
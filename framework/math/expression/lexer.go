@@ -0,0 +1,122 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenAssign
+)
+
+type token struct {
+	typ    tokenType
+	text   string
+	value  float64
+	column int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of line"
+	}
+
+	return fmt.Sprintf("\"%s\"", t.text)
+}
+
+var operators = []string{"||", "&&", "<=", ">=", "==", "!=", "<", ">", "+", "-", "*", "/", "%", "^", "!"}
+
+func tokenize(line string) ([]token, error) {
+	tokens := make([]token, 0)
+
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				j := i + 1
+				if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+					j++
+				}
+
+				if j < len(runes) && unicode.IsDigit(runes[j]) {
+					for i = j; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+					}
+				}
+			}
+
+			text := string(runes[start:i])
+
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: invalid number \"%s\"", start+1, text)
+			}
+
+			tokens = append(tokens, token{typ: tokenNumber, text: text, value: value, column: start + 1})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			tokens = append(tokens, token{typ: tokenIdent, text: string(runes[start:i]), column: start + 1})
+		case r == '(':
+			tokens = append(tokens, token{typ: tokenLParen, text: "(", column: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{typ: tokenRParen, text: ")", column: i + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{typ: tokenComma, text: ",", column: i + 1})
+			i++
+		default:
+			rest := string(runes[i:])
+
+			found := false
+
+			for _, op := range operators {
+				if strings.HasPrefix(rest, op) {
+					tokens = append(tokens, token{typ: tokenOperator, text: op, column: i + 1})
+					i += len([]rune(op))
+					found = true
+
+					break
+				}
+			}
+
+			if !found {
+				if r != '=' {
+					return nil, fmt.Errorf("column %d: unexpected character '%c'", i+1, r)
+				}
+
+				tokens = append(tokens, token{typ: tokenAssign, text: "=", column: i + 1})
+				i++
+			}
+		}
+	}
+
+	return append(tokens, token{typ: tokenEOF, column: len(runes) + 1}), nil
+}
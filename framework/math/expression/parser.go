@@ -0,0 +1,328 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+)
+
+type node func() float64
+
+type function struct {
+	minArgs int
+	maxArgs int // -1 means unlimited
+	call    func(args []float64) float64
+}
+
+func fixed(n int, call func(args []float64) float64) function {
+	return function{minArgs: n, maxArgs: n, call: call}
+}
+
+var constants = map[string]float64{
+	"pi":  math.Pi,
+	"tau": 2 * math.Pi,
+	"e":   math.E,
+}
+
+var functions = map[string]function{
+	"sin":   fixed(1, func(a []float64) float64 { return math.Sin(a[0]) }),
+	"cos":   fixed(1, func(a []float64) float64 { return math.Cos(a[0]) }),
+	"tan":   fixed(1, func(a []float64) float64 { return math.Tan(a[0]) }),
+	"asin":  fixed(1, func(a []float64) float64 { return math.Asin(a[0]) }),
+	"acos":  fixed(1, func(a []float64) float64 { return math.Acos(a[0]) }),
+	"atan":  fixed(1, func(a []float64) float64 { return math.Atan(a[0]) }),
+	"atan2": fixed(2, func(a []float64) float64 { return math.Atan2(a[0], a[1]) }),
+	"sqrt":  fixed(1, func(a []float64) float64 { return math.Sqrt(a[0]) }),
+	"abs":   fixed(1, func(a []float64) float64 { return math.Abs(a[0]) }),
+	"floor": fixed(1, func(a []float64) float64 { return math.Floor(a[0]) }),
+	"ceil":  fixed(1, func(a []float64) float64 { return math.Ceil(a[0]) }),
+	"round": fixed(1, func(a []float64) float64 { return math.Round(a[0]) }),
+	"exp":   fixed(1, func(a []float64) float64 { return math.Exp(a[0]) }),
+	"log":   fixed(1, func(a []float64) float64 { return math.Log(a[0]) }),
+	"pow":   fixed(2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }),
+	"hypot": fixed(2, func(a []float64) float64 { return math.Hypot(a[0], a[1]) }),
+	"sign": fixed(1, func(a []float64) float64 {
+		if a[0] > 0 {
+			return 1
+		} else if a[0] < 0 {
+			return -1
+		}
+
+		return 0
+	}),
+	"clamp": fixed(3, func(a []float64) float64 { return math.Min(a[2], math.Max(a[1], a[0])) }),
+	"lerp":  fixed(3, func(a []float64) float64 { return a[0] + (a[1]-a[0])*a[2] }),
+	"min": {1, -1, func(a []float64) float64 {
+		res := a[0]
+		for _, v := range a[1:] {
+			res = math.Min(res, v)
+		}
+
+		return res
+	}},
+	"max": {1, -1, func(a []float64) float64 {
+		res := a[0]
+		for _, v := range a[1:] {
+			res = math.Max(res, v)
+		}
+
+		return res
+	}},
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+
+	lookup func(name string) (int, bool)
+	slots  *[]float64
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) (string, bool) {
+	t := p.peek()
+
+	if t.typ != tokenOperator {
+		return "", false
+	}
+
+	for _, op := range ops {
+		if t.text == op {
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *parser) parseExpression() (node, error) {
+	return p.parseBinary(0)
+}
+
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"<", ">", "<=", ">=", "==", "!="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.isOperator(precedence[level]...)
+		if !ok {
+			return left, nil
+		}
+
+		p.next()
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = binary(op, left, right)
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.isOperator("-", "!"); ok {
+		p.next()
+
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if op == "-" {
+			return func() float64 { return -value() }, nil
+		}
+
+		return func() float64 { return boolean(value() == 0) }, nil
+	}
+
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (node, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.isOperator("^"); ok {
+		p.next()
+
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return func() float64 { return math.Pow(base(), exponent()) }, nil
+	}
+
+	return base, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.typ {
+	case tokenNumber:
+		value := t.value
+		return func() float64 { return value }, nil
+	case tokenLParen:
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.typ != tokenRParen {
+			return nil, fmt.Errorf("column %d: expected \")\", got %s", closing.column, closing)
+		}
+
+		return value, nil
+	case tokenIdent:
+		if p.peek().typ == tokenLParen {
+			return p.parseCall(t)
+		}
+
+		if value, ok := constants[t.text]; ok {
+			return func() float64 { return value }, nil
+		}
+
+		index, ok := p.lookup(t.text)
+		if !ok {
+			return nil, fmt.Errorf("column %d: unknown variable \"%s\"", t.column, t.text)
+		}
+
+		slots := p.slots
+
+		return func() float64 { return (*slots)[index] }, nil
+	}
+
+	return nil, fmt.Errorf("column %d: unexpected %s", t.column, t)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok && name.text != "if" {
+		return nil, fmt.Errorf("column %d: unknown function \"%s\"", name.column, name.text)
+	}
+
+	p.next() // (
+
+	args := make([]node, 0)
+
+	if p.peek().typ != tokenRParen {
+		for {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, arg)
+
+			if p.peek().typ != tokenComma {
+				break
+			}
+
+			p.next()
+		}
+	}
+
+	if closing := p.next(); closing.typ != tokenRParen {
+		return nil, fmt.Errorf("column %d: expected \")\", got %s", closing.column, closing)
+	}
+
+	// "if" is not in the function table because only the taken branch should be evaluated
+	if name.text == "if" {
+		fn = fixed(3, nil)
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("column %d: wrong number of arguments for \"%s\": %d", name.column, name.text, len(args))
+	}
+
+	if name.text == "if" {
+		condition, whenTrue, whenFalse := args[0], args[1], args[2]
+
+		return func() float64 {
+			if condition() != 0 {
+				return whenTrue()
+			}
+
+			return whenFalse()
+		}, nil
+	}
+
+	values := make([]float64, len(args))
+
+	return func() float64 {
+		for i, arg := range args {
+			values[i] = arg()
+		}
+
+		return fn.call(values)
+	}, nil
+}
+
+func binary(op string, left, right node) node {
+	switch op {
+	case "||":
+		return func() float64 { return boolean(left() != 0 || right() != 0) }
+	case "&&":
+		return func() float64 { return boolean(left() != 0 && right() != 0) }
+	case "<":
+		return func() float64 { return boolean(left() < right()) }
+	case ">":
+		return func() float64 { return boolean(left() > right()) }
+	case "<=":
+		return func() float64 { return boolean(left() <= right()) }
+	case ">=":
+		return func() float64 { return boolean(left() >= right()) }
+	case "==":
+		return func() float64 { return boolean(left() == right()) }
+	case "!=":
+		return func() float64 { return boolean(left() != right()) }
+	case "+":
+		return func() float64 { return left() + right() }
+	case "-":
+		return func() float64 { return left() - right() }
+	case "*":
+		return func() float64 { return left() * right() }
+	case "/":
+		return func() float64 { return left() / right() }
+	default: // %
+		return func() float64 { return math.Mod(left(), right()) }
+	}
+}
+
+func boolean(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,130 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+type statement struct {
+	slot  int
+	value node
+}
+
+// Program is a list of assignments in form of "name = expression", one per line.
+// Lines starting with # or // are comments. Variables have to be assigned before they are used.
+type Program struct {
+	slots      []float64
+	names      map[string]int
+	inputs     map[string]bool
+	statements []statement
+}
+
+// Compile parses the source and resolves all variables and functions, so a compiled Program can't fail while running.
+// inputs are variables that are set from outside before each Run.
+func Compile(source string, inputs ...string) (*Program, error) {
+	program := &Program{
+		names:  make(map[string]int),
+		inputs: make(map[string]bool),
+	}
+
+	for _, input := range inputs {
+		program.addSlot(input)
+		program.inputs[input] = true
+	}
+
+	for i, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if err := program.compileLine(line); err != nil {
+			return nil, fmt.Errorf("line %d, %s", i+1, err)
+		}
+	}
+
+	return program, nil
+}
+
+func (program *Program) compileLine(line string) error {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) < 3 || tokens[0].typ != tokenIdent || tokens[1].typ != tokenAssign {
+		return fmt.Errorf("column 1: expected assignment in form of \"name = expression\"")
+	}
+
+	name := tokens[0].text
+
+	if program.inputs[name] {
+		return fmt.Errorf("column 1: can't assign to input variable \"%s\"", name)
+	}
+
+	if _, ok := constants[name]; ok {
+		return fmt.Errorf("column 1: can't assign to constant \"%s\"", name)
+	}
+
+	if _, ok := functions[name]; ok || name == "if" {
+		return fmt.Errorf("column 1: \"%s\" is a function name", name)
+	}
+
+	p := &parser{
+		tokens: tokens[2:],
+		lookup: func(name string) (int, bool) {
+			slot, ok := program.names[name]
+			return slot, ok
+		},
+		slots: &program.slots,
+	}
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return err
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return fmt.Errorf("column %d: unexpected %s", t.column, t)
+	}
+
+	slot, ok := program.names[name]
+	if !ok {
+		slot = program.addSlot(name)
+	}
+
+	program.statements = append(program.statements, statement{slot: slot, value: value})
+
+	return nil
+}
+
+func (program *Program) addSlot(name string) int {
+	program.slots = append(program.slots, 0)
+	program.names[name] = len(program.slots) - 1
+
+	return len(program.slots) - 1
+}
+
+// Assigns checks whether the program assigns the given variable
+func (program *Program) Assigns(name string) bool {
+	_, ok := program.names[name]
+	return ok && !program.inputs[name]
+}
+
+// Variable returns a pointer to variable's value, used to set inputs and read results without map lookups.
+func (program *Program) Variable(name string) *float64 {
+	slot, ok := program.names[name]
+	if !ok {
+		panic(fmt.Sprintf("expression: unknown variable \"%s\"", name))
+	}
+
+	return &program.slots[slot]
+}
+
+// Run executes all assignments in order
+func (program *Program) Run() {
+	for _, s := range program.statements {
+		program.slots[s.slot] = s.value()
+	}
+}
@@ -7,7 +7,7 @@ require (
 	github.com/Microsoft/go-winio v0.5.0
 	github.com/StackExchange/wmi v1.2.1 // indirect
 	github.com/ananagame/rich-go v0.0.0-20210525072106-9d45f0e06959 // indirect
-	github.com/bnch/uleb128 v0.0.0-20160221084957-fac1fe18ad59 // indirect
+	github.com/bnch/uleb128 v0.0.0-20160221084957-fac1fe18ad59
 	github.com/dustin/go-humanize v1.0.0
 	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3
 	github.com/fsnotify/fsnotify v1.5.1
@@ -15,7 +15,7 @@ require (
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20221017161538-93cebf72946b
 	github.com/go-gl/mathgl v1.0.0
 	github.com/go-ole/go-ole v1.2.5 // indirect
-	github.com/itchio/lzma v0.0.0-20190703113020-d3e24e3e3d49 // indirect
+	github.com/itchio/lzma v0.0.0-20190703113020-d3e24e3e3d49
 	github.com/karrick/godirwalk v1.16.1
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.13 // indirect
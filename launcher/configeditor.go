@@ -5,6 +5,8 @@ import (
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/inkyblackness/imgui-go/v4"
 	"github.com/sqweek/dialog"
+	_ "github.com/wieku/danser-go/app/dance/movers"   // registers mover options
+	_ "github.com/wieku/danser-go/app/dance/spinners" // registers spinner mover options
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/env"
 	"github.com/wieku/danser-go/framework/math/color"
@@ -232,7 +234,7 @@ func (editor *settingsEditor) buildSearchCache(path string, u reflect.Value, sea
 	consumed := make(map[string]uint8)
 
 	for i := 0; i < count; i++ {
-		field := typ.Field(i)
+		field := unwrapInterface(typ.Field(i))
 		dF := def.Field(i)
 
 		if def.Field(i).Tag.Get("skip") != "" {
@@ -471,7 +473,7 @@ func (editor *settingsEditor) buildArray(jsonPath, sPath, name string, u reflect
 
 		if imgui.Button("+" + jsonPath) {
 			if fName, ok := d.Tag.Lookup("new"); ok {
-				u.Set(reflect.Append(u, reflect.ValueOf(settings.DefaultsFactory).MethodByName(fName).Call(nil)[0]))
+				u.Set(reflect.Append(u, settings.NewArrayElement(fName)))
 			}
 		}
 
@@ -550,6 +552,15 @@ func (editor *settingsEditor) buildArrayElement(jsonPath, sPath string, u reflec
 	return
 }
 
+// unwrapInterface returns the value stored in interface fields like CursorDance.MoverSettings, which are created at runtime
+func unwrapInterface(field reflect.Value) reflect.Value {
+	if field.Kind() == reflect.Interface && field.CanInterface() && !field.IsNil() {
+		return field.Elem()
+	}
+
+	return field
+}
+
 func (editor *settingsEditor) traverseChildren(jsonPath, lPath string, u reflect.Value, d reflect.StructField) {
 	typ := u.Elem()
 	def := u.Type().Elem()
@@ -569,7 +580,7 @@ func (editor *settingsEditor) traverseChildren(jsonPath, lPath string, u reflect
 	wasSection := false
 
 	for i := 0; i < count; i++ {
-		field := typ.Field(i)
+		field := unwrapInterface(typ.Field(i))
 		dF := def.Field(i)
 
 		if (!field.CanInterface() && (!dF.Anonymous && dF.Tag.Get("vector") == "")) || dF.Tag.Get("skip") != "" {
@@ -1366,17 +1377,27 @@ func (editor *settingsEditor) drawComponent(jsonPath, label string, long, checkb
 			label = "(!) " + label
 		}
 
+		inherited := editor.isInherited(jsonPath)
+
+		if inherited {
+			imgui.PushStyleColor(imgui.StyleColorText, vec4(0.6, 0.6, 0.6, 1))
+		}
+
 		imgui.BeginGroup()
 		imgui.AlignTextToFramePadding()
 		imgui.Text(label)
 		imgui.EndGroup()
 
+		if inherited {
+			imgui.PopStyleColor()
+		}
+
 		if imgui.IsItemHovered() {
 			_, hidePath := d.Tag.Lookup("hidePath")
 
 			showPath := !hidePath && launcherConfig.ShowJSONPaths
 
-			if showPath || hasTooltip {
+			if showPath || hasTooltip || inherited {
 				imgui.BeginTooltip()
 
 				tTip := ""
@@ -1384,8 +1405,16 @@ func (editor *settingsEditor) drawComponent(jsonPath, label string, long, checkb
 					tTip = strings.ReplaceAll(jsonPath, "#", "")
 				}
 
+				if inherited {
+					if tTip != "" {
+						tTip += "\n\n"
+					}
+
+					tTip += fmt.Sprintf("Inherited from \"%s\"", editor.current.Extends)
+				}
+
 				if hasTooltip {
-					if showPath {
+					if tTip != "" {
 						tTip += "\n\n"
 					}
 
@@ -1413,6 +1442,17 @@ func (editor *settingsEditor) drawComponent(jsonPath, label string, long, checkb
 	}
 }
 
+// isInherited checks whether value is not overridden in settings file which extends another one
+func (editor *settingsEditor) isInherited(jsonPath string) bool {
+	for _, path := range strings.Split(jsonPath, "\n") {
+		if !editor.current.IsInherited(strings.ReplaceAll(path, "#", "")) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (editor *settingsEditor) tryLockLive(d reflect.StructField) bool {
 	liveEdit := true
 
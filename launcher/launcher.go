@@ -35,6 +35,7 @@ import (
 	"github.com/sqweek/dialog"
 	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/database"
+	"github.com/wieku/danser-go/app/events"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/graphics/gui/drawables"
 	"github.com/wieku/danser-go/app/input"
@@ -65,7 +66,6 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -1667,17 +1667,16 @@ func (l *launcher) startDanser() {
 		dExec = filepath.Join(env.LibDir(), build.DanserExec)
 	}
 
-	l.danserCmd = exec.Command(dExec, l.bld.getArguments()...)
+	l.danserCmd = exec.Command(dExec, append(l.bld.getArguments(), "-events", "stderr")...)
 
-	rFile, oFile, err := os.Pipe()
+	l.danserCmd.Stdin = os.Stdin
+	l.danserCmd.Stdout = os.Stdout
+
+	stderr, err := l.danserCmd.StderrPipe()
 	if err != nil {
 		panic(err)
 	}
 
-	l.danserCmd.Stderr = os.Stderr
-	l.danserCmd.Stdin = os.Stdin
-	l.danserCmd.Stdout = io.MultiWriter(os.Stdout, oFile)
-
 	err = l.danserCmd.Start()
 	if err != nil {
 		showMessage(mError, "danser failed to start! %s", err.Error())
@@ -1694,80 +1693,69 @@ func (l *launcher) startDanser() {
 	l.recordStatus = "Preparing..."
 
 	panicMessage := ""
-	panicWait := &sync.WaitGroup{}
-	panicWait.Add(1)
 
 	resultFile := ""
 
+	streamWait := &sync.WaitGroup{}
+	streamWait.Add(1)
+
 	goroutines.Run(func() {
-		sc := bufio.NewScanner(rFile)
+		defer streamWait.Done()
+
+		sc := bufio.NewScanner(stderr)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
 		l.encodeInProgress = false
 
 		for sc.Scan() {
-			line := sc.Text()
-
-			if strings.Contains(line, "Launcher: Open settings") {
-				if l.currentEditor == nil || !l.currentEditor.opened {
-					l.openCurrentSettingsEditor()
-				}
-
-				l.win.Restore()
-				l.win.Focus()
-			}
-
-			if strings.Contains(line, "panic:") {
-				panicMessage = line[strings.Index(line, "panic:"):]
-				panicWait.Done()
-			}
-
-			if strings.Contains(line, "Starting encoding!") {
-				l.encodeInProgress = true
-				l.encodeStart = time.Now()
-
-				platform.StartProgress(l.win)
-			}
-
-			if strings.Contains(line, "Finishing rendering") {
-				l.encodeInProgress = false
-
-				l.recordProgress = 1
-				l.recordStatus = "Finalizing..."
-				l.recordStatusSpeed = ""
-				l.recordStatusETA = ""
+			event, ok := events.Parse(sc.Text())
+			if !ok { // Not an event, probably go runtime's output
+				_, _ = fmt.Fprintln(os.Stderr, sc.Text())
+				continue
 			}
 
-			if idx := strings.Index(line, "Video is available at: "); idx > -1 {
-				resultFile = strings.TrimPrefix(line[idx:], "Video is available at: ")
-			}
-
-			if idx := strings.Index(line, "Screenshot "); idx > -1 && strings.Contains(line, " saved!") {
-				resultFile = strings.TrimSuffix(strings.TrimPrefix(line[idx:], "Screenshot "), " saved!")
-				resultFile = filepath.Join(env.DataDir(), "screenshots", resultFile)
-			}
-
-			if strings.Contains(line, "Progress") && l.encodeInProgress {
-				line = line[strings.Index(line, "Progress"):]
-
-				rStats := strings.Split(line, ",")
-
-				spl := strings.TrimSpace(strings.Split(rStats[0], ":")[1])
-
-				l.recordStatus = spl
-
-				l.recordStatusSpeed = strings.TrimSpace(rStats[1])
-				l.recordStatusETA = strings.TrimSpace(rStats[2])
-
-				speed := strings.TrimSpace(strings.Split(rStats[1], ":")[1])
+			switch event.Type {
+			case events.TypeCommand:
+				if event.Message == events.CommandOpenSettings {
+					if l.currentEditor == nil || !l.currentEditor.opened {
+						l.openCurrentSettingsEditor()
+					}
 
-				speedP, _ := strconv.ParseFloat(speed[:len(speed)-1], 32)
+					l.win.Restore()
+					l.win.Focus()
+				}
+			case events.TypeError:
+				panicMessage = "panic: " + event.Message
+			case events.TypeStage:
+				switch event.Stage {
+				case events.StageRecording:
+					l.encodeInProgress = true
+					l.encodeStart = time.Now()
+
+					platform.StartProgress(l.win)
+				case events.StageFinalizing:
+					l.encodeInProgress = false
+
+					l.recordProgress = 1
+					l.recordStatus = "Finalizing..."
+					l.recordStatusSpeed = ""
+					l.recordStatusETA = ""
+				}
+			case events.TypeOutput:
+				resultFile = event.Path
+			case events.TypeProgress:
+				if !l.encodeInProgress {
+					break
+				}
 
-				l.triangleSpeed.AddEvent(l.triangleSpeed.GetTime(), l.triangleSpeed.GetTime()+500, speedP)
+				l.recordStatus = fmt.Sprintf("%.0f%%", event.Progress)
+				l.recordStatusSpeed = fmt.Sprintf("Speed: %.2fx", event.Speed)
+				l.recordStatusETA = "ETA: " + util.FormatSeconds(int(event.ETA))
 
-				at, _ := strconv.Atoi(spl[:len(spl)-1])
+				l.triangleSpeed.AddEvent(l.triangleSpeed.GetTime(), l.triangleSpeed.GetTime()+500, event.Speed)
 
-				l.recordProgress = float32(at) / 100
-				platform.SetProgress(l.win, at, 100)
+				l.recordProgress = float32(event.Progress) / 100
+				platform.SetProgress(l.win, int(event.Progress), 100)
 			}
 		}
 
@@ -1778,13 +1766,13 @@ func (l *launcher) startDanser() {
 	})
 
 	goroutines.Run(func() {
+		streamWait.Wait() // Wait closes the pipe so stream has to be read first
+
 		err = l.danserCmd.Wait()
 
 		l.danserCleanup(err == nil)
 
 		if err != nil {
-			panicWait.Wait()
-
 			mainthread.Call(func() {
 				pMsg := panicMessage
 				if idx := strings.Index(pMsg, "Error:"); idx > -1 {
@@ -1801,9 +1789,6 @@ func (l *launcher) startDanser() {
 			C.beep_custom()
 		}
 
-		rFile.Close()
-		oFile.Close()
-
 		l.win.Restore()
 	})
 }